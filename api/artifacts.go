@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/Sagn1k/scarab/artifacts"
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupArtifactRoutes serves screenshots and PDFs kept by the disk artifact
+// store to the key they were captured for. Other stores hand out their own
+// URLs.
+func (s *Server) setupArtifactRoutes(scraperService *scraper.ScraperService) {
+	disk, ok := scraperService.Artifacts().(*artifacts.Disk)
+	if !ok {
+		return
+	}
+
+	s.app.Get("/artifacts/:name", s.followUp(auth.ScopeScrape), func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		path, ok := disk.Path(name)
+		if !ok || !owns(c, disk.Owner(name)) {
+			return notFound("artifact not found")
+		}
+		return c.SendFile(path)
+	})
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+const keyLocal = "apiKey"
+
+// requestKey reads the secret from X-API-Key or a bearer token.
+func requestKey(c *fiber.Ctx) string {
+	if key := c.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if bearer, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		return bearer
+	}
+	return ""
+}
+
+// authKey returns the key the request was authenticated with, or nil when
+// authentication is off.
+func authKey(c *fiber.Ctx) *auth.Key {
+	key, _ := c.Locals(keyLocal).(*auth.Key)
+	return key
+}
+
+// owner identifies the caller as the owner of jobs, crawls and artifacts:
+// the authenticated key's ID, or empty when authentication is off.
+func owner(c *fiber.Ctx) string {
+	if key := authKey(c); key != nil {
+		return key.ID
+	}
+	return ""
+}
+
+// owns reports whether the caller may read or cancel work submitted by
+// owner. Keys see only their own work unless they have the admin scope.
+func owns(c *fiber.Ctx, owner string) bool {
+	key := authKey(c)
+	return key == nil || key.ID == owner || key.Allows(auth.ScopeAdmin)
+}
+
+func unauthorized(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return newHTTPError(fiber.StatusUnauthorized, "unauthorized", "missing or invalid API key")
+}
+
+// identify authenticates the request without checking scopes, rate limits
+// or quotas, for endpoints such as /usage that must keep working for a key
+// that has run out.
+func (s *Server) identify(c *fiber.Ctx) error {
+	if !s.keys.Enabled() {
+		return c.Next()
+	}
+
+	key, ok := s.keys.Lookup(requestKey(c))
+	if !ok {
+		return unauthorized(c)
+	}
+	c.Locals(keyLocal, key)
+	return c.Next()
+}
+
+// guard authenticates the request and checks that its key has scope, is
+// within its rate limit and has quota left. Without configured keys every
+// request is let through. The routes count the scrapes they start against
+// the quotas themselves.
+func (s *Server) guard(scope auth.Scope) fiber.Handler {
+	return s.check(scope, true)
+}
+
+// followUp is guard for routes that follow up on work already counted,
+// such as polling, cancelling and downloads, which keep working for a key
+// that has run out of quota.
+func (s *Server) followUp(scope auth.Scope) fiber.Handler {
+	return s.check(scope, false)
+}
+
+func (s *Server) check(scope auth.Scope, quota bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !s.keys.Enabled() {
+			return c.Next()
+		}
+
+		key, ok := s.keys.Lookup(requestKey(c))
+		if !ok {
+			return unauthorized(c)
+		}
+		if !key.Allows(scope) {
+			return forbidden("API key %s lacks the %s scope", key.ID, scope)
+		}
+
+		if allowed, wait := s.keyLimiter.Allow(key); !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			limited := newHTTPError(fiber.StatusTooManyRequests, "rate_limited", "rate limit exceeded for API key %s", key.ID)
+			limited.Retryable = true
+			limited.Details = fiber.Map{"retryAfter": retryAfter}
+			return limited
+		}
+
+		var exceeded *QuotaExceeded
+		if quota {
+			exceeded = s.exceededQuota(key)
+		}
+		if exceeded != nil {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(exceeded.ResetAt).Seconds()))))
+			c.Set("X-RateLimit-Reset", strconv.FormatInt(exceeded.ResetAt.Unix(), 10))
+			quotaErr := newHTTPError(fiber.StatusTooManyRequests, "quota_exceeded", "%s quota exceeded for API key %s", exceeded.Quota, key.ID)
+			quotaErr.Retryable = true
+			quotaErr.Details = exceeded
+			return quotaErr
+		}
+
+		c.Locals(keyLocal, key)
+		return c.Next()
+	}
+}
+
+// QuotaExceeded is the details of the 429 answered for a used-up quota.
+type QuotaExceeded struct {
+	Quota   string    `json:"quota"`
+	Limit   int       `json:"limit"`
+	Used    int       `json:"used"`
+	ResetAt time.Time `json:"resetAt"`
+}
+
+// exceededQuota returns the first daily or monthly limit key has used up,
+// or nil.
+func (s *Server) exceededQuota(key *auth.Key) *QuotaExceeded {
+	now := time.Now().UTC()
+	day := s.usage.Day(key.ID, now)
+	month := s.usage.Month(key.ID, now)
+
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, quota := range []QuotaExceeded{
+		{Quota: "dailyRequests", Limit: key.DailyRequests, Used: day.Requests, ResetAt: tomorrow},
+		{Quota: "monthlyRequests", Limit: key.MonthlyRequests, Used: month.Requests, ResetAt: nextMonth},
+		{Quota: "dailyTokens", Limit: key.DailyTokens, Used: day.TotalTokens, ResetAt: tomorrow},
+		{Quota: "monthlyTokens", Limit: key.MonthlyTokens, Used: month.TotalTokens, ResetAt: nextMonth},
+	} {
+		if quota.Limit > 0 && quota.Used >= quota.Limit {
+			return &quota
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/artifacts"
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/Sagn1k/scarab/usage"
+)
+
+func TestGuard(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	err := os.WriteFile(keysFile, []byte(`[
+		{"id": "scraper", "key": "s-scrape", "scopes": ["scrape"]},
+		{"id": "crawler", "key": "s-crawl", "scopes": ["crawl"]},
+		{"id": "admin", "key": "s-admin", "scopes": ["admin"]},
+		{"id": "limited", "key": "s-limited", "scopes": ["scrape"], "rateLimit": 0.001, "rateBurst": 1}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, &config.Config{APIKeysFile: keysFile})
+
+	// A request without a URL passes the guard and then fails validation,
+	// so it needs no browser.
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		keys       []string
+		wantStatus []int
+		wantCode   string
+	}{
+		{"no key", "POST", "/scrape", []string{""}, []int{401}, "unauthorized"},
+		{"unknown key", "POST", "/scrape", []string{"nope"}, []int{401}, "unauthorized"},
+		{"key with the scope", "POST", "/scrape", []string{"s-scrape"}, []int{400}, "invalid_request"},
+		{"key without the scope", "POST", "/scrape", []string{"s-crawl"}, []int{403}, "forbidden"},
+		{"admin has every scope", "POST", "/extract", []string{"s-admin"}, []int{400}, ""},
+		{"rate limit", "POST", "/scrape", []string{"s-limited", "s-limited"}, []int{400, 429}, "rate_limited"},
+		{"other keys' usage needs admin", "GET", "/usage?key=admin", []string{"s-scrape"}, []int{403}, "forbidden"},
+		{"admins read any usage", "GET", "/usage?key=scraper", []string{"s-admin"}, []int{200}, ""},
+		{"listing keys needs admin", "GET", "/usage/keys", []string{"s-scrape"}, []int{403}, "forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body interface{}
+			if tt.method == "POST" {
+				body = map[string]interface{}{}
+			}
+
+			var last ErrorBody
+			var resp *http.Response
+			for i, key := range tt.keys {
+				last = ErrorBody{}
+				resp = do(t, server, tt.method, tt.path, key, body, &last)
+				if resp.StatusCode != tt.wantStatus[i] {
+					t.Fatalf("request %d: status = %d, want %d (%+v)", i+1, resp.StatusCode, tt.wantStatus[i], last.Error)
+				}
+			}
+			if tt.wantCode != "" && last.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", last.Error.Code, tt.wantCode)
+			}
+			if resp.StatusCode == 401 && resp.Header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", resp.Header.Get("WWW-Authenticate"))
+			}
+			if resp.StatusCode == 429 && resp.Header.Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+		})
+	}
+}
+
+func TestQuotaCountsScrapes(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	err := os.WriteFile(keysFile, []byte(`[{"id": "quota", "key": "s-quota", "scopes": ["scrape"], "dailyRequests": 3}]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newRenderingServer(t, &config.Config{APIKeysFile: keysFile, BatchMaxConcurrency: 2}, &pageRenderer{})
+
+	// Each batch item is a scrape of its own.
+	batch := map[string]interface{}{"items": []map[string]string{{"url": "https://example.com/a"}, {"url": "https://example.com/b"}}}
+	if resp := do(t, server, "POST", "/scrape/batch", "s-quota", batch, nil); resp.StatusCode != 200 {
+		t.Fatalf("batch status = %d, want 200", resp.StatusCode)
+	}
+
+	var job JobResponse
+	if resp := do(t, server, "POST", "/jobs", "s-quota", map[string]string{"url": "https://example.com/c"}, &job); resp.StatusCode != 202 {
+		t.Fatalf("job status = %d, want 202", resp.StatusCode)
+	}
+	// Polling a job uses up nothing, even with the quota gone.
+	for i := 0; i < 5; i++ {
+		if resp := do(t, server, "GET", "/jobs/"+job.JobID, "s-quota", nil, nil); resp.StatusCode != 200 {
+			t.Fatalf("poll %d: status = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+
+	var report usage.Report
+	do(t, server, "GET", "/usage", "s-quota", nil, &report)
+	if report.Total.Requests != 3 {
+		t.Errorf("requests = %d, want 3", report.Total.Requests)
+	}
+
+	var body ErrorBody
+	resp := do(t, server, "POST", "/scrape", "s-quota", map[string]string{"url": "https://example.com/d"}, &body)
+	if resp.StatusCode != 429 || body.Error.Code != "quota_exceeded" {
+		t.Errorf("status = %d, code = %q, want 429 quota_exceeded", resp.StatusCode, body.Error.Code)
+	}
+}
+
+func TestGuardBearerToken(t *testing.T) {
+	server := newTestServer(t, &config.Config{APIKeys: []string{"secret"}})
+
+	req, _ := http.NewRequest("GET", server.URL+"/usage", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestOpenWithoutKeys(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+
+	var body ErrorBody
+	if resp := do(t, server, "POST", "/scrape", "", map[string]interface{}{}, &body); resp.StatusCode != 400 {
+		t.Errorf("status = %d, want 400 (%+v)", resp.StatusCode, body.Error)
+	}
+
+	// An unchecked X-API-Key is not used to name the caller.
+	var report usage.Report
+	do(t, server, "GET", "/usage", "secret", nil, &report)
+	if report.Key != usage.Anonymous {
+		t.Errorf("usage key = %q, want %q", report.Key, usage.Anonymous)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	err := os.WriteFile(keysFile, []byte(`[
+		{"id": "owner", "key": "s-owner", "scopes": ["scrape", "crawl"]},
+		{"id": "other", "key": "s-other", "scopes": ["scrape", "crawl"]},
+		{"id": "admin", "key": "s-admin", "scopes": ["admin"]}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		APIKeysFile:     keysFile,
+		LLMProvider:     "fake",
+		Converter:       scraper.ConverterNative,
+		ArtifactStore:   "disk",
+		ArtifactDir:     t.TempDir(),
+		ArtifactBaseURL: "/artifacts",
+	}
+	api := newFakeLLMServer(cfg)
+	api.Scraper().SetRenderer(&pageRenderer{delay: time.Hour})
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+
+	var job JobResponse
+	if resp := do(t, server, "POST", "/jobs", "s-owner", map[string]interface{}{"url": "https://example.com/"}, &job); resp.StatusCode != 202 {
+		t.Fatalf("POST /jobs status = %d", resp.StatusCode)
+	}
+	var crawl CrawlResponse
+	if resp := do(t, server, "POST", "/crawl", "s-owner", map[string]interface{}{"url": "https://example.com/"}, &crawl); resp.StatusCode != 202 {
+		t.Fatalf("POST /crawl status = %d", resp.StatusCode)
+	}
+	url, err := api.Scraper().Artifacts().Put(artifacts.WithOwner(context.Background(), "owner"), "image/png", []byte("png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{"/jobs/" + job.JobID, "/crawl/" + crawl.CrawlID, url}
+	for _, path := range paths {
+		for key, want := range map[string]int{"s-owner": 200, "s-admin": 200, "s-other": 404} {
+			if resp := do(t, server, "GET", path, key, nil, nil); resp.StatusCode != want {
+				t.Errorf("GET %s with %s: status = %d, want %d", path, key, resp.StatusCode, want)
+			}
+		}
+	}
+
+	for _, path := range paths[:2] {
+		if resp := do(t, server, "DELETE", path, "s-other", nil, nil); resp.StatusCode != 404 {
+			t.Errorf("DELETE %s by another key: status = %d, want 404", path, resp.StatusCode)
+		}
+		if resp := do(t, server, "DELETE", path, "s-owner", nil, nil); resp.StatusCode != 200 {
+			t.Errorf("DELETE %s by its owner: status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+}
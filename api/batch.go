@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BatchRequest struct {
+	Items       []ScrapeRequest `json:"items"`
+	Concurrency int             `json:"concurrency"`
+	Stream      bool            `json:"stream"`
+}
+
+type BatchItemResult struct {
+	Index    int    `json:"index"`
+	URL      string `json:"url"`
+	Success  bool   `json:"success"`
+	Markdown string `json:"markdown,omitempty"`
+	scraper.Outputs
+	Metadata   *scraper.Metadata         `json:"metadata,omitempty"`
+	Actions    []scraper.ActionResult    `json:"actions,omitempty"`
+	Pagination *scraper.PaginationResult `json:"pagination,omitempty"`
+	Conversion *scraper.ConversionInfo   `json:"conversion,omitempty"`
+	Usage      *llm.Usage                `json:"usage,omitempty"`
+	Cache      string                    `json:"cache,omitempty"`
+	Error      string                    `json:"error,omitempty"`
+	// Code and Retryable classify Error as in the error responses.
+	Code      string `json:"code,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+func (r *BatchItemResult) fail(err error) {
+	httpErr := classify(err)
+	r.Error = httpErr.Message
+	r.Code = httpErr.Code
+	r.Retryable = httpErr.Retryable
+}
+
+type BatchResponse struct {
+	Success bool              `json:"success"`
+	Results []BatchItemResult `json:"results"`
+}
+
+func (s *Server) setupBatchRoutes(scraperService *scraper.ScraperService) {
+	s.app.Post("/scrape/batch", s.guard(auth.ScopeScrape), func(c *fiber.Ctx) error {
+		var req BatchRequest
+		if err := c.BodyParser(&req); err != nil {
+			return bodyError(err)
+		}
+
+		if len(req.Items) == 0 {
+			return badRequest("items are required")
+		}
+		if s.config.BatchMaxItems > 0 && len(req.Items) > s.config.BatchMaxItems {
+			return badRequest("batch exceeds the limit of %d items", s.config.BatchMaxItems)
+		}
+
+		concurrency := req.Concurrency
+		if concurrency < 1 || (s.config.BatchMaxConcurrency > 0 && concurrency > s.config.BatchMaxConcurrency) {
+			concurrency = s.config.BatchMaxConcurrency
+		}
+
+		stream := req.Stream || strings.Contains(c.Get(fiber.HeaderAccept), "application/x-ndjson")
+		if !stream {
+			ctx, cancel := requestContext(c)
+			defer cancel()
+
+			results := make([]BatchItemResult, len(req.Items))
+			runBatch(s.callerContext(ctx, c), scraperService, req.Items, concurrency, s.scrapeCounter(c), func(result BatchItemResult) {
+				results[result.Index] = result
+			})
+
+			return c.JSON(BatchResponse{
+				Success: true,
+				Results: results,
+			})
+		}
+
+		// The stream writer runs after the handler returns, when c is no
+		// longer valid.
+		usageCtx := s.callerContext(context.Background(), c)
+		count := s.scrapeCounter(c)
+
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			ctx, cancel := context.WithCancel(usageCtx)
+			defer cancel()
+
+			encoder := json.NewEncoder(w)
+			runBatch(ctx, scraperService, req.Items, concurrency, count, func(result BatchItemResult) {
+				if ctx.Err() != nil {
+					return
+				}
+				// A failed write means the client went away; stop the rest.
+				if err := encoder.Encode(result); err != nil {
+					cancel()
+					return
+				}
+				if err := w.Flush(); err != nil {
+					cancel()
+				}
+			})
+		})
+
+		return nil
+	})
+}
+
+// runBatch scrapes every item with at most concurrency scrapes in flight and
+// calls emit once per item as it finishes. count is called for every scrape
+// started. emit is never called concurrently.
+func runBatch(ctx context.Context, scraperService *scraper.ScraperService, items []ScrapeRequest, concurrency int, count func(), emit func(BatchItemResult)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg     sync.WaitGroup
+		emitMu sync.Mutex
+		sem    = make(chan struct{}, concurrency)
+	)
+
+	for i, item := range items {
+		wg.Add(1)
+		go func(index int, item ScrapeRequest) {
+			defer wg.Done()
+
+			result := BatchItemResult{Index: index, URL: item.URL}
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.fail(ctx.Err())
+				emitMu.Lock()
+				emit(result)
+				emitMu.Unlock()
+				return
+			}
+
+			if item.URL != "" {
+				count()
+			}
+			if item.URL == "" {
+				result.fail(badRequest("URL is required"))
+			} else if scraped, err := scraperService.Scrape(ctx, item.URL, item.Params); err != nil {
+				result.fail(err)
+			} else {
+				result.Success = true
+				result.Markdown = scraped.Markdown
+				result.Outputs = scraped.Outputs
+				result.Metadata = scraped.Metadata
+				result.Actions = scraped.Actions
+				result.Pagination = scraped.Pagination
+				result.Conversion = conversionInfo(scraped)
+				result.Usage = &scraped.Usage
+				result.Cache = scraped.Cache
+			}
+
+			emitMu.Lock()
+			emit(result)
+			emitMu.Unlock()
+		}(i, item)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+func TestBatch(t *testing.T) {
+	renderer := &pageRenderer{delay: 10 * time.Millisecond}
+	server := newRenderingServer(t, &config.Config{BatchMaxConcurrency: 2}, renderer)
+
+	var resp BatchResponse
+	httpResp := do(t, server, "POST", "/scrape/batch", "", map[string]interface{}{
+		"concurrency": 10,
+		"items": []map[string]interface{}{
+			{"url": "https://example.com/a"},
+			{"url": ""},
+			{"url": "https://example.com/b", "params": map[string]interface{}{"formats": []string{"text"}}},
+			{"url": "not a url"},
+			{"url": "https://example.com/c"},
+		},
+	}, &resp)
+	if httpResp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", httpResp.StatusCode)
+	}
+
+	want := []struct {
+		success bool
+		code    string
+	}{
+		{true, ""},
+		{false, "invalid_request"},
+		{true, ""},
+		{false, "invalid_url"},
+		{true, ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, w := range want {
+		result := resp.Results[i]
+		if result.Index != i || result.Success != w.success || result.Code != w.code {
+			t.Errorf("result %d = %+v, want success %v code %q", i, result, w.success, w.code)
+		}
+	}
+	if !strings.Contains(resp.Results[0].Markdown, "https://example.com/a") {
+		t.Errorf("Markdown = %q, want the page's", resp.Results[0].Markdown)
+	}
+	if resp.Results[2].Text == "" || resp.Results[2].Markdown != "" {
+		t.Errorf("item with its own params = %+v, want text only", resp.Results[2])
+	}
+	if peak := renderer.peak(); peak > 2 {
+		t.Errorf("%d renders ran at once, want at most the configured 2", peak)
+	}
+}
+
+func TestBatchLimits(t *testing.T) {
+	server := newRenderingServer(t, &config.Config{BatchMaxItems: 2}, &pageRenderer{})
+
+	tests := []struct {
+		name  string
+		items []map[string]interface{}
+	}{
+		{"no items", nil},
+		{"too many items", []map[string]interface{}{{"url": "https://a.example/"}, {"url": "https://b.example/"}, {"url": "https://c.example/"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body ErrorBody
+			resp := do(t, server, "POST", "/scrape/batch", "", map[string]interface{}{"items": tt.items}, &body)
+			if resp.StatusCode != http.StatusBadRequest || body.Error.Code != "invalid_request" {
+				t.Errorf("response = %d %+v, want 400 invalid_request", resp.StatusCode, body.Error)
+			}
+		})
+	}
+}
+
+func TestBatchStream(t *testing.T) {
+	server := newRenderingServer(t, &config.Config{}, &pageRenderer{})
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"concurrency": 3,
+		"items": []map[string]interface{}{
+			{"url": "https://example.com/a"},
+			{"url": "https://example.com/b"},
+			{"url": "ftp://example.com/c"},
+		},
+	})
+	req, _ := http.NewRequest("POST", server.URL+"/scrape/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// Results arrive as they finish, one per line.
+	var indexes []int
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result BatchItemResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		if result.Success != (result.Index < 2) {
+			t.Errorf("result %d = %+v", result.Index, result)
+		}
+		indexes = append(indexes, result.Index)
+	}
+	sort.Ints(indexes)
+	if len(indexes) != 3 || indexes[0] != 0 || indexes[1] != 1 || indexes[2] != 2 {
+		t.Errorf("indexes = %v, want each item once", indexes)
+	}
+}
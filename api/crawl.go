@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CrawlRequest struct {
+	URL      string                `json:"url"`
+	MaxDepth int                   `json:"maxDepth"`
+	MaxPages int                   `json:"maxPages"`
+	Scope    scraper.CrawlScope    `json:"scope"`
+	Include  []string              `json:"include"`
+	Exclude  []string              `json:"exclude"`
+	Params   scraper.ScrapeOptions `json:"params"`
+}
+
+type CrawlResponse struct {
+	CrawlID    string              `json:"crawlId"`
+	Status     string              `json:"status"`
+	Error      string              `json:"error,omitempty"`
+	Total      int                 `json:"total"`
+	NextOffset int                 `json:"nextOffset"`
+	Pages      []scraper.CrawlPage `json:"pages"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+}
+
+type crawlSession struct {
+	mu         sync.Mutex
+	id         string
+	owner      string
+	status     string
+	err        string
+	pages      []scraper.CrawlPage
+	createdAt  time.Time
+	finishedAt *time.Time
+	cancel     context.CancelFunc
+}
+
+func (cs *crawlSession) snapshot(offset int) CrawlResponse {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if offset < 0 || offset > len(cs.pages) {
+		offset = len(cs.pages)
+	}
+	pages := make([]scraper.CrawlPage, len(cs.pages)-offset)
+	copy(pages, cs.pages[offset:])
+
+	return CrawlResponse{
+		CrawlID:    cs.id,
+		Status:     cs.status,
+		Error:      cs.err,
+		Total:      len(cs.pages),
+		NextOffset: len(cs.pages),
+		Pages:      pages,
+		CreatedAt:  cs.createdAt,
+		FinishedAt: cs.finishedAt,
+	}
+}
+
+func (s *Server) setupCrawlRoutes(scraperService *scraper.ScraperService) {
+	var (
+		mu     sync.RWMutex
+		crawls = make(map[string]*crawlSession)
+	)
+
+	// lookup returns the caller's crawl called id. Crawls of other keys are
+	// reported as missing, so their IDs cannot be probed.
+	lookup := func(c *fiber.Ctx) *crawlSession {
+		mu.RLock()
+		session := crawls[c.Params("id")]
+		mu.RUnlock()
+		if session == nil || !owns(c, session.owner) {
+			return nil
+		}
+		return session
+	}
+
+	s.app.Post("/crawl", s.guard(auth.ScopeCrawl), func(c *fiber.Ctx) error {
+		var req CrawlRequest
+		if err := c.BodyParser(&req); err != nil {
+			return bodyError(err)
+		}
+
+		if req.URL == "" {
+			return badRequest("URL is required")
+		}
+
+		opts, err := s.crawlOptions(req)
+		if err != nil {
+			return badRequest("%v", err)
+		}
+
+		ctx, cancel := context.WithCancel(s.callerContext(context.Background(), c))
+		session := &crawlSession{
+			id:        uuid.NewString(),
+			owner:     owner(c),
+			status:    "running",
+			createdAt: time.Now(),
+			cancel:    cancel,
+		}
+
+		mu.Lock()
+		crawls[session.id] = session
+		mu.Unlock()
+
+		count := s.scrapeCounter(c)
+		go func() {
+			defer cancel()
+
+			err := scraperService.Crawl(ctx, req.URL, opts, func(page scraper.CrawlPage) {
+				count()
+				session.mu.Lock()
+				session.pages = append(session.pages, page)
+				session.mu.Unlock()
+			})
+
+			session.mu.Lock()
+			defer session.mu.Unlock()
+
+			now := time.Now()
+			session.finishedAt = &now
+			switch {
+			case ctx.Err() != nil:
+				session.status = "cancelled"
+			case err != nil:
+				session.status = "failed"
+				session.err = err.Error()
+			default:
+				session.status = "done"
+			}
+
+			if s.config.JobRetentionMinutes > 0 {
+				time.AfterFunc(time.Duration(s.config.JobRetentionMinutes)*time.Minute, func() {
+					mu.Lock()
+					delete(crawls, session.id)
+					mu.Unlock()
+				})
+			}
+		}()
+
+		return c.Status(fiber.StatusAccepted).JSON(session.snapshot(0))
+	})
+
+	s.app.Get("/crawl/:id", s.followUp(auth.ScopeCrawl), func(c *fiber.Ctx) error {
+		session := lookup(c)
+		if session == nil {
+			return notFound("crawl not found")
+		}
+
+		return c.JSON(session.snapshot(c.QueryInt("offset", 0)))
+	})
+
+	s.app.Delete("/crawl/:id", s.followUp(auth.ScopeCrawl), func(c *fiber.Ctx) error {
+		session := lookup(c)
+		if session == nil {
+			return notFound("crawl not found")
+		}
+
+		session.cancel()
+		return c.JSON(session.snapshot(0))
+	})
+}
+
+func (s *Server) crawlOptions(req CrawlRequest) (scraper.CrawlOptions, error) {
+	opts := scraper.CrawlOptions{
+		MaxDepth: req.MaxDepth,
+		MaxPages: req.MaxPages,
+		Scope:    req.Scope,
+		Params:   req.Params,
+	}
+
+	if opts.MaxDepth <= 0 || opts.MaxDepth > s.config.CrawlMaxDepth {
+		opts.MaxDepth = s.config.CrawlMaxDepth
+	}
+	if opts.MaxPages <= 0 || opts.MaxPages > s.config.CrawlMaxPages {
+		opts.MaxPages = s.config.CrawlMaxPages
+	}
+
+	switch opts.Scope {
+	case "":
+		opts.Scope = scraper.ScopeDomain
+	case scraper.ScopeDomain, scraper.ScopePrefix:
+	default:
+		return opts, fmt.Errorf("scope must be %q or %q", scraper.ScopeDomain, scraper.ScopePrefix)
+	}
+
+	for _, pattern := range req.Include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return opts, err
+		}
+		opts.Include = append(opts.Include, re)
+	}
+	for _, pattern := range req.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return opts, err
+		}
+		opts.Exclude = append(opts.Exclude, re)
+	}
+
+	return opts, nil
+}
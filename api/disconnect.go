@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// disconnectPollInterval is how often requestContext checks whether the
+// client is still connected.
+const disconnectPollInterval = 500 * time.Millisecond
+
+// requestContext returns a context for the work of a request that is
+// cancelled when the client closes the connection, so that an abandoned
+// request stops its render. fasthttp does not report disconnects, so the
+// connection is polled; where it cannot be, only the scrape deadline
+// applies. The caller must cancel the context before the handler returns.
+func requestContext(c *fiber.Ctx) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(c.Context())
+
+	conn := c.Context().Conn()
+	if conn == nil || !canDetectDisconnect(conn) {
+		return ctx, cancel
+	}
+
+	go func() {
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if disconnected(conn) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package api
+
+import "net"
+
+func canDetectDisconnect(conn net.Conn) bool {
+	return false
+}
+
+func disconnected(conn net.Conn) bool {
+	return false
+}
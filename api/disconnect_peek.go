@@ -0,0 +1,43 @@
+//go:build linux || darwin
+
+package api
+
+import (
+	"net"
+	"syscall"
+)
+
+func canDetectDisconnect(conn net.Conn) bool {
+	_, ok := conn.(syscall.Conn)
+	return ok
+}
+
+// disconnected peeks at the connection without consuming anything, so a
+// pipelined request stays in place for fasthttp. A read of zero bytes is
+// the client's end of stream.
+func disconnected(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	closed := false
+	_ = raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == syscall.EAGAIN || err == syscall.EWOULDBLOCK || err == syscall.EINTR:
+		case err != nil:
+			closed = true
+		default:
+			closed = n == 0
+		}
+		// Done either way; never wait for the connection to be readable.
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,65 @@
+//go:build linux || darwin
+
+package api
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(t *testing.T) (server, client net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server, err = ln.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return server, client
+}
+
+func TestDisconnected(t *testing.T) {
+	server, client := tcpPair(t)
+	if !canDetectDisconnect(server) {
+		t.Fatal("canDetectDisconnect() = false for a TCP connection")
+	}
+	if disconnected(server) {
+		t.Error("disconnected() = true while the client is idle")
+	}
+
+	// A pipelined request is peeked at, not consumed.
+	if _, err := client.Write([]byte("GET")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if disconnected(server) {
+		t.Error("disconnected() = true with a request waiting")
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(server, buf); err != nil || string(buf) != "GET" {
+		t.Errorf("read %q, %v after peeking, want GET", buf, err)
+	}
+
+	client.Close()
+	deadline := time.Now().Add(time.Second)
+	for !disconnected(server) {
+		if time.Now().After(deadline) {
+			t.Fatal("disconnected() = false after the client closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Sagn1k/scarab/errors"
+	"github.com/Sagn1k/scarab/jobs"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const requestIDLocal = "requestid"
+
+// ErrorBody is the JSON body of every error response.
+type ErrorBody struct {
+	Error ErrorInfo `json:"error"`
+}
+
+type ErrorInfo struct {
+	// Code is a stable, machine-readable name for the failure, such as
+	// "timeout" or "challenge_blocked".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+	// Retryable says whether the same request may succeed later.
+	Retryable bool        `json:"retryable"`
+	RequestID string      `json:"requestId,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// HTTPError is an error together with the status and code it is answered
+// with. Handlers return it for failures they detect themselves.
+type HTTPError struct {
+	Status    int
+	Code      string
+	Message   string
+	Retryable bool
+	Details   interface{}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+func newHTTPError(status int, code, format string, args ...interface{}) *HTTPError {
+	return &HTTPError{
+		Status:  status,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// bodyError reports a request body that could not be parsed. Invalid params
+// keep their list of fields.
+func bodyError(err error) error {
+	if errors.IsType(err, errors.ErrInvalidParams) {
+		return err
+	}
+	return badRequest("invalid request body: %v", err)
+}
+
+func badRequest(format string, args ...interface{}) *HTTPError {
+	return newHTTPError(fiber.StatusBadRequest, "invalid_request", format, args...)
+}
+
+func forbidden(format string, args ...interface{}) *HTTPError {
+	return newHTTPError(fiber.StatusForbidden, "forbidden", format, args...)
+}
+
+func notFound(format string, args ...interface{}) *HTTPError {
+	return newHTTPError(fiber.StatusNotFound, "not_found", format, args...)
+}
+
+// errorClasses maps the sentinel errors to their status and code. The
+// classified LLM errors come before ErrLLMAPIFailure, which they wrap.
+var errorClasses = []struct {
+	err       error
+	status    int
+	code      string
+	retryable bool
+}{
+	{errors.ErrInvalidURL, fiber.StatusBadRequest, "invalid_url", false},
+	{errors.ErrInvalidParams, fiber.StatusBadRequest, "invalid_params", false},
+	{errors.ErrRobotsDisallowed, fiber.StatusForbidden, "robots_disallowed", false},
+	{errors.ErrCloudflareBlock, fiber.StatusForbidden, "challenge_blocked", false},
+	{errors.ErrRateLimited, fiber.StatusTooManyRequests, "rate_limited", true},
+	{errors.ErrTimeout, fiber.StatusGatewayTimeout, "timeout", true},
+	{errors.ErrProxyFailure, fiber.StatusBadGateway, "proxy_failure", true},
+	{errors.ErrPageLoad, fiber.StatusBadGateway, "page_load_failed", true},
+	{errors.ErrLLMRateLimited, fiber.StatusServiceUnavailable, "llm_rate_limited", true},
+	{errors.ErrLLMServer, fiber.StatusServiceUnavailable, "llm_unavailable", true},
+	{errors.ErrLLMCircuitOpen, fiber.StatusServiceUnavailable, "llm_circuit_open", true},
+	{errors.ErrLLMAuth, fiber.StatusBadGateway, "llm_auth_failed", false},
+	{errors.ErrLLMContextLength, fiber.StatusBadGateway, "llm_context_length", false},
+	{errors.ErrLLMAPIFailure, fiber.StatusBadGateway, "llm_failure", false},
+	{jobs.ErrQueueFull, fiber.StatusServiceUnavailable, "queue_full", true},
+	{jobs.ErrJobNotFound, fiber.StatusNotFound, "not_found", false},
+	{context.Canceled, fiber.StatusRequestTimeout, "cancelled", true},
+}
+
+// classify returns the status and code err is reported with. Errors that
+// match no class are internal errors.
+func classify(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.AsType(err, &httpErr) {
+		return httpErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.AsType(err, &fiberErr) {
+		return &HTTPError{
+			Status:    fiberErr.Code,
+			Code:      statusCode(fiberErr.Code),
+			Message:   fiberErr.Message,
+			Retryable: fiberErr.Code == fiber.StatusTooManyRequests || fiberErr.Code == fiber.StatusServiceUnavailable,
+		}
+	}
+
+	for _, class := range errorClasses {
+		if errors.IsType(err, class.err) {
+			httpErr := &HTTPError{
+				Status:    class.status,
+				Code:      class.code,
+				Message:   err.Error(),
+				Retryable: class.retryable,
+			}
+			var invalid *scraper.OptionsError
+			if errors.AsType(err, &invalid) {
+				httpErr.Details = fiber.Map{"fields": invalid.Fields}
+			}
+			return httpErr
+		}
+	}
+
+	return &HTTPError{
+		Status:  fiber.StatusInternalServerError,
+		Code:    "internal_error",
+		Message: err.Error(),
+	}
+}
+
+// statusCode names the errors Fiber raises itself, e.g. "not_found" for an
+// unknown route.
+func statusCode(status int) string {
+	if status == fiber.StatusBadRequest {
+		return "invalid_request"
+	}
+	return strings.ToLower(strings.ReplaceAll(utils.StatusMessage(status), " ", "_"))
+}
+
+func (e *HTTPError) info(requestID string) ErrorInfo {
+	return ErrorInfo{
+		Code:      e.Code,
+		Message:   e.Message,
+		Status:    e.Status,
+		Retryable: e.Retryable,
+		RequestID: requestID,
+		Details:   e.Details,
+	}
+}
+
+// handleError is the ErrorHandler of the app: every error a handler returns
+// is answered with its status and an ErrorBody.
+func handleError(c *fiber.Ctx, err error) error {
+	httpErr := classify(err)
+	return c.Status(httpErr.Status).JSON(ErrorBody{Error: httpErr.info(requestID(c))})
+}
+
+// requestID returns the ID the request is logged and answered with.
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDLocal).(string)
+	return id
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+	"github.com/Sagn1k/scarab/jobs"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantStatus    int
+		wantCode      string
+		wantRetryable bool
+	}{
+		{"invalid URL", errors.WithCause(errors.ErrInvalidURL, "%q", "x"), 400, "invalid_url", false},
+		{"invalid params", &scraper.OptionsError{Fields: []scraper.FieldError{{Field: "a", Message: "b"}}}, 400, "invalid_params", false},
+		{"robots", errors.ErrRobotsDisallowed, 403, "robots_disallowed", false},
+		{"cloudflare", errors.ErrCloudflareBlock, 403, "challenge_blocked", false},
+		{"host rate limit", errors.ErrRateLimited, 429, "rate_limited", true},
+		{"timeout", fmt.Errorf("render: %w", errors.ErrTimeout), 504, "timeout", true},
+		{"proxy", errors.ErrProxyFailure, 502, "proxy_failure", true},
+		{"page load", errors.ErrPageLoad, 502, "page_load_failed", true},
+		{"LLM rate limited", errors.ErrLLMRateLimited, 503, "llm_rate_limited", true},
+		{"LLM server", errors.ErrLLMServer, 503, "llm_unavailable", true},
+		{"LLM breaker", errors.ErrLLMCircuitOpen, 503, "llm_circuit_open", true},
+		{"LLM auth", errors.ErrLLMAuth, 502, "llm_auth_failed", false},
+		{"LLM context", errors.ErrLLMContextLength, 502, "llm_context_length", false},
+		{"LLM other", errors.ErrLLMAPIFailure, 502, "llm_failure", false},
+		{"queue full", jobs.ErrQueueFull, 503, "queue_full", true},
+		{"job not found", jobs.ErrJobNotFound, 404, "not_found", false},
+		{"cancelled", fmt.Errorf("scrape cancelled: %w", context.Canceled), 408, "cancelled", true},
+		{"handler error", badRequest("URL is required"), 400, "invalid_request", false},
+		{"fiber error", fiber.ErrMethodNotAllowed, 405, "method_not_allowed", false},
+		{"fiber unavailable", fiber.ErrServiceUnavailable, 503, "service_unavailable", true},
+		{"unknown", fmt.Errorf("boom"), 500, "internal_error", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classify(tt.err)
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode || got.Retryable != tt.wantRetryable {
+				t.Errorf("classify() = %d %s retryable=%v, want %d %s retryable=%v",
+					got.Status, got.Code, got.Retryable, tt.wantStatus, tt.wantCode, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	server := newTestServer(t, &config.Config{})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{"unknown route", "GET", "/nope", nil, 404, "not_found", nil},
+		{"missing URL", "POST", "/scrape", map[string]interface{}{}, 400, "invalid_request", nil},
+		{"invalid URL", "POST", "/scrape", map[string]interface{}{"url": "ftp://example.com"}, 400, "invalid_url", nil},
+		{
+			"invalid params",
+			"POST", "/scrape",
+			map[string]interface{}{"url": "https://example.com", "params": map[string]interface{}{"converter": "magic", "waitTime": -1}},
+			400, "invalid_params", []string{"waitTime", "converter"},
+		},
+		{"unknown job", "GET", "/jobs/unknown", nil, 404, "not_found", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Error struct {
+					ErrorInfo
+					Details struct {
+						Fields []scraper.FieldError `json:"fields"`
+					} `json:"details"`
+				} `json:"error"`
+			}
+			resp := do(t, server, tt.method, tt.path, "", tt.body, &body)
+
+			if resp.StatusCode != tt.wantStatus || body.Error.Status != tt.wantStatus || body.Error.Code != tt.wantCode {
+				t.Errorf("got %d %+v, want %d %s", resp.StatusCode, body.Error.ErrorInfo, tt.wantStatus, tt.wantCode)
+			}
+			if body.Error.RequestID == "" || body.Error.RequestID != resp.Header.Get("X-Request-ID") {
+				t.Errorf("requestId = %q, header = %q", body.Error.RequestID, resp.Header.Get("X-Request-ID"))
+			}
+
+			var fields []string
+			for _, field := range body.Error.Details.Fields {
+				fields = append(fields, field.Field)
+			}
+			if fmt.Sprint(fields) != fmt.Sprint(tt.wantFields) && !(len(fields) == 0 && len(tt.wantFields) == 0) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
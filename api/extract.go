@@ -0,0 +1,65 @@
+package api
+
+import (
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/schema"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExtractRequest struct {
+	URL    string                 `json:"url"`
+	Schema map[string]interface{} `json:"schema"`
+	Params scraper.ScrapeOptions  `json:"params"`
+}
+
+type ExtractResponse struct {
+	Success   bool                     `json:"success"`
+	URL       string                   `json:"url"`
+	Data      interface{}              `json:"data"`
+	Errors    []schema.ValidationError `json:"errors,omitempty"`
+	Attempts  int                      `json:"attempts"`
+	Truncated bool                     `json:"truncated"`
+	Usage     *llm.Usage               `json:"usage,omitempty"`
+}
+
+func (s *Server) setupExtractRoutes(scraperService *scraper.ScraperService) {
+	s.app.Post("/extract", s.guard(auth.ScopeExtract), func(c *fiber.Ctx) error {
+		var req ExtractRequest
+		if err := c.BodyParser(&req); err != nil {
+			return bodyError(err)
+		}
+
+		if req.URL == "" {
+			return badRequest("URL is required")
+		}
+		if err := schema.Check(req.Schema); err != nil {
+			return badRequest("invalid schema: %v", err)
+		}
+
+		ctx, cancel := requestContext(c)
+		defer cancel()
+
+		s.countScrape(c)
+		result, err := scraperService.Extract(s.callerContext(ctx, c), req.URL, req.Schema, req.Params)
+		if err != nil {
+			return err
+		}
+
+		status := fiber.StatusOK
+		if !result.Valid {
+			status = fiber.StatusUnprocessableEntity
+		}
+
+		return c.Status(status).JSON(ExtractResponse{
+			Success:   result.Valid,
+			URL:       result.URL,
+			Data:      result.Data,
+			Errors:    result.Errors,
+			Attempts:  result.Attempts,
+			Truncated: result.Truncated,
+			Usage:     &result.Usage,
+		})
+	})
+}
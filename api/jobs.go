@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/jobs"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) setupJobRoutes(scraperService *scraper.ScraperService) {
+	manager := jobs.NewManager(s.config, jobs.NewMemoryStore(), scraperService)
+
+	s.app.Post("/jobs", s.guard(auth.ScopeScrape), func(c *fiber.Ctx) error {
+		var req ScrapeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return bodyError(err)
+		}
+
+		if req.URL == "" {
+			return badRequest("URL is required")
+		}
+
+		job, err := manager.Submit(s.callerContext(context.Background(), c), owner(c), req.URL, req.Params)
+		if err != nil {
+			return err
+		}
+		s.countScrape(c)
+
+		return c.Status(fiber.StatusAccepted).JSON(JobResponse{
+			JobID:  job.ID,
+			Status: job.Status,
+		})
+	})
+
+	// Jobs of other keys are reported as missing, so their IDs cannot be
+	// probed.
+	ownedJob := func(c *fiber.Ctx) (*jobs.Job, error) {
+		job, err := manager.Get(c.Params("id"))
+		if err != nil {
+			return nil, err
+		}
+		if !owns(c, job.Owner) {
+			return nil, jobs.ErrJobNotFound
+		}
+		return job, nil
+	}
+
+	s.app.Get("/jobs/:id", s.followUp(auth.ScopeScrape), func(c *fiber.Ctx) error {
+		job, err := ownedJob(c)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(job)
+	})
+
+	s.app.Get("/jobs/:id/result", s.followUp(auth.ScopeScrape), func(c *fiber.Ctx) error {
+		job, err := ownedJob(c)
+		if err != nil {
+			return err
+		}
+
+		if job.Status != jobs.StatusDone {
+			conflict := newHTTPError(fiber.StatusConflict, "job_not_done", "job has no result")
+			conflict.Retryable = !job.Status.Finished()
+			conflict.Details = fiber.Map{"status": job.Status, "reason": job.Error}
+			return conflict
+		}
+
+		return c.JSON(newScrapeResponse(job.Result))
+	})
+
+	s.app.Delete("/jobs/:id", s.followUp(auth.ScopeScrape), func(c *fiber.Ctx) error {
+		if _, err := ownedJob(c); err != nil {
+			return err
+		}
+
+		job, err := manager.Cancel(c.Params("id"))
+		if err == jobs.ErrJobFinished {
+			conflict := newHTTPError(fiber.StatusConflict, "job_finished", "%v", err)
+			conflict.Details = fiber.Map{"status": job.Status}
+			return conflict
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(job)
+	})
+}
+
+type JobResponse struct {
+	JobID  string      `json:"jobId"`
+	Status jobs.Status `json:"status"`
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// openAPISpec describes the API's routes and types. Keep it in step with
+// the request and response structs.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (s *Server) setupOpenAPIRoutes() {
+	s.app.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(openAPISpec)
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	// The artifact route is only served by the disk store.
+	api := newFakeLLMServer(&config.Config{ArtifactStore: "disk", ArtifactDir: t.TempDir()})
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if resp := do(t, server, "GET", "/openapi.json", "", nil, &spec); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /openapi.json status = %d", resp.StatusCode)
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range api.app.GetRoutes(true) {
+		// Fiber answers HEAD for every GET route by itself.
+		if route.Method == http.MethodHead {
+			continue
+		}
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("%s %s is served but missing from openapi.json", route.Method, path)
+		}
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			if !registered[method+" "+path] {
+				t.Errorf("openapi.json describes %s %s, which is not served", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
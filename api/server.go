@@ -2,34 +2,57 @@ package api
 
 import (
 	"log"
+	"net/http"
 
+	"github.com/Sagn1k/scarab/auth"
 	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
 	"github.com/Sagn1k/scarab/scraper"
+	"github.com/Sagn1k/scarab/usage"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
 )
 
 type Server struct {
-	app    *fiber.App
-	config *config.Config
+	app        *fiber.App
+	config     *config.Config
+	usage      *usage.Tracker
+	keys       *auth.Keyring
+	keyLimiter *auth.RateLimiter
+	scraper    *scraper.ScraperService
 }
 
 func NewServer(cfg *config.Config) *Server {
 	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		ErrorHandler: handleError,
 	})
 
+	app.Use(requestid.New(requestid.Config{
+		Generator:  uuid.NewString,
+		ContextKey: requestIDLocal,
+	}))
 	app.Use(logger.New())
 	app.Use(recover.New())
 
+	keys, err := auth.Load(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+	if !keys.Enabled() {
+		log.Println("Warning: No API keys configured, the API is open to anyone")
+	}
+
 	server := &Server{
-		app:    app,
-		config: cfg,
+		app:        app,
+		config:     cfg,
+		usage:      usage.NewTracker(cfg.UsageRetentionDays),
+		keys:       keys,
+		keyLimiter: auth.NewRateLimiter(),
+		scraper:    scraper.NewScraperService(cfg),
 	}
 
 	server.registerRoutes()
@@ -47,6 +70,18 @@ func (s *Server) Start() {
 	log.Fatal(s.app.Listen(":" + port))
 }
 
+// Scraper returns the service the routes scrape with, e.g. to give it a
+// stub renderer in tests.
+func (s *Server) Scraper() *scraper.ScraperService {
+	return s.scraper
+}
+
+// Handler returns the API as an http.Handler, for serving it from net/http,
+// e.g. in an httptest.Server.
+func (s *Server) Handler() http.Handler {
+	return adaptor.FiberApp(s.app)
+}
+
 func (s *Server) registerRoutes() {
 	s.app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -55,42 +90,79 @@ func (s *Server) registerRoutes() {
 		})
 	})
 
-	s.setupScraperRoutes()
+	scraperService := s.scraper
+
+	s.setupScraperRoutes(scraperService)
+	s.setupStreamRoutes(scraperService)
+	s.setupBatchRoutes(scraperService)
+	s.setupExtractRoutes(scraperService)
+	s.setupJobRoutes(scraperService)
+	s.setupCrawlRoutes(scraperService)
+	s.setupArtifactRoutes(scraperService)
+	s.setupUsageRoutes()
+	s.setupOpenAPIRoutes()
 }
 
-func (s *Server) setupScraperRoutes() {
-	scraperService := scraper.NewScraperService(s.config)
-
-	s.app.Post("/scrape", func(c *fiber.Ctx) error {
+func (s *Server) setupScraperRoutes(scraperService *scraper.ScraperService) {
+	s.app.Post("/scrape", s.guard(auth.ScopeScrape), func(c *fiber.Ctx) error {
 		var req ScrapeRequest
 		if err := c.BodyParser(&req); err != nil {
-			return err
+			return bodyError(err)
 		}
 
 		if req.URL == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "URL is required",
-			})
+			return badRequest("URL is required")
 		}
 
-		result, err := scraperService.Scrape(c.Context(), req.URL, req.Params)
+		ctx, cancel := requestContext(c)
+		defer cancel()
+
+		s.countScrape(c)
+		result, err := scraperService.Scrape(s.callerContext(ctx, c), req.URL, req.Params)
 		if err != nil {
 			return err
 		}
 
-		return c.JSON(ScrapeResponse{
-			Success:  true,
-			Markdown: result,
-		})
+		return c.JSON(newScrapeResponse(result))
 	})
 }
 
 type ScrapeRequest struct {
-	URL    string                 `json:"url"`
-	Params map[string]interface{} `json:"params"`
+	URL    string                `json:"url"`
+	Params scraper.ScrapeOptions `json:"params"`
 }
 
 type ScrapeResponse struct {
 	Success  bool   `json:"success"`
-	Markdown string `json:"markdown"`
+	Markdown string `json:"markdown,omitempty"`
+	scraper.Outputs
+	Metadata   *scraper.Metadata         `json:"metadata,omitempty"`
+	Actions    []scraper.ActionResult    `json:"actions,omitempty"`
+	Pagination *scraper.PaginationResult `json:"pagination,omitempty"`
+	Conversion *scraper.ConversionInfo   `json:"conversion,omitempty"`
+	Usage      *llm.Usage                `json:"usage,omitempty"`
+	Cache      string                    `json:"cache,omitempty"`
+}
+
+func newScrapeResponse(result *scraper.ScrapeResult) ScrapeResponse {
+	return ScrapeResponse{
+		Success:    true,
+		Markdown:   result.Markdown,
+		Outputs:    result.Outputs,
+		Metadata:   result.Metadata,
+		Actions:    result.Actions,
+		Pagination: result.Pagination,
+		Conversion: conversionInfo(result),
+		Usage:      &result.Usage,
+		Cache:      result.Cache,
+	}
+}
+
+// conversionInfo returns how the result's markdown was made, or nil when
+// markdown was not requested.
+func conversionInfo(result *scraper.ScrapeResult) *scraper.ConversionInfo {
+	if result.Conversion.Converter == "" {
+		return nil
+	}
+	return &result.Conversion
 }
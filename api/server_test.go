@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/scraper"
+)
+
+// newTestServer serves the API for cfg. The LLM defaults to the fake
+// provider.
+func newTestServer(t *testing.T, cfg *config.Config) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(newFakeLLMServer(cfg).Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newFakeLLMServer is NewServer with the fake LLM provider registered, and
+// used unless cfg names another provider.
+func newFakeLLMServer(cfg *config.Config) *Server {
+	if cfg.LLMProvider == "" {
+		cfg.LLMProvider = "fake"
+	}
+	server := NewServer(cfg)
+	server.Scraper().LLM().RegisterProvider(llm.NewFakeProvider())
+	return server
+}
+
+// newRenderingServer is newTestServer with pages rendered by r instead of a
+// browser, and converted natively unless cfg says otherwise.
+func newRenderingServer(t *testing.T, cfg *config.Config, r scraper.Renderer) *httptest.Server {
+	t.Helper()
+	if cfg.Converter == "" {
+		cfg.Converter = scraper.ConverterNative
+	}
+	api := newFakeLLMServer(cfg)
+	api.Scraper().SetRenderer(r)
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// pageRenderer serves a page with the URL as its heading, after delay.
+// It records the most renders it saw in flight at once.
+type pageRenderer struct {
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (r *pageRenderer) RenderPage(ctx context.Context, url string, options *scraper.RenderOptions) (*scraper.RenderResult, error) {
+	r.mu.Lock()
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &scraper.RenderResult{HTML: "<h1>" + url + "</h1>", URL: url}, nil
+}
+
+func (r *pageRenderer) peak() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxInFlight
+}
+
+// do sends a request with an optional JSON body and API key, and decodes
+// the JSON response into out if it is not nil.
+func do(t *testing.T, server *httptest.Server, method, path, key string, body interface{}, out interface{}) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/errors"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StreamDone is the data of the final "done" event of /scrape/stream.
+type StreamDone struct {
+	URL string `json:"url"`
+	// Formats other than markdown, which is streamed, arrive here.
+	scraper.Outputs
+	Metadata   *scraper.Metadata         `json:"metadata,omitempty"`
+	Actions    []scraper.ActionResult    `json:"actions,omitempty"`
+	Pagination *scraper.PaginationResult `json:"pagination,omitempty"`
+	Conversion *scraper.ConversionInfo   `json:"conversion,omitempty"`
+	Usage      llm.Usage                 `json:"usage"`
+	Cache      string                    `json:"cache,omitempty"`
+}
+
+func (s *Server) setupStreamRoutes(scraperService *scraper.ScraperService) {
+	handler := func(c *fiber.Ctx) error {
+		var req ScrapeRequest
+		if c.Method() == fiber.MethodGet {
+			// EventSource can only GET, so params come as a JSON query value.
+			req.URL = c.Query("url")
+			if raw := c.Query("params"); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &req.Params); err != nil {
+					if errors.IsType(err, errors.ErrInvalidParams) {
+						return err
+					}
+					return badRequest("params must be a JSON object")
+				}
+			}
+		} else if err := c.BodyParser(&req); err != nil {
+			return bodyError(err)
+		}
+
+		if req.URL == "" {
+			return badRequest("URL is required")
+		}
+
+		// The stream writer runs after the handler returns, when c is no
+		// longer valid.
+		usageCtx := s.callerContext(context.Background(), c)
+		reqID := requestID(c)
+		s.countScrape(c)
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			ctx, cancel := context.WithCancel(usageCtx)
+			defer cancel()
+
+			events := &eventWriter{w: w, cancel: cancel}
+			ctx = scraper.WithProgress(ctx, func(stage scraper.Stage) {
+				events.send("progress", fiber.Map{"stage": stage})
+			})
+			ctx = llm.WithDeltas(ctx, func(delta string) {
+				events.send("markdown", fiber.Map{"delta": delta})
+			})
+
+			// Rendering can take a while without any events; keep proxies
+			// from timing out the connection.
+			stop := make(chan struct{})
+			defer close(stop)
+			go func() {
+				ticker := time.NewTicker(15 * time.Second)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stop:
+						return
+					case <-ticker.C:
+						events.comment("keep-alive")
+					}
+				}
+			}()
+
+			result, err := scraperService.Scrape(ctx, req.URL, req.Params)
+			if err != nil {
+				events.send("error", classify(err).info(reqID))
+				return
+			}
+
+			events.send("done", StreamDone{
+				URL:        result.URL,
+				Outputs:    result.Outputs,
+				Metadata:   result.Metadata,
+				Actions:    result.Actions,
+				Pagination: result.Pagination,
+				Conversion: conversionInfo(result),
+				Usage:      result.Usage,
+				Cache:      result.Cache,
+			})
+		})
+
+		return nil
+	}
+
+	s.app.Get("/scrape/stream", s.guard(auth.ScopeScrape), handler)
+	s.app.Post("/scrape/stream", s.guard(auth.ScopeScrape), handler)
+}
+
+// eventWriter writes server-sent events. A failed write means the client
+// went away, so it cancels the scrape and drops later events.
+type eventWriter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	cancel context.CancelFunc
+	failed bool
+}
+
+func (e *eventWriter) send(event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	e.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload))
+}
+
+func (e *eventWriter) comment(text string) {
+	e.write(": " + text + "\n\n")
+}
+
+func (e *eventWriter) write(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.failed {
+		return
+	}
+	_, err := e.w.WriteString(s)
+	if err == nil {
+		err = e.w.Flush()
+	}
+	if err != nil {
+		e.failed = true
+		e.cancel()
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Sagn1k/scarab/artifacts"
+	"github.com/Sagn1k/scarab/auth"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/usage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKey identifies the caller for usage accounting: the authenticated
+// key's ID, or usage.Anonymous when authentication is off. An unchecked
+// header is never used, as it would expose secrets in reports and let
+// callers grow the tracker without bound.
+func apiKey(c *fiber.Ctx) string {
+	if key := authKey(c); key != nil {
+		return key.ID
+	}
+	return usage.Anonymous
+}
+
+// callerContext attributes the LLM usage of work done with ctx to the
+// caller's API key, and makes the caller the owner of stored artifacts.
+func (s *Server) callerContext(ctx context.Context, c *fiber.Ctx) context.Context {
+	ctx = artifacts.WithOwner(ctx, owner(c))
+	return llm.WithUsageRecorder(ctx, s.usage.Recorder(apiKey(c)))
+}
+
+// countScrape counts one scrape started for the caller against its request
+// quotas. Polling jobs and crawls or downloading artifacts costs nothing.
+func (s *Server) countScrape(c *fiber.Ctx) {
+	s.usage.RecordRequest(apiKey(c))
+}
+
+// scrapeCounter is countScrape for work that outlives c, such as each item
+// of a batch or page of a crawl.
+func (s *Server) scrapeCounter(c *fiber.Ctx) func() {
+	key := apiKey(c)
+	return func() { s.usage.RecordRequest(key) }
+}
+
+func (s *Server) setupUsageRoutes() {
+	// Admins can read any key's usage with ?key=<id>, or list the keys
+	// with usage at /usage/keys.
+	s.app.Get("/usage", s.identify, func(c *fiber.Ctx) error {
+		id := apiKey(c)
+		if other := c.Query("key"); other != "" && other != id {
+			if key := authKey(c); key == nil || !key.Allows(auth.ScopeAdmin) {
+				return forbidden("reading another key's usage needs the admin scope")
+			}
+			id = other
+		}
+		return c.JSON(s.usage.Report(id))
+	})
+
+	s.app.Get("/usage/keys", s.identify, func(c *fiber.Ctx) error {
+		if key := authKey(c); key == nil || !key.Allows(auth.ScopeAdmin) {
+			return forbidden("listing keys needs the admin scope")
+		}
+		return c.JSON(fiber.Map{"keys": s.usage.Keys()})
+	})
+}
@@ -0,0 +1,78 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Disk writes each artifact to its own file under dir, named with a random
+// UUID so that URLs cannot be guessed. The owner, when there is one, is
+// written next to it in a file with an extra ".owner" extension. Files are
+// kept until removed by hand or by an external job.
+type Disk struct {
+	dir     string
+	baseURL string
+}
+
+// NewDisk stores artifacts under dir. Their URLs are baseURL followed by
+// the file name, so baseURL must serve dir, e.g. the API's /artifacts route
+// or a web server or bucket synced with it.
+func NewDisk(dir, baseURL string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &Disk{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (d *Disk) Put(ctx context.Context, contentType string, data []byte) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	name := uuid.NewString() + extension(contentType)
+	if owner := OwnerFrom(ctx); owner != "" {
+		if err := os.WriteFile(filepath.Join(d.dir, name+ownerExtension), []byte(owner), 0o644); err != nil {
+			return "", fmt.Errorf("failed to store artifact owner: %w", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, name), data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to store artifact: %w", err)
+	}
+	return d.baseURL + "/" + name, nil
+}
+
+const ownerExtension = ".owner"
+
+var artifactName = regexp.MustCompile(`^[0-9a-f-]{36}\.[a-z0-9]+$`)
+
+// Path returns the file of the artifact called name. Names that Put could
+// not have produced are refused, so requests cannot reach other files.
+func (d *Disk) Path(name string) (string, bool) {
+	if !artifactName.MatchString(name) {
+		return "", false
+	}
+	path := filepath.Join(d.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// Owner returns the owner the artifact called name was stored for, or
+// empty when it has none.
+func (d *Disk) Owner(name string) string {
+	if !artifactName.MatchString(name) {
+		return ""
+	}
+	owner, err := os.ReadFile(filepath.Join(d.dir, name+ownerExtension))
+	if err != nil {
+		return ""
+	}
+	return string(owner)
+}
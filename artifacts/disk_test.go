@@ -0,0 +1,118 @@
+package artifacts
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestDisk(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		contentType string
+		wantPrefix  string
+		wantExt     string
+	}{
+		{"png", "https://example.com/artifacts", "image/png", "https://example.com/artifacts/", ".png"},
+		{"jpeg", "https://example.com/artifacts", "image/jpeg", "https://example.com/artifacts/", ".jpg"},
+		{"pdf", "https://example.com/artifacts/", "application/pdf", "https://example.com/artifacts/", ".pdf"},
+		{"unknown type", "/artifacts", "application/x-scarab", "/artifacts/", ".bin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDisk(t.TempDir()+"/nested", tt.baseURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url, err := d.Put(context.Background(), tt.contentType, []byte("data"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(url, tt.wantPrefix) || !strings.HasSuffix(url, tt.wantExt) {
+				t.Errorf("Put() = %q, want %s<name>%s", url, tt.wantPrefix, tt.wantExt)
+			}
+
+			file, ok := d.Path(path.Base(url))
+			if !ok {
+				t.Fatalf("Path(%q) found nothing", path.Base(url))
+			}
+			if data, err := os.ReadFile(file); err != nil || string(data) != "data" {
+				t.Errorf("stored file = %q, %v, want data", data, err)
+			}
+		})
+	}
+}
+
+func TestDiskPathRefusesOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, "/artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/notes.png", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"",
+		"notes.png",
+		"../disk.go",
+		"../00000000-0000-0000-0000-000000000000.png",
+		"00000000-0000-0000-0000-000000000000",
+		"00000000-0000-0000-0000-000000000000.png",
+		"0000000A-0000-0000-0000-000000000000.png",
+	} {
+		if path, ok := d.Path(name); ok {
+			t.Errorf("Path(%q) = %q, want refused", name, path)
+		}
+	}
+}
+
+func TestDiskPutCancelled(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, "/artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if url, err := d.Put(ctx, "image/png", []byte("data")); err == nil {
+		t.Errorf("Put() = %q, want an error", url)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("a cancelled Put wrote %d files", len(entries))
+	}
+}
+
+func TestDiskOwner(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, "/artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owned, err := d.Put(WithOwner(context.Background(), "scraper"), "image/png", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unowned, err := d.Put(context.Background(), "image/png", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if owner := d.Owner(path.Base(owned)); owner != "scraper" {
+		t.Errorf("Owner() = %q, want scraper", owner)
+	}
+	if owner := d.Owner(path.Base(unowned)); owner != "" {
+		t.Errorf("Owner() of an artifact stored without one = %q", owner)
+	}
+	if file, ok := d.Path(path.Base(owned) + ownerExtension); ok {
+		t.Errorf("Path() serves the owner file %q", file)
+	}
+}
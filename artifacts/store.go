@@ -0,0 +1,43 @@
+package artifacts
+
+import (
+	"context"
+	"mime"
+)
+
+// Store keeps files produced by a scrape, such as screenshots, and hands
+// out URLs to fetch them. Implementations are safe for concurrent use.
+type Store interface {
+	// Put stores data under a new name and returns its URL.
+	Put(ctx context.Context, contentType string, data []byte) (string, error)
+}
+
+type ownerKey struct{}
+
+// WithOwner returns a context under which stored artifacts belong to owner,
+// the ID of the API key they were captured for.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFrom returns the owner set by WithOwner, or empty.
+func OwnerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// extension returns the file extension for contentType, e.g. ".png".
+func extension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "application/pdf":
+		return ".pdf"
+	}
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+type Scope string
+
+const (
+	ScopeScrape  Scope = "scrape"
+	ScopeCrawl   Scope = "crawl"
+	ScopeExtract Scope = "extract"
+	// ScopeAdmin grants every other scope and access to all keys' usage.
+	ScopeAdmin Scope = "admin"
+)
+
+// Quota limits a key's use per UTC day and month. Zero means unlimited.
+type Quota struct {
+	DailyRequests   int `json:"dailyRequests"`
+	MonthlyRequests int `json:"monthlyRequests"`
+	DailyTokens     int `json:"dailyTokens"`
+	MonthlyTokens   int `json:"monthlyTokens"`
+}
+
+// Key is an API key and what it may do. ID names the key in usage reports
+// and logs so the secret itself is never shown.
+type Key struct {
+	ID     string  `json:"id"`
+	Secret string  `json:"key"`
+	Scopes []Scope `json:"scopes"`
+	Quota
+	// RateLimit is the requests per second allowed to the key, with bursts
+	// of up to RateBurst. Zero disables it.
+	RateLimit float64 `json:"rateLimit"`
+	RateBurst int     `json:"rateBurst"`
+}
+
+func (k *Key) Allows(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Keyring looks keys up by their secret.
+type Keyring struct {
+	bySecret map[[sha256.Size]byte]*Key
+}
+
+// Load builds the keyring from API_KEYS, which grants every listed secret
+// all scopes except admin under the configured default quota, and from the
+// JSON array in API_KEYS_FILE, whose entries set their own scopes, quotas
+// and rate limits. Fields a file entry leaves at zero take the defaults.
+func Load(cfg *config.Config) (*Keyring, error) {
+	defaults := Key{
+		Scopes: []Scope{ScopeScrape, ScopeCrawl, ScopeExtract},
+		Quota: Quota{
+			DailyRequests:   cfg.APIKeyDailyRequests,
+			MonthlyRequests: cfg.APIKeyMonthlyRequests,
+			DailyTokens:     cfg.APIKeyDailyTokens,
+			MonthlyTokens:   cfg.APIKeyMonthlyTokens,
+		},
+		RateLimit: cfg.APIKeyRateLimit,
+		RateBurst: cfg.APIKeyRateBurst,
+	}
+
+	var keys []Key
+	for i, secret := range cfg.APIKeys {
+		key := defaults
+		key.ID = fmt.Sprintf("key-%d", i+1)
+		key.Secret = secret
+		keys = append(keys, key)
+	}
+
+	if cfg.APIKeysFile != "" {
+		data, err := os.ReadFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API keys file: %w", err)
+		}
+		var fileKeys []Key
+		if err := json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+		}
+		for _, key := range fileKeys {
+			keys = append(keys, withDefaults(key, defaults))
+		}
+	}
+
+	ring := &Keyring{bySecret: make(map[[sha256.Size]byte]*Key)}
+	ids := make(map[string]bool)
+	for i := range keys {
+		key := &keys[i]
+		if key.Secret == "" {
+			return nil, fmt.Errorf("API key %q has no secret", key.ID)
+		}
+		if key.ID == "" {
+			key.ID = fmt.Sprintf("key-%d", i+1)
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate API key id %q", key.ID)
+		}
+		ids[key.ID] = true
+		ring.bySecret[sha256.Sum256([]byte(key.Secret))] = key
+	}
+
+	return ring, nil
+}
+
+func withDefaults(key, defaults Key) Key {
+	if len(key.Scopes) == 0 {
+		key.Scopes = defaults.Scopes
+	}
+	if key.DailyRequests == 0 {
+		key.DailyRequests = defaults.DailyRequests
+	}
+	if key.MonthlyRequests == 0 {
+		key.MonthlyRequests = defaults.MonthlyRequests
+	}
+	if key.DailyTokens == 0 {
+		key.DailyTokens = defaults.DailyTokens
+	}
+	if key.MonthlyTokens == 0 {
+		key.MonthlyTokens = defaults.MonthlyTokens
+	}
+	if key.RateLimit == 0 {
+		key.RateLimit = defaults.RateLimit
+	}
+	if key.RateBurst == 0 {
+		key.RateBurst = defaults.RateBurst
+	}
+	return key
+}
+
+// Enabled reports whether any keys are configured. Without keys the API is
+// open.
+func (r *Keyring) Enabled() bool {
+	return len(r.bySecret) > 0
+}
+
+// Lookup returns the key with the given secret. Secrets are compared by
+// hash so lookups take the same time whatever the input.
+func (r *Keyring) Lookup(secret string) (*Key, bool) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return nil, false
+	}
+	key, ok := r.bySecret[sha256.Sum256([]byte(secret))]
+	return key, ok
+}
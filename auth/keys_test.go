@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+func TestLoad(t *testing.T) {
+	defaults := &config.Config{
+		APIKeyDailyRequests: 100,
+		APIKeyMonthlyTokens: 5000,
+		APIKeyRateLimit:     2,
+		APIKeyRateBurst:     4,
+	}
+	defaultScopes := []Scope{ScopeScrape, ScopeCrawl, ScopeExtract}
+
+	tests := []struct {
+		name    string
+		keys    []string
+		file    string
+		want    map[string]Key
+		wantErr string
+	}{
+		{
+			name: "no keys",
+			want: map[string]Key{},
+		},
+		{
+			name: "keys from the environment",
+			keys: []string{"s1", "s2"},
+			want: map[string]Key{
+				"s1": {ID: "key-1", Secret: "s1", Scopes: defaultScopes, Quota: Quota{DailyRequests: 100, MonthlyTokens: 5000}, RateLimit: 2, RateBurst: 4},
+				"s2": {ID: "key-2", Secret: "s2", Scopes: defaultScopes, Quota: Quota{DailyRequests: 100, MonthlyTokens: 5000}, RateLimit: 2, RateBurst: 4},
+			},
+		},
+		{
+			name: "file entries fill in the defaults",
+			file: `[{"id":"admin","key":"s3","scopes":["admin"],"dailyRequests":7},{"key":"s4"}]`,
+			want: map[string]Key{
+				"s3": {ID: "admin", Secret: "s3", Scopes: []Scope{ScopeAdmin}, Quota: Quota{DailyRequests: 7, MonthlyTokens: 5000}, RateLimit: 2, RateBurst: 4},
+				"s4": {ID: "key-2", Secret: "s4", Scopes: defaultScopes, Quota: Quota{DailyRequests: 100, MonthlyTokens: 5000}, RateLimit: 2, RateBurst: 4},
+			},
+		},
+		{
+			name:    "missing secret",
+			file:    `[{"id":"a"}]`,
+			wantErr: `API key "a" has no secret`,
+		},
+		{
+			name:    "duplicate id",
+			keys:    []string{"s1"},
+			file:    `[{"id":"key-1","key":"s2"}]`,
+			wantErr: `duplicate API key id "key-1"`,
+		},
+		{
+			name:    "invalid file",
+			file:    `{"id":"a"}`,
+			wantErr: "failed to parse API keys file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *defaults
+			cfg.APIKeys = tt.keys
+			if tt.file != "" {
+				cfg.APIKeysFile = filepath.Join(t.TempDir(), "keys.json")
+				if err := os.WriteFile(cfg.APIKeysFile, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ring, err := Load(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if ring.Enabled() != (len(tt.want) > 0) {
+				t.Errorf("Enabled() = %v", ring.Enabled())
+			}
+			for secret, want := range tt.want {
+				got, ok := ring.Lookup(secret)
+				if !ok {
+					t.Errorf("Lookup(%q) found nothing", secret)
+					continue
+				}
+				if !reflect.DeepEqual(*got, want) {
+					t.Errorf("Lookup(%q) = %+v, want %+v", secret, *got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(&config.Config{APIKeysFile: filepath.Join(t.TempDir(), "missing.json")})
+	if err == nil || !strings.Contains(err.Error(), "failed to read API keys file") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ring, err := Load(&config.Config{APIKeys: []string{"secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		secret string
+		want   bool
+	}{
+		{"secret", true},
+		{"  secret\n", true},
+		{"Secret", false},
+		{"secret2", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, ok := ring.Lookup(tt.secret); ok != tt.want {
+			t.Errorf("Lookup(%q) found = %v, want %v", tt.secret, ok, tt.want)
+		}
+	}
+}
+
+func TestKeyAllows(t *testing.T) {
+	tests := []struct {
+		scopes []Scope
+		scope  Scope
+		want   bool
+	}{
+		{[]Scope{ScopeScrape}, ScopeScrape, true},
+		{[]Scope{ScopeScrape}, ScopeCrawl, false},
+		{[]Scope{ScopeScrape}, ScopeAdmin, false},
+		{[]Scope{ScopeAdmin}, ScopeExtract, true},
+		{nil, ScopeScrape, false},
+	}
+	for _, tt := range tests {
+		key := &Key{Scopes: tt.scopes}
+		if got := key.Allows(tt.scope); got != tt.want {
+			t.Errorf("Key%v.Allows(%s) = %v, want %v", tt.scopes, tt.scope, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket per key.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from key's bucket. If none is left it returns false
+// and how long until one is.
+func (l *RateLimiter) Allow(key *Key) (bool, time.Duration) {
+	if key.RateLimit <= 0 {
+		return true, 0
+	}
+	burst := float64(key.RateBurst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key.ID]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key.ID] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * key.RateLimit
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / key.RateLimit * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         Key
+		calls       int
+		wantAllowed int
+	}{
+		{"unlimited", Key{ID: "a"}, 50, 50},
+		{"burst", Key{ID: "b", RateLimit: 0.01, RateBurst: 3}, 5, 3},
+		{"burst of at least one", Key{ID: "c", RateLimit: 0.01}, 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter()
+			allowed := 0
+			for i := 0; i < tt.calls; i++ {
+				if ok, _ := limiter.Allow(&tt.key); ok {
+					allowed++
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of %d calls, want %d", allowed, tt.calls, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitAndRefill(t *testing.T) {
+	limiter := NewRateLimiter()
+	key := &Key{ID: "k", RateLimit: 50, RateBurst: 1}
+
+	if ok, _ := limiter.Allow(key); !ok {
+		t.Fatal("first call was limited")
+	}
+	ok, wait := limiter.Allow(key)
+	if ok {
+		t.Fatal("second call was allowed")
+	}
+	if wait <= 0 || wait > 20*time.Millisecond {
+		t.Errorf("wait = %v, want up to 20ms at 50 per second", wait)
+	}
+
+	time.Sleep(wait + 5*time.Millisecond)
+	if ok, _ := limiter.Allow(key); !ok {
+		t.Error("call after the wait was limited")
+	}
+
+	// Buckets are per key.
+	if ok, _ := limiter.Allow(&Key{ID: "other", RateLimit: 50, RateBurst: 1}); !ok {
+		t.Error("another key was limited")
+	}
+}
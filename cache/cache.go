@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Cache stores byte values under string keys for a limited time.
+// Implementations are safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key unless it is missing or has
+	// expired.
+	Get(key string) ([]byte, bool)
+	// Set stores value under key. A ttl of zero or less never expires.
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+// Key derives a content-addressed key from its parts, so equal inputs share
+// an entry whatever their length or characters.
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func expired(expires time.Time) bool {
+	return !expires.IsZero() && time.Now().After(expires)
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	backends := map[string]func(t *testing.T) Cache{
+		"memory": func(t *testing.T) Cache {
+			return NewMemory(10)
+		},
+		"disk": func(t *testing.T) Cache {
+			disk, err := NewDisk(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewDisk() error = %v", err)
+			}
+			return disk
+		},
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, c Cache)
+	}{
+		{"missing", func(t *testing.T, c Cache) {
+			if _, ok := c.Get("nope"); ok {
+				t.Error("Get() found a key that was never set")
+			}
+		}},
+		{"set and get", func(t *testing.T, c Cache) {
+			c.Set("k", []byte("v"), time.Minute)
+			if got, ok := c.Get("k"); !ok || string(got) != "v" {
+				t.Errorf("Get() = %q, %v, want v", got, ok)
+			}
+		}},
+		{"empty value", func(t *testing.T, c Cache) {
+			c.Set("k", nil, 0)
+			if got, ok := c.Get("k"); !ok || len(got) != 0 {
+				t.Errorf("Get() = %q, %v, want an empty value", got, ok)
+			}
+		}},
+		{"overwrite", func(t *testing.T, c Cache) {
+			c.Set("k", []byte("old"), time.Minute)
+			c.Set("k", []byte("new"), time.Minute)
+			if got, _ := c.Get("k"); string(got) != "new" {
+				t.Errorf("Get() = %q, want new", got)
+			}
+		}},
+		{"delete", func(t *testing.T, c Cache) {
+			c.Set("k", []byte("v"), 0)
+			c.Delete("k")
+			c.Delete("never set")
+			if _, ok := c.Get("k"); ok {
+				t.Error("Get() found a deleted key")
+			}
+		}},
+		{"expiry", func(t *testing.T, c Cache) {
+			c.Set("short", []byte("v"), 10*time.Millisecond)
+			c.Set("forever", []byte("v"), 0)
+			time.Sleep(30 * time.Millisecond)
+			if _, ok := c.Get("short"); ok {
+				t.Error("Get() found an expired key")
+			}
+			if _, ok := c.Get("forever"); !ok {
+				t.Error("Get() lost a key without a ttl")
+			}
+		}},
+		{"keys of any length", func(t *testing.T, c Cache) {
+			long := string(make([]byte, 5000)) + "/../x"
+			c.Set(long, []byte("v"), 0)
+			if got, ok := c.Get(long); !ok || string(got) != "v" {
+				t.Errorf("Get() = %q, %v, want v", got, ok)
+			}
+		}},
+	}
+
+	for name, newCache := range backends {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				tt.run(t, newCache(t))
+			})
+		}
+	}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewMemory(2)
+	m.Set("a", []byte("1"), 0)
+	m.Set("b", []byte("2"), 0)
+	m.Get("a")
+	m.Set("c", []byte("3"), 0)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := m.Get(key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestMemoryUnbounded(t *testing.T) {
+	m := NewMemory(0)
+	for i := 0; i < 100; i++ {
+		m.Set(string(rune('a'+i)), []byte("v"), 0)
+	}
+	if m.order.Len() != 100 {
+		t.Errorf("kept %d entries, want 100", m.order.Len())
+	}
+}
+
+func TestDiskPersistsAndRemovesExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := NewDisk(dir)
+	first.Set("kept", []byte("v"), time.Hour)
+	first.Set("expiring", []byte("v"), time.Millisecond)
+
+	second, err := NewDisk(dir)
+	if err != nil {
+		t.Fatalf("NewDisk() error = %v", err)
+	}
+	if got, ok := second.Get("kept"); !ok || string(got) != "v" {
+		t.Errorf("Get() from a new instance = %q, %v, want v", got, ok)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := second.Get("expiring"); ok {
+		t.Error("Get() found an expired key")
+	}
+	if _, err := os.Stat(second.path("expiring")); !os.IsNotExist(err) {
+		t.Errorf("expired file was not removed: %v", err)
+	}
+
+	temps, _ := filepath.Glob(filepath.Join(dir, "*", ".tmp-*"))
+	if len(temps) != 0 {
+		t.Errorf("temporary files left behind: %v", temps)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("a", "b") != Key("a", "b") {
+		t.Error("Key() differs for equal parts")
+	}
+	if Key("a", "b") == Key("ab") || Key("a", "b") == Key("a", "c") {
+		t.Error("Key() is equal for different parts")
+	}
+	if len(Key("x")) != 64 {
+		t.Errorf("Key() = %q, want a hex SHA-256", Key("x"))
+	}
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Disk stores each value in its own file under dir, so entries survive
+// restarts and can be shared by processes on one machine. Expired files are
+// removed when read.
+type Disk struct {
+	dir string
+}
+
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Disk{dir: dir}, nil
+}
+
+// path spreads the files over subdirectories named after the first two
+// characters of the hashed key.
+func (d *Disk) path(key string) string {
+	name := Key(key)
+	return filepath.Join(d.dir, name[:2], name)
+}
+
+// Each file starts with the expiry as Unix nanoseconds (0 for none),
+// followed by the value.
+const diskHeaderSize = 8
+
+func (d *Disk) Get(key string) ([]byte, bool) {
+	path := d.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) < diskHeaderSize {
+		return nil, false
+	}
+
+	var expires time.Time
+	if nanos := int64(binary.BigEndian.Uint64(data[:diskHeaderSize])); nanos != 0 {
+		expires = time.Unix(0, nanos)
+	}
+	if expired(expires) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	return data[diskHeaderSize:], true
+}
+
+func (d *Disk) Set(key string, value []byte, ttl time.Duration) {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+
+	data := make([]byte, diskHeaderSize+len(value))
+	if expires := expiry(ttl); !expires.IsZero() {
+		binary.BigEndian.PutUint64(data, uint64(expires.UnixNano()))
+	}
+	copy(data[diskHeaderSize:], value)
+
+	// Write to a temporary file and rename it so readers never see a
+	// partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+func (d *Disk) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
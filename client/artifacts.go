@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ArtifactData returns the bytes of a capture, decoding it when inline and
+// downloading it otherwise. URLs served by the API are fetched with the
+// client's key; other URLs, such as a bucket's, are fetched as they are.
+func (c *Client) ArtifactData(ctx context.Context, a *Artifact) ([]byte, error) {
+	if a.URL == "" {
+		data, err := base64.StdEncoding.DecodeString(a.Data)
+		if err != nil {
+			return nil, fmt.Errorf("scarab: error decoding artifact: %w", err)
+		}
+		return data, nil
+	}
+
+	if strings.HasPrefix(a.URL, "/") || strings.HasPrefix(a.URL, c.baseURL+"/") {
+		resp, err := c.send(ctx, http.MethodGet, strings.TrimPrefix(a.URL, c.baseURL), nil, a.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return readArtifact(resp)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("scarab: error creating request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scarab: error downloading artifact: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, apiError(resp)
+	}
+	return readArtifact(resp)
+}
+
+func readArtifact(resp *http.Response) ([]byte, error) {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("scarab: error downloading artifact: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,226 @@
+// Package client calls the Scarab API over HTTP. Failures the API marks as
+// retryable, and connection errors on requests that are safe to repeat,
+// are retried with exponential backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+
+	// MaxRetries is how many times a failed call is retried. RetryBase
+	// and RetryMax bound the backoff between attempts; a Retry-After
+	// header from the API takes precedence up to RetryMax.
+	MaxRetries int
+	RetryBase  time.Duration
+	RetryMax   time.Duration
+}
+
+// New returns a client for the API at baseURL, e.g. http://localhost:3000.
+// apiKey may be empty when the server has no keys configured, and
+// httpClient nil to use http.DefaultClient.
+func New(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: httpClient,
+		MaxRetries: 3,
+		RetryBase:  500 * time.Millisecond,
+		RetryMax:   30 * time.Second,
+	}
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	// Code is the API's stable name for the failure, e.g. "timeout".
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	Retryable bool            `json:"retryable"`
+	RequestID string          `json:"requestId"`
+	Details   json.RawMessage `json:"details"`
+	// RetryAfter is the wait the API asked for, if any.
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("scarab: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+	if e.RequestID != "" {
+		msg += ", request " + e.RequestID
+	}
+	return msg
+}
+
+// do sends a JSON request and decodes a 2xx response, or one of the
+// accepted statuses, into out.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, accept ...int) error {
+	resp, err := c.send(ctx, method, path, in, "application/json", accept...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("scarab: error parsing response: %w", err)
+	}
+	return nil
+}
+
+// send sends the request, retrying as the client is configured, and
+// returns the last response. A successful response, 2xx or one of the
+// accepted statuses, has its body left for the caller to read and close.
+func (c *Client) send(ctx context.Context, method, path string, in interface{}, mediaType string, accept ...int) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("scarab: error marshaling request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("scarab: error creating request: %w", err)
+		}
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", mediaType)
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// The request may have been handled; only repeat it when
+			// that does no harm.
+			if !repeatable(method, path) || attempt >= c.MaxRetries {
+				return nil, fmt.Errorf("scarab: error sending request: %w", err)
+			}
+		case !success(resp.StatusCode, accept) && resp.StatusCode >= 400:
+			apiErr := apiError(resp)
+			resp.Body.Close()
+			if !apiErr.Retryable || attempt >= c.MaxRetries {
+				return nil, apiErr
+			}
+			retryAfter = apiErr.RetryAfter
+		default:
+			return resp, nil
+		}
+
+		if err := sleep(ctx, c.backoff(attempt, retryAfter)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// success reports whether status is 2xx or one of accept. Accepted error
+// statuses are answered with the normal body, like 422 from /extract.
+func success(status int, accept []int) bool {
+	if status >= 200 && status < 300 {
+		return true
+	}
+	for _, s := range accept {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatable reports whether a request whose outcome is unknown may be
+// sent again. Everything but creating a job or crawl is.
+func repeatable(method, path string) bool {
+	return method != http.MethodPost || (path != "/jobs" && path != "/crawl")
+}
+
+// apiError reads the error body of resp. Bodies that are not the API's
+// error format, e.g. from a proxy, are kept as the message.
+func apiError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var envelope struct {
+		Error *Error `json:"error"`
+	}
+	apiErr := &Error{}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil {
+		apiErr = envelope.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			apiErr.Retryable = true
+		}
+	}
+
+	apiErr.StatusCode = resp.StatusCode
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// backoff returns the wait before retry attempt+1: exponential with jitter,
+// at least retryAfter and at most RetryMax.
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	window := c.RetryBase << uint(attempt)
+	if window <= 0 || (c.RetryMax > 0 && window > c.RetryMax) {
+		window = c.RetryMax
+	}
+	var delay time.Duration
+	if window > 0 {
+		delay = window/2 + time.Duration(rand.Int63n(int64(window/2)+1))
+	}
+
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	if c.RetryMax > 0 && delay > c.RetryMax {
+		delay = c.RetryMax
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,551 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/api"
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/scraper"
+)
+
+const testPage = `<html><head><title>Test page</title></head><body>
+<h1>Hello</h1>
+<p>Some text with a <a href="/next">link</a>.</p>
+</body></html>`
+
+// stubRenderer serves testPage for every URL instead of opening a browser.
+// Captures are returned as the bytes of their format's name. While block is
+// open, rendering waits for it or for the request to end.
+type stubRenderer struct {
+	block chan struct{}
+}
+
+func (r *stubRenderer) RenderPage(ctx context.Context, url string, options *scraper.RenderOptions) (*scraper.RenderResult, error) {
+	if r.block != nil {
+		select {
+		case <-r.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	result := &scraper.RenderResult{HTML: testPage, URL: url, Metadata: scraper.ExtractMetadata(testPage, url)}
+	if options.Screenshot != nil {
+		result.Screenshot = []byte(options.Screenshot.Format)
+	}
+	if options.PDF != nil {
+		result.PDF = []byte("pdf")
+	}
+	return result, nil
+}
+
+// newTestAPI serves the API for cfg with the fake LLM and a stubRenderer,
+// and returns a client for it that retries without waiting long.
+func newTestAPI(t *testing.T, cfg *config.Config, renderer *stubRenderer) (*Client, *httptest.Server) {
+	t.Helper()
+	if cfg.LLMProvider == "" {
+		cfg.LLMProvider = "fake"
+	}
+	if cfg.Converter == "" {
+		cfg.Converter = "native"
+	}
+
+	server := api.NewServer(cfg)
+	server.Scraper().LLM().RegisterProvider(llm.NewFakeProvider())
+	server.Scraper().SetRenderer(renderer)
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	if renderer.block != nil {
+		// Cleanups run last first, so this unblocks requests before the
+		// server waits for them.
+		t.Cleanup(func() { close(renderer.block) })
+	}
+
+	return retryFast(New(httpServer.URL, "tester", nil)), httpServer
+}
+
+func retryFast(c *Client) *Client {
+	c.RetryBase = time.Millisecond
+	c.RetryMax = 10 * time.Millisecond
+	return c
+}
+
+func TestScrape(t *testing.T) {
+	c, _ := newTestAPI(t, &config.Config{}, &stubRenderer{})
+
+	resp, err := c.Scrape(context.Background(), "https://example.com/page", &ScrapeOptions{
+		Formats: []string{FormatMarkdown, FormatText, FormatHTML, FormatJSON},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.Success {
+		t.Error("Success = false")
+	}
+	if !strings.Contains(resp.Markdown, "# Hello") {
+		t.Errorf("Markdown = %q, want the heading", resp.Markdown)
+	}
+	if !strings.Contains(resp.Text, "Some text with a link.") {
+		t.Errorf("Text = %q", resp.Text)
+	}
+	if !strings.Contains(resp.HTML, "<h1>Hello</h1>") {
+		t.Errorf("HTML = %q", resp.HTML)
+	}
+	if resp.Document == nil || len(resp.Document.Sections) == 0 || resp.Document.Sections[0].Heading != "Hello" {
+		t.Errorf("Document = %+v, want a Hello section", resp.Document)
+	}
+	if resp.Metadata == nil || resp.Metadata.Title != "Test page" {
+		t.Errorf("Metadata = %+v, want the title", resp.Metadata)
+	}
+	if resp.Conversion == nil || resp.Conversion.Converter != "native" {
+		t.Errorf("Conversion = %+v, want native", resp.Conversion)
+	}
+}
+
+func TestScrapeWithLLM(t *testing.T) {
+	c, _ := newTestAPI(t, &config.Config{APIKeys: []string{"tester"}}, &stubRenderer{})
+	ctx := context.Background()
+
+	resp, err := c.Scrape(ctx, "https://example.com/", &ScrapeOptions{Converter: "llm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Conversion == nil || resp.Conversion.Provider != "fake" {
+		t.Errorf("Conversion = %+v, want the fake provider", resp.Conversion)
+	}
+	if resp.Usage == nil || resp.Usage.Calls != 1 || resp.Usage.TotalTokens == 0 {
+		t.Errorf("Usage = %+v, want one metered call", resp.Usage)
+	}
+
+	report, err := c.Usage(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Key != "key-1" || report.Total.Calls != 1 || report.Total.TotalTokens != resp.Usage.TotalTokens {
+		t.Errorf("Usage report = %+v, want the scrape's call for key-1", report)
+	}
+	if len(report.Days) != 1 {
+		t.Errorf("Days = %+v, want today", report.Days)
+	}
+}
+
+func TestScrapeStream(t *testing.T) {
+	c, _ := newTestAPI(t, &config.Config{}, &stubRenderer{})
+
+	var stages []string
+	var markdown strings.Builder
+	done, err := c.ScrapeStream(context.Background(), "https://example.com/", &ScrapeOptions{Converter: "llm"}, StreamHandler{
+		Progress: func(stage string) { stages = append(stages, stage) },
+		Markdown: func(delta string) { markdown.WriteString(delta) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if done.URL != "https://example.com/" || done.Usage.Calls != 1 {
+		t.Errorf("done = %+v", done)
+	}
+	if strings.Join(stages, ",") != "navigating,rendered,converting" {
+		t.Errorf("stages = %q", stages)
+	}
+	if markdown.Len() == 0 {
+		t.Error("no markdown was streamed")
+	}
+}
+
+func TestScrapeBatchAndExtract(t *testing.T) {
+	c, _ := newTestAPI(t, &config.Config{}, &stubRenderer{})
+	ctx := context.Background()
+
+	batch, err := c.ScrapeBatch(ctx, BatchRequest{Items: []ScrapeRequest{
+		{URL: "https://example.com/a"},
+		{URL: "not a url"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Results) != 2 || !batch.Results[0].Success || batch.Results[1].Success || batch.Results[1].Code != "invalid_url" {
+		t.Errorf("Results = %+v, want the first to succeed and the second to fail", batch.Results)
+	}
+
+	// The fake LLM answers JSON requests with {}, which lacks the required
+	// title.
+	extract, err := c.Extract(ctx, ExtractRequest{
+		URL: "https://example.com/",
+		Schema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}},
+			"required":   []string{"title"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extract.Success || len(extract.Errors) == 0 || extract.Errors[0].Path != "/title" {
+		t.Errorf("Extract = %+v, want a missing title", extract)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	c, _ := newTestAPI(t, &config.Config{}, &stubRenderer{})
+	ctx := context.Background()
+
+	submitted, err := c.SubmitJob(ctx, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submitted.JobID == "" || submitted.Status != JobQueued {
+		t.Fatalf("SubmitJob = %+v", submitted)
+	}
+
+	job, err := c.WaitJob(ctx, submitted.JobID, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobDone || job.URL != "https://example.com/" || job.FinishedAt == nil {
+		t.Fatalf("job = %+v, want done", job)
+	}
+
+	result, err := c.GetJobResult(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Markdown, "# Hello") {
+		t.Errorf("Markdown = %q", result.Markdown)
+	}
+
+	var apiErr *Error
+	if _, err := c.CancelJob(ctx, job.ID); !errors.As(err, &apiErr) || apiErr.Code != "job_finished" || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("CancelJob of a finished job = %v, want job_finished", err)
+	}
+	if _, err := c.GetJob(ctx, "missing"); !errors.As(err, &apiErr) || apiErr.Code != "not_found" {
+		t.Errorf("GetJob of a missing job = %v, want not_found", err)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	renderer := &stubRenderer{block: make(chan struct{})}
+	c, _ := newTestAPI(t, &config.Config{}, renderer)
+	ctx := context.Background()
+
+	submitted, err := c.SubmitJob(ctx, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var apiErr *Error
+	if _, err := c.GetJobResult(ctx, submitted.JobID); !errors.As(err, &apiErr) || apiErr.Code != "job_not_done" || !apiErr.Retryable {
+		t.Errorf("GetJobResult of a running job = %v, want a retryable job_not_done", err)
+	}
+
+	if _, err := c.CancelJob(ctx, submitted.JobID); err != nil {
+		t.Fatal(err)
+	}
+	job, err := c.WaitJob(ctx, submitted.JobID, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobCancelled {
+		t.Errorf("Status = %q, want cancelled", job.Status)
+	}
+}
+
+func TestArtifacts(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *config.Config
+		stored bool
+	}{
+		{"inline", &config.Config{}, false},
+		{"disk", &config.Config{ArtifactStore: "disk", ArtifactDir: t.TempDir(), ArtifactBaseURL: "/artifacts"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestAPI(t, tt.cfg, &stubRenderer{})
+			ctx := context.Background()
+
+			resp, err := c.Scrape(ctx, "https://example.com/", &ScrapeOptions{
+				Screenshot: &Screenshot{Format: "jpeg"},
+				PDF:        &PDF{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, capture := range []struct {
+				artifact    *Artifact
+				contentType string
+				data        string
+			}{
+				{resp.Screenshot, "image/jpeg", "jpeg"},
+				{resp.PDF, "application/pdf", "pdf"},
+			} {
+				a := capture.artifact
+				if a == nil {
+					t.Fatalf("capture %s is missing", capture.contentType)
+				}
+				if a.ContentType != capture.contentType || a.Size != len(capture.data) {
+					t.Errorf("artifact = %+v, want %s of %d bytes", a, capture.contentType, len(capture.data))
+				}
+				if (a.URL != "") != tt.stored {
+					t.Errorf("URL = %q, want stored %v", a.URL, tt.stored)
+				}
+
+				data, err := c.ArtifactData(ctx, a)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != capture.data {
+					t.Errorf("ArtifactData = %q, want %q", data, capture.data)
+				}
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _ := newTestAPI(t, &config.Config{}, &stubRenderer{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		call       func() error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			"invalid params",
+			func() error {
+				_, err := c.Scrape(ctx, "https://example.com/", &ScrapeOptions{CacheMode: "sometimes"})
+				return err
+			},
+			http.StatusBadRequest, "invalid_params", "cacheMode",
+		},
+		{
+			"invalid URL",
+			func() error {
+				_, err := c.Scrape(ctx, "ftp://example.com/", nil)
+				return err
+			},
+			http.StatusBadRequest, "invalid_url", "",
+		},
+		{
+			"missing URL",
+			func() error {
+				_, err := c.SubmitJob(ctx, "", nil)
+				return err
+			},
+			http.StatusBadRequest, "invalid_request", "",
+		},
+		{
+			"streamed error",
+			func() error {
+				_, err := c.ScrapeStream(ctx, "ftp://example.com/", nil, StreamHandler{})
+				return err
+			},
+			http.StatusBadRequest, "invalid_url", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiErr *Error
+			if err := tt.call(); !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an *Error", err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Code != tt.wantCode || apiErr.Retryable {
+				t.Errorf("error = %+v, want %d %s", apiErr, tt.wantStatus, tt.wantCode)
+			}
+			if apiErr.RequestID == "" {
+				t.Error("RequestID is empty")
+			}
+			if !strings.Contains(string(apiErr.Details), tt.wantDetail) {
+				t.Errorf("Details = %s, want %q", apiErr.Details, tt.wantDetail)
+			}
+		})
+	}
+}
+
+// failFirst answers the first n requests with status and body, and passes
+// the rest to next.
+func failFirst(n int, status int, body string, next http.Handler) (http.Handler, *atomic.Int32) {
+	var requests atomic.Int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= n {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &requests
+}
+
+func TestRetry(t *testing.T) {
+	const apiBusy = `{"error":{"code":"queue_full","message":"busy","status":503,"retryable":true}}`
+	const apiFailed = `{"error":{"code":"internal_error","message":"broken","status":500,"retryable":false}}`
+
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		body         string
+		wantRequests int32
+		wantCode     string
+	}{
+		{"rate limited by a proxy", 2, http.StatusTooManyRequests, "slow down", 3, ""},
+		{"gateway errors", 1, http.StatusBadGateway, "", 2, ""},
+		{"retryable API errors", 3, http.StatusServiceUnavailable, apiBusy, 4, ""},
+		{"too many failures", 4, http.StatusServiceUnavailable, apiBusy, 4, "queue_full"},
+		{"errors that are not retryable", 1, http.StatusInternalServerError, apiFailed, 1, "internal_error"},
+		{"client errors from a proxy", 1, http.StatusBadRequest, "bad", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, apiServer := newTestAPI(t, &config.Config{}, &stubRenderer{})
+			handler, requests := failFirst(tt.failures, tt.status, tt.body, apiServer.Config.Handler)
+			proxy := httptest.NewServer(handler)
+			defer proxy.Close()
+			c := retryFast(New(proxy.URL, "", nil))
+
+			_, err := c.Scrape(context.Background(), "https://example.com/", nil)
+
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			succeeds := tt.failures < int(tt.wantRequests)
+			if succeeds {
+				if err != nil {
+					t.Errorf("error = %v, want success", err)
+				}
+				return
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Code != tt.wantCode {
+				t.Errorf("error = %v, want the last %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	// A server that closes every connection without answering.
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+	c := retryFast(New(server.URL, "", nil))
+	c.MaxRetries = 2
+	ctx := context.Background()
+
+	_, _ = c.Scrape(ctx, "https://example.com/", nil)
+	_, _ = c.SubmitJob(ctx, "https://example.com/", nil)
+	_, _ = c.GetJob(ctx, "id")
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"POST /scrape": 3, "POST /jobs": 1, "GET /jobs/id": 3}
+	for call, n := range want {
+		if requests[call] != n {
+			t.Errorf("%s sent %d times, want %d", call, requests[call], n)
+		}
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("while the API works", func(t *testing.T) {
+		renderer := &stubRenderer{block: make(chan struct{})}
+		c, _ := newTestAPI(t, &config.Config{}, renderer)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := c.Scrape(ctx, "https://example.com/", nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the context's", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("returned after %v", elapsed)
+		}
+	})
+
+	t.Run("between retries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+		c := New(server.URL, "", nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := c.GetJob(ctx, "id")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the context's", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("returned after %v", elapsed)
+		}
+	})
+
+	t.Run("while streaming", func(t *testing.T) {
+		renderer := &stubRenderer{block: make(chan struct{})}
+		c, _ := newTestAPI(t, &config.Config{}, renderer)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := c.ScrapeStream(ctx, "https://example.com/", nil, StreamHandler{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the context's", err)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{RetryBase: 100 * time.Millisecond, RetryMax: time.Second}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{"first retry", 0, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"doubles", 2, 0, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"capped", 10, 0, 500 * time.Millisecond, time.Second},
+		{"retry after", 0, 700 * time.Millisecond, 700 * time.Millisecond, 700 * time.Millisecond},
+		{"retry after is capped", 0, time.Minute, time.Second, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if d := c.backoff(tt.attempt, tt.retryAfter); d < tt.min || d > tt.max {
+					t.Fatalf("backoff = %v, want between %v and %v", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SubmitJob queues a scrape and returns its ID. Submitting is not retried
+// on connection errors, since the job may already exist.
+func (c *Client) SubmitJob(ctx context.Context, url string, params *ScrapeOptions) (*JobResponse, error) {
+	var resp JobResponse
+	if err := c.do(ctx, http.MethodPost, "/jobs", ScrapeRequest{URL: url, Params: params}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobResult returns the result of a finished job. A job without one
+// fails with code "job_not_done", retryable while the job is running.
+func (c *Client) GetJobResult(ctx context.Context, id string) (*ScrapeResponse, error) {
+	var resp ScrapeResponse
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id)+"/result", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitJob polls the job every interval until it finishes or ctx is done.
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+	for {
+		job, err := c.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status.Finished() {
+			return job, nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// StartCrawl starts a crawl and returns its first snapshot. Like
+// SubmitJob, it is not retried on connection errors.
+func (c *Client) StartCrawl(ctx context.Context, req CrawlRequest) (*CrawlResponse, error) {
+	var resp CrawlResponse
+	if err := c.do(ctx, http.MethodPost, "/crawl", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetCrawl returns the crawl with the pages from offset on. Pass the
+// previous NextOffset to page through results as they arrive.
+func (c *Client) GetCrawl(ctx context.Context, id string, offset int) (*CrawlResponse, error) {
+	path := "/crawl/" + url.PathEscape(id)
+	if offset > 0 {
+		path += "?offset=" + strconv.Itoa(offset)
+	}
+
+	var resp CrawlResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) CancelCrawl(ctx context.Context, id string) (*CrawlResponse, error) {
+	var resp CrawlResponse
+	if err := c.do(ctx, http.MethodDelete, "/crawl/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (c *Client) Scrape(ctx context.Context, url string, params *ScrapeOptions) (*ScrapeResponse, error) {
+	var resp ScrapeResponse
+	if err := c.do(ctx, http.MethodPost, "/scrape", ScrapeRequest{URL: url, Params: params}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ScrapeBatch scrapes every item and returns the results in request order.
+// Items fail individually; see BatchItemResult.Error.
+func (c *Client) ScrapeBatch(ctx context.Context, req BatchRequest) (*BatchResponse, error) {
+	var resp BatchResponse
+	if err := c.do(ctx, http.MethodPost, "/scrape/batch", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Extract has the API fill schema from the page. A result that does not
+// validate is returned with Success false and its Errors, not as an error.
+func (c *Client) Extract(ctx context.Context, req ExtractRequest) (*ExtractResponse, error) {
+	var resp ExtractResponse
+	if err := c.do(ctx, http.MethodPost, "/extract", req, &resp, http.StatusUnprocessableEntity); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// StreamHandler receives the events of a streamed scrape. Either function
+// may be nil.
+type StreamHandler struct {
+	// Progress is called with each stage: navigating, rendered,
+	// converting or fallback. After fallback the markdown starts over.
+	Progress func(stage string)
+	// Markdown is called with each piece of the markdown as it is written.
+	Markdown func(delta string)
+}
+
+// ScrapeStream scrapes url through /scrape/stream, passing events to h as
+// they arrive, and returns the final summary. Only the initial request is
+// retried; a stream that fails midway returns the error event.
+func (c *Client) ScrapeStream(ctx context.Context, url string, params *ScrapeOptions, h StreamHandler) (*StreamDone, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/scrape/stream", ScrapeRequest{URL: url, Params: params}, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			done, err := dispatch(event, data.String(), h)
+			if done != nil || err != nil {
+				return done, err
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Keep-alive comment.
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("scarab: error reading stream: %w", err)
+	}
+	return nil, fmt.Errorf("scarab: stream ended without a result")
+}
+
+// dispatch handles one event, returning the summary or the error that ends
+// the stream.
+func dispatch(event, data string, h StreamHandler) (*StreamDone, error) {
+	switch event {
+	case "progress":
+		var progress struct {
+			Stage string `json:"stage"`
+		}
+		if err := json.Unmarshal([]byte(data), &progress); err == nil && h.Progress != nil {
+			h.Progress(progress.Stage)
+		}
+	case "markdown":
+		var markdown struct {
+			Delta string `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(data), &markdown); err == nil && h.Markdown != nil {
+			h.Markdown(markdown.Delta)
+		}
+	case "error":
+		apiErr := &Error{}
+		if err := json.Unmarshal([]byte(data), apiErr); err != nil {
+			return nil, fmt.Errorf("scarab: error parsing stream: %w", err)
+		}
+		var status struct {
+			Status int `json:"status"`
+		}
+		_ = json.Unmarshal([]byte(data), &status)
+		apiErr.StatusCode = status.Status
+		return nil, apiErr
+	case "done":
+		var done StreamDone
+		if err := json.Unmarshal([]byte(data), &done); err != nil {
+			return nil, fmt.Errorf("scarab: error parsing stream: %w", err)
+		}
+		return &done, nil
+	}
+	return nil, nil
+}
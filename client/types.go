@@ -0,0 +1,381 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ScrapeOptions are the per-request settings of a scrape. Unset fields take
+// the server's defaults; see /openapi.json for the allowed values.
+type ScrapeOptions struct {
+	WaitTime         *int              `json:"waitTime,omitempty"`
+	Selectors        []string          `json:"selectors,omitempty"`
+	BypassCloudflare *bool             `json:"bypassCloudflare,omitempty"`
+	Converter        string            `json:"converter,omitempty"`
+	OutputFormat     string            `json:"outputFormat,omitempty"`
+	Formats          []string          `json:"formats,omitempty"`
+	Provider         string            `json:"provider,omitempty"`
+	Model            string            `json:"model,omitempty"`
+	Fallback         *bool             `json:"fallback,omitempty"`
+	CacheMode        string            `json:"cacheMode,omitempty"`
+	RateLimit        string            `json:"rateLimit,omitempty"`
+	IgnoreRobots     bool              `json:"ignoreRobots,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Cookies          []Cookie          `json:"cookies,omitempty"`
+	Viewport         *Viewport         `json:"viewport,omitempty"`
+	UserAgent        string            `json:"userAgent,omitempty"`
+	Screenshot       *Screenshot       `json:"screenshot,omitempty"`
+	PDF              *PDF              `json:"pdf,omitempty"`
+	Actions          []Action          `json:"actions,omitempty"`
+	Paginate         *Paginate         `json:"paginate,omitempty"`
+}
+
+// Action types for ScrapeOptions.Actions.
+const (
+	ActionClick  = "click"
+	ActionType   = "type"
+	ActionPress  = "press"
+	ActionScroll = "scroll"
+	ActionWait   = "wait"
+	ActionSelect = "select"
+	ActionHover  = "hover"
+	ActionEval   = "eval"
+)
+
+// Action is a step run on the page before its content is read. Which
+// fields apply depends on Type; Timeout is in milliseconds.
+type Action struct {
+	Type         string `json:"type"`
+	Selector     string `json:"selector,omitempty"`
+	Text         string `json:"text,omitempty"`
+	Key          string `json:"key,omitempty"`
+	Value        string `json:"value,omitempty"`
+	Script       string `json:"script,omitempty"`
+	NetworkIdle  bool   `json:"networkIdle,omitempty"`
+	Milliseconds int    `json:"milliseconds,omitempty"`
+	Timeout      int    `json:"timeout,omitempty"`
+}
+
+// Pagination modes for Paginate.Mode.
+const (
+	PaginateScroll = "scroll"
+	PaginateClick  = "click"
+	PaginateNext   = "next"
+)
+
+// Paginate loads more of a page by scrolling, clicking a "load more" button
+// or following next-page links. Times are in milliseconds.
+type Paginate struct {
+	Mode         string `json:"mode"`
+	Selector     string `json:"selector,omitempty"`
+	ItemSelector string `json:"itemSelector,omitempty"`
+	MaxItems     int    `json:"maxItems,omitempty"`
+	MaxPages     int    `json:"maxPages,omitempty"`
+	TimeBudget   int    `json:"timeBudget,omitempty"`
+	IdleTime     int    `json:"idleTime,omitempty"`
+}
+
+// PaginationResult reports what pagination loaded and why it stopped.
+type PaginationResult struct {
+	Mode    string   `json:"mode"`
+	Pages   int      `json:"pages"`
+	Items   int      `json:"items,omitempty"`
+	URLs    []string `json:"urls,omitempty"`
+	Stopped string   `json:"stopped"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ActionResult reports how one action went. Value is what an eval
+// action's script returned.
+type ActionResult struct {
+	Index      int             `json:"index"`
+	Type       string          `json:"type"`
+	Success    bool            `json:"success"`
+	Error      string          `json:"error,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	DurationMS int64           `json:"durationMs"`
+}
+
+// Screenshot asks for a PNG or JPEG of the viewport or the whole page.
+type Screenshot struct {
+	FullPage bool   `json:"fullPage,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Quality  int    `json:"quality,omitempty"`
+}
+
+// PDF asks for the page printed to PDF on letter, legal or a4 paper.
+type PDF struct {
+	Landscape       bool   `json:"landscape,omitempty"`
+	PrintBackground bool   `json:"printBackground,omitempty"`
+	PaperSize       string `json:"paperSize,omitempty"`
+}
+
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+}
+
+type Viewport struct {
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"deviceScaleFactor,omitempty"`
+	Mobile            bool    `json:"mobile,omitempty"`
+}
+
+type ScrapeRequest struct {
+	URL    string         `json:"url"`
+	Params *ScrapeOptions `json:"params,omitempty"`
+}
+
+// Output formats for ScrapeOptions.Formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// Outputs are the formats of a page besides markdown. Each is set only when
+// requested.
+type Outputs struct {
+	Text     string    `json:"text,omitempty"`
+	HTML     string    `json:"html,omitempty"`
+	Document *Document `json:"document,omitempty"`
+	// Screenshot and PDF are set when requested in ScrapeOptions.
+	Screenshot *Artifact `json:"screenshot,omitempty"`
+	PDF        *Artifact `json:"pdf,omitempty"`
+}
+
+// Artifact is a capture of a page. Data holds it base64-encoded unless the
+// server stores captures, in which case URL points to it.
+type Artifact struct {
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	Data        string `json:"data,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// Document is a page as a tree of sections, the "json" format.
+type Document struct {
+	Title    string     `json:"title,omitempty"`
+	URL      string     `json:"url"`
+	Sections []*Section `json:"sections"`
+}
+
+type Section struct {
+	Heading    string     `json:"heading,omitempty"`
+	Level      int        `json:"level"`
+	Paragraphs []string   `json:"paragraphs,omitempty"`
+	Links      []Link     `json:"links,omitempty"`
+	Sections   []*Section `json:"sections,omitempty"`
+}
+
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Metadata is what a page says about itself in its markup. Dates are given
+// as the page states them.
+type Metadata struct {
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Canonical   string            `json:"canonical,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Author      string            `json:"author,omitempty"`
+	Published   string            `json:"published,omitempty"`
+	Modified    string            `json:"modified,omitempty"`
+	OpenGraph   map[string]string `json:"openGraph,omitempty"`
+	Twitter     map[string]string `json:"twitter,omitempty"`
+	JSONLD      []json.RawMessage `json:"jsonLd,omitempty"`
+	Microdata   []MicrodataItem   `json:"microdata,omitempty"`
+}
+
+// MicrodataItem is an itemscope element. Property values are strings or,
+// for nested items, objects of the same shape.
+type MicrodataItem struct {
+	Type       []string                   `json:"type,omitempty"`
+	ID         string                     `json:"id,omitempty"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+type ScrapeResponse struct {
+	Success  bool   `json:"success"`
+	Markdown string `json:"markdown,omitempty"`
+	Outputs
+	Metadata   *Metadata         `json:"metadata,omitempty"`
+	Actions    []ActionResult    `json:"actions,omitempty"`
+	Pagination *PaginationResult `json:"pagination,omitempty"`
+	Conversion *ConversionInfo   `json:"conversion,omitempty"`
+	Usage      *Usage            `json:"usage,omitempty"`
+	Cache      string            `json:"cache,omitempty"`
+}
+
+type ConversionInfo struct {
+	Converter    string            `json:"converter"`
+	Provider     string            `json:"provider,omitempty"`
+	Model        string            `json:"model,omitempty"`
+	Chunks       int               `json:"chunks,omitempty"`
+	Truncated    bool              `json:"truncated"`
+	DroppedChars int               `json:"droppedChars,omitempty"`
+	Fallbacks    []FallbackAttempt `json:"fallbacks,omitempty"`
+}
+
+type FallbackAttempt struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	Error    string `json:"error"`
+}
+
+type Usage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// StreamDone is the final event of a streamed scrape.
+type StreamDone struct {
+	URL string `json:"url"`
+	Outputs
+	Metadata   *Metadata         `json:"metadata,omitempty"`
+	Actions    []ActionResult    `json:"actions,omitempty"`
+	Pagination *PaginationResult `json:"pagination,omitempty"`
+	Conversion *ConversionInfo   `json:"conversion,omitempty"`
+	Usage      Usage             `json:"usage"`
+	Cache      string            `json:"cache,omitempty"`
+}
+
+type BatchRequest struct {
+	Items       []ScrapeRequest `json:"items"`
+	Concurrency int             `json:"concurrency,omitempty"`
+}
+
+type BatchItemResult struct {
+	Index    int    `json:"index"`
+	URL      string `json:"url"`
+	Success  bool   `json:"success"`
+	Markdown string `json:"markdown,omitempty"`
+	Outputs
+	Metadata   *Metadata         `json:"metadata,omitempty"`
+	Actions    []ActionResult    `json:"actions,omitempty"`
+	Pagination *PaginationResult `json:"pagination,omitempty"`
+	Conversion *ConversionInfo   `json:"conversion,omitempty"`
+	Usage      *Usage            `json:"usage,omitempty"`
+	Cache      string            `json:"cache,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Code       string            `json:"code,omitempty"`
+	Retryable  bool              `json:"retryable,omitempty"`
+}
+
+type BatchResponse struct {
+	Success bool              `json:"success"`
+	Results []BatchItemResult `json:"results"`
+}
+
+type ExtractRequest struct {
+	URL    string                 `json:"url"`
+	Schema map[string]interface{} `json:"schema"`
+	Params *ScrapeOptions         `json:"params,omitempty"`
+}
+
+type ExtractResponse struct {
+	Success   bool              `json:"success"`
+	URL       string            `json:"url"`
+	Data      json.RawMessage   `json:"data"`
+	Errors    []ValidationError `json:"errors,omitempty"`
+	Attempts  int               `json:"attempts"`
+	Truncated bool              `json:"truncated"`
+	Usage     *Usage            `json:"usage,omitempty"`
+}
+
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type JobStatus string
+
+const (
+	JobQueued     JobStatus = "queued"
+	JobRendering  JobStatus = "rendering"
+	JobConverting JobStatus = "converting"
+	JobDone       JobStatus = "done"
+	JobFailed     JobStatus = "failed"
+	JobCancelled  JobStatus = "cancelled"
+)
+
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+type JobResponse struct {
+	JobID  string    `json:"jobId"`
+	Status JobStatus `json:"status"`
+}
+
+type Job struct {
+	ID         string        `json:"id"`
+	URL        string        `json:"url"`
+	Params     ScrapeOptions `json:"params"`
+	Status     JobStatus     `json:"status"`
+	Attempts   int           `json:"attempts"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+	StartedAt  *time.Time    `json:"startedAt,omitempty"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+type CrawlRequest struct {
+	URL      string         `json:"url"`
+	MaxDepth int            `json:"maxDepth,omitempty"`
+	MaxPages int            `json:"maxPages,omitempty"`
+	Scope    string         `json:"scope,omitempty"`
+	Include  []string       `json:"include,omitempty"`
+	Exclude  []string       `json:"exclude,omitempty"`
+	Params   *ScrapeOptions `json:"params,omitempty"`
+}
+
+type CrawlResponse struct {
+	CrawlID    string      `json:"crawlId"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Total      int         `json:"total"`
+	NextOffset int         `json:"nextOffset"`
+	Pages      []CrawlPage `json:"pages"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+type CrawlPage struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Markdown string `json:"markdown,omitempty"`
+	Outputs
+	Metadata   *Metadata         `json:"metadata,omitempty"`
+	Actions    []ActionResult    `json:"actions,omitempty"`
+	Pagination *PaginationResult `json:"pagination,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type UsageTotals struct {
+	Requests int `json:"requests"`
+	Usage
+}
+
+type DayUsage struct {
+	Date string `json:"date"`
+	UsageTotals
+}
+
+type UsageReport struct {
+	Key   string      `json:"key"`
+	Total UsageTotals `json:"total"`
+	Days  []DayUsage  `json:"days"`
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Usage returns the usage of the client's API key, or with the admin scope
+// of another key when key is set.
+func (c *Client) Usage(ctx context.Context, key string) (*UsageReport, error) {
+	path := "/usage"
+	if key != "" {
+		path += "?key=" + url.QueryEscape(key)
+	}
+
+	var report UsageReport
+	if err := c.do(ctx, http.MethodGet, path, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// UsageKeys lists the keys with recorded usage. It needs the admin scope.
+func (c *Client) UsageKeys(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Keys []string `json:"keys"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/usage/keys", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
@@ -6,16 +6,102 @@ import (
 	"strings"
 )
 
+type HostLimit struct {
+	Rate          float64
+	Burst         int
+	MaxConcurrent int
+}
+
+// LLMStep is one entry of the LLM fallback chain. Provider "native" stands
+// for the native converter; an empty Model means the provider's default.
+type LLMStep struct {
+	Provider string
+	Model    string
+}
+
+// ModelPrice is the cost in US dollars per million prompt (Input) and
+// completion (Output) tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
 type Config struct {
 	ServerPort       string
 	LLMAPIKey        string
 	LLMModel         string
 	LLMMaxTokens     int
 	LLMAPIBaseURL    string
+	Converter        string
 	BrowserTimeout   int
+	ScrapeTimeout    int
 	CloudflareWaitMS int
 	ProxyList        []string
 	UserAgents       []string
+
+	BrowserPoolSize           int
+	BrowserMaxConcurrency     int
+	BrowserHealthCheckSeconds int
+
+	BatchMaxItems       int
+	BatchMaxConcurrency int
+
+	CrawlMaxDepth int
+	CrawlMaxPages int
+
+	JobWorkers          int
+	JobQueueSize        int
+	JobRetentionMinutes int
+
+	RespectRobots      bool
+	RobotsUserAgent    string
+	RobotsCacheMinutes int
+
+	HostRateLimit      HostLimit
+	HostRateLimitRules map[string]HostLimit
+
+	LLMChunkChars       int
+	LLMMaxChunks        int
+	LLMChunkMode        string
+	LLMChunkConcurrency int
+
+	ExtractMaxAttempts int
+
+	LLMProvider      string
+	AnthropicAPIKey  string
+	AnthropicBaseURL string
+	AnthropicModel   string
+	OllamaBaseURL    string
+	OllamaModel      string
+
+	LLMMaxRetries             int
+	LLMRetryBaseMS            int
+	LLMRetryMaxMS             int
+	LLMBreakerThreshold       int
+	LLMBreakerCooldownSeconds int
+
+	LLMFallback []LLMStep
+
+	LLMPrices          map[string]ModelPrice
+	UsageRetentionDays int
+
+	CacheBackend    string
+	CacheDir        string
+	CacheMaxEntries int
+	CacheTTLSeconds int
+
+	ArtifactStore   string
+	ArtifactDir     string
+	ArtifactBaseURL string
+
+	APIKeys               []string
+	APIKeysFile           string
+	APIKeyDailyRequests   int
+	APIKeyMonthlyRequests int
+	APIKeyDailyTokens     int
+	APIKeyMonthlyTokens   int
+	APIKeyRateLimit       float64
+	APIKeyRateBurst       int
 }
 
 func NewConfig() *Config {
@@ -31,6 +117,28 @@ func NewConfig() *Config {
 		return val
 	}
 
+	parseFloat := func(str string, defaultVal float64) float64 {
+		if str == "" {
+			return defaultVal
+		}
+		val, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return defaultVal
+		}
+		return val
+	}
+
+	parseBool := func(str string, defaultVal bool) bool {
+		if str == "" {
+			return defaultVal
+		}
+		val, err := strconv.ParseBool(str)
+		if err != nil {
+			return defaultVal
+		}
+		return val
+	}
+
 	defaultUserAgents := []string{
 		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36",
 		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Safari/605.1.15",
@@ -52,16 +160,95 @@ func NewConfig() *Config {
 		proxies = strings.Split(proxyList, ",")
 	}
 
+	var apiKeys []string
+	for _, key := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+
+	hostRateLimit := HostLimit{
+		Rate:          parseFloat(os.Getenv("HOST_RATE_LIMIT"), 1),
+		Burst:         parseInt(os.Getenv("HOST_RATE_BURST"), 2),
+		MaxConcurrent: parseInt(os.Getenv("HOST_MAX_CONCURRENT"), 2),
+	}
+
 	return &Config{
 		ServerPort:       os.Getenv("PORT"),
 		LLMAPIKey:        os.Getenv("LLM_API_KEY"),
 		LLMModel:         getEnvWithDefault("LLM_MODEL", "gpt-3.5-turbo"),
 		LLMMaxTokens:     parseInt(os.Getenv("LLM_MAX_TOKENS"), 4096),
 		LLMAPIBaseURL:    getEnvWithDefault("LLM_API_BASE_URL", "https://api.openai.com"),
+		Converter:        getEnvWithDefault("CONVERTER", "llm"),
 		BrowserTimeout:   parseInt(os.Getenv("BROWSER_TIMEOUT_SECONDS"), 30),
+		ScrapeTimeout:    parseInt(os.Getenv("SCRAPE_TIMEOUT_SECONDS"), 120),
 		CloudflareWaitMS: parseInt(os.Getenv("CLOUDFLARE_WAIT_MS"), 5000),
 		ProxyList:        proxies,
 		UserAgents:       userAgentsList,
+
+		BrowserPoolSize:           parseInt(os.Getenv("BROWSER_POOL_SIZE"), 1),
+		BrowserMaxConcurrency:     parseInt(os.Getenv("BROWSER_MAX_CONCURRENCY"), 4),
+		BrowserHealthCheckSeconds: parseInt(os.Getenv("BROWSER_HEALTH_CHECK_SECONDS"), 30),
+
+		BatchMaxItems:       parseInt(os.Getenv("BATCH_MAX_ITEMS"), 500),
+		BatchMaxConcurrency: parseInt(os.Getenv("BATCH_MAX_CONCURRENCY"), 4),
+
+		CrawlMaxDepth: parseInt(os.Getenv("CRAWL_MAX_DEPTH"), 3),
+		CrawlMaxPages: parseInt(os.Getenv("CRAWL_MAX_PAGES"), 100),
+
+		JobWorkers:          parseInt(os.Getenv("JOB_WORKERS"), 2),
+		JobQueueSize:        parseInt(os.Getenv("JOB_QUEUE_SIZE"), 100),
+		JobRetentionMinutes: parseInt(os.Getenv("JOB_RETENTION_MINUTES"), 60),
+
+		RespectRobots:      parseBool(os.Getenv("RESPECT_ROBOTS_TXT"), true),
+		RobotsUserAgent:    getEnvWithDefault("ROBOTS_USER_AGENT", "Scarab"),
+		RobotsCacheMinutes: parseInt(os.Getenv("ROBOTS_CACHE_MINUTES"), 60),
+
+		HostRateLimit:      hostRateLimit,
+		HostRateLimitRules: parseHostLimits(os.Getenv("HOST_RATE_LIMITS"), hostRateLimit),
+
+		LLMChunkChars:       parseInt(os.Getenv("LLM_CHUNK_CHARS"), 40000),
+		LLMMaxChunks:        parseInt(os.Getenv("LLM_MAX_CHUNKS"), 10),
+		LLMChunkMode:        getEnvWithDefault("LLM_CHUNK_MODE", "sequential"),
+		LLMChunkConcurrency: parseInt(os.Getenv("LLM_CHUNK_CONCURRENCY"), 4),
+
+		ExtractMaxAttempts: parseInt(os.Getenv("EXTRACT_MAX_ATTEMPTS"), 3),
+
+		LLMProvider:      getEnvWithDefault("LLM_PROVIDER", "openai"),
+		AnthropicAPIKey:  os.Getenv("ANTHROPIC_API_KEY"),
+		AnthropicBaseURL: getEnvWithDefault("ANTHROPIC_BASE_URL", "https://api.anthropic.com"),
+		AnthropicModel:   getEnvWithDefault("ANTHROPIC_MODEL", "claude-3-5-haiku-latest"),
+		OllamaBaseURL:    getEnvWithDefault("OLLAMA_BASE_URL", "http://localhost:11434"),
+		OllamaModel:      getEnvWithDefault("OLLAMA_MODEL", "llama3.1"),
+
+		LLMMaxRetries:             parseInt(os.Getenv("LLM_MAX_RETRIES"), 3),
+		LLMRetryBaseMS:            parseInt(os.Getenv("LLM_RETRY_BASE_MS"), 500),
+		LLMRetryMaxMS:             parseInt(os.Getenv("LLM_RETRY_MAX_MS"), 30000),
+		LLMBreakerThreshold:       parseInt(os.Getenv("LLM_BREAKER_THRESHOLD"), 5),
+		LLMBreakerCooldownSeconds: parseInt(os.Getenv("LLM_BREAKER_COOLDOWN_SECONDS"), 30),
+
+		LLMFallback: parseLLMSteps(os.Getenv("LLM_FALLBACK")),
+
+		LLMPrices:          parsePrices(os.Getenv("LLM_PRICES")),
+		UsageRetentionDays: parseInt(os.Getenv("USAGE_RETENTION_DAYS"), 90),
+
+		CacheBackend:    getEnvWithDefault("CACHE_BACKEND", "memory"),
+		CacheDir:        getEnvWithDefault("CACHE_DIR", "./cache-data"),
+		CacheMaxEntries: parseInt(os.Getenv("CACHE_MAX_ENTRIES"), 1000),
+		CacheTTLSeconds: parseInt(os.Getenv("CACHE_TTL_SECONDS"), 900),
+
+		ArtifactStore:   getEnvWithDefault("ARTIFACT_STORE", "inline"),
+		ArtifactDir:     getEnvWithDefault("ARTIFACT_DIR", "./artifacts-data"),
+		ArtifactBaseURL: getEnvWithDefault("ARTIFACT_BASE_URL", "/artifacts"),
+
+		APIKeys:               apiKeys,
+		APIKeysFile:           os.Getenv("API_KEYS_FILE"),
+		APIKeyDailyRequests:   parseInt(os.Getenv("API_KEY_DAILY_REQUESTS"), 0),
+		APIKeyMonthlyRequests: parseInt(os.Getenv("API_KEY_MONTHLY_REQUESTS"), 0),
+		APIKeyDailyTokens:     parseInt(os.Getenv("API_KEY_DAILY_TOKENS"), 0),
+		APIKeyMonthlyTokens:   parseInt(os.Getenv("API_KEY_MONTHLY_TOKENS"), 0),
+		APIKeyRateLimit:       parseFloat(os.Getenv("API_KEY_RATE_LIMIT"), 0),
+		APIKeyRateBurst:       parseInt(os.Getenv("API_KEY_RATE_BURST"), 5),
 	}
 }
 
@@ -72,3 +259,80 @@ func getEnvWithDefault(key, defaultValue string) string {
 	}
 	return value
 }
+
+// parseHostLimits reads "domain=rate:burst:concurrent" entries separated by
+// commas. Missing or invalid fields fall back to the defaults.
+func parseHostLimits(value string, defaults HostLimit) map[string]HostLimit {
+	limits := make(map[string]HostLimit)
+	if value == "" {
+		return limits
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		domain, spec, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || domain == "" {
+			continue
+		}
+
+		limit := defaults
+		fields := strings.Split(spec, ":")
+		if len(fields) > 0 {
+			if rate, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				limit.Rate = rate
+			}
+		}
+		if len(fields) > 1 {
+			if burst, err := strconv.Atoi(fields[1]); err == nil {
+				limit.Burst = burst
+			}
+		}
+		if len(fields) > 2 {
+			if concurrent, err := strconv.Atoi(fields[2]); err == nil {
+				limit.MaxConcurrent = concurrent
+			}
+		}
+
+		limits[strings.TrimPrefix(strings.ToLower(domain), "www.")] = limit
+	}
+
+	return limits
+}
+
+// parseLLMSteps reads "provider:model" entries separated by commas. The
+// model may be omitted, and "native" falls back to the native converter.
+func parseLLMSteps(value string) []LLMStep {
+	var steps []LLMStep
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		provider, model, _ := strings.Cut(entry, ":")
+		steps = append(steps, LLMStep{Provider: strings.TrimSpace(provider), Model: strings.TrimSpace(model)})
+	}
+	return steps
+}
+
+// parsePrices reads "model=input:output" entries separated by commas, with
+// prices in US dollars per million tokens. Invalid entries are skipped.
+func parsePrices(value string) map[string]ModelPrice {
+	prices := make(map[string]ModelPrice)
+	for _, entry := range strings.Split(value, ",") {
+		model, spec, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || model == "" {
+			continue
+		}
+
+		input, output, _ := strings.Cut(spec, ":")
+		in, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			continue
+		}
+		out, err := strconv.ParseFloat(output, 64)
+		if err != nil {
+			out = in
+		}
+		prices[model] = ModelPrice{Input: in, Output: out}
+	}
+	return prices
+}
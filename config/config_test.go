@@ -0,0 +1,26 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePrices(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]ModelPrice
+	}{
+		{"", map[string]ModelPrice{}},
+		{"gpt-4o=2.5:10", map[string]ModelPrice{"gpt-4o": {Input: 2.5, Output: 10}}},
+		{" a=1:2 , b=3 ", map[string]ModelPrice{"a": {Input: 1, Output: 2}, "b": {Input: 3, Output: 3}}},
+		{"a=x:1,=1:1,b,c=2:2", map[string]ModelPrice{"c": {Input: 2, Output: 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parsePrices(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePrices(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
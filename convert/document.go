@@ -0,0 +1,201 @@
+package convert
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Document is a page as a tree of sections, for splitting it into chunks
+// that keep their headings.
+type Document struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+	// Sections holds the content before the first heading, if any, as a
+	// section without heading, then one section per top-level heading.
+	Sections []*Section `json:"sections"`
+}
+
+// Section is a heading with the paragraphs and links up to the next heading
+// of the same or a higher level, which start subsections.
+type Section struct {
+	Heading    string     `json:"heading,omitempty"`
+	Level      int        `json:"level"`
+	Paragraphs []string   `json:"paragraphs,omitempty"`
+	Links      []Link     `json:"links,omitempty"`
+	Sections   []*Section `json:"sections,omitempty"`
+}
+
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Elements whose start or end ends the current paragraph, on top of
+// blockElements.
+var paragraphBreaks = map[atom.Atom]bool{
+	atom.Br:         true,
+	atom.Hr:         true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Li:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Pre:        true,
+	atom.Blockquote: true,
+	atom.Table:      true,
+	atom.Caption:    true,
+	atom.Tr:         true,
+	atom.Td:         true,
+	atom.Th:         true,
+}
+
+type documentBuilder struct {
+	converter
+	doc *Document
+	// open is the path from the root section to the current one.
+	open      []*Section
+	paragraph strings.Builder
+}
+
+// ToDocument converts an HTML document into a Document. Relative links are
+// resolved against pageURL.
+func ToDocument(document string, pageURL string, opts Options) (*Document, error) {
+	doc, root, err := parse(document, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &documentBuilder{
+		converter: converter{base: baseURL(pageURL)},
+		doc:       &Document{Title: documentTitle(doc), URL: pageURL, Sections: []*Section{}},
+	}
+	b.open = []*Section{{}}
+	b.walk(root)
+	b.endParagraph()
+
+	// The root only collects what comes before the first heading.
+	intro := b.open[0]
+	if len(intro.Paragraphs) > 0 || len(intro.Links) > 0 {
+		b.doc.Sections = append(b.doc.Sections, &Section{Paragraphs: intro.Paragraphs, Links: intro.Links})
+	}
+	b.doc.Sections = append(b.doc.Sections, intro.Sections...)
+	return b.doc, nil
+}
+
+func (b *documentBuilder) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.paragraph.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		b.children(n)
+		return
+	}
+
+	if skippedElements[n.DataAtom] || hidden(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		heading := collapse(textContent(n))
+		if heading == "" {
+			return
+		}
+		b.endParagraph()
+		b.startSection(heading, int(n.Data[1]-'0'))
+		return
+
+	case atom.A:
+		href := getAttr(n, "href")
+		text := collapse(textContent(n))
+		if text != "" && href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			current := b.current()
+			current.Links = append(current.Links, Link{Text: text, URL: b.resolve(href)})
+		}
+	}
+
+	breaks := blockElements[n.DataAtom] || paragraphBreaks[n.DataAtom]
+	if breaks {
+		b.endParagraph()
+	}
+	b.children(n)
+	if breaks {
+		b.endParagraph()
+	}
+}
+
+func (b *documentBuilder) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.walk(child)
+	}
+}
+
+func (b *documentBuilder) current() *Section {
+	return b.open[len(b.open)-1]
+}
+
+// startSection closes the open sections at level or deeper and opens a new
+// one under the nearest higher heading.
+func (b *documentBuilder) startSection(heading string, level int) {
+	for len(b.open) > 1 && b.current().Level >= level {
+		b.open = b.open[:len(b.open)-1]
+	}
+	section := &Section{Heading: heading, Level: level}
+	parent := b.current()
+	parent.Sections = append(parent.Sections, section)
+	b.open = append(b.open, section)
+}
+
+func (b *documentBuilder) endParagraph() {
+	if text := collapse(b.paragraph.String()); text != "" {
+		current := b.current()
+		current.Paragraphs = append(current.Paragraphs, text)
+	}
+	b.paragraph.Reset()
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Text returns the document as plain text: the title, then every heading
+// and paragraph in order, separated by blank lines.
+func (d *Document) Text() string {
+	var blocks []string
+	if d.Title != "" {
+		blocks = append(blocks, d.Title)
+	}
+
+	// The title usually repeats as the first heading.
+	firstHeading := true
+	var add func(sections []*Section)
+	add = func(sections []*Section) {
+		for _, section := range sections {
+			if section.Heading != "" {
+				if !firstHeading || section.Heading != d.Title {
+					blocks = append(blocks, section.Heading)
+				}
+				firstHeading = false
+			}
+			blocks = append(blocks, section.Paragraphs...)
+			add(section.Sections)
+		}
+	}
+	add(d.Sections)
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// ToText converts an HTML document into plain text for indexing, without
+// markup, links or images.
+func ToText(document string, pageURL string, opts Options) (string, error) {
+	doc, err := ToDocument(document, pageURL, opts)
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
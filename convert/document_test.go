@@ -0,0 +1,88 @@
+package convert
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "content before the first heading",
+			html: `<body><p>Intro</p><h1>Title</h1><p>Body</p></body>`,
+			want: `[{"level":0,"paragraphs":["Intro"]},{"heading":"Title","level":1,"paragraphs":["Body"]}]`,
+		},
+		{
+			name: "subsections nest under their heading",
+			html: `<body><h1>A</h1><h2>A.1</h2><p>x</p><h3>A.1.a</h3><p>y</p><h2>A.2</h2><h1>B</h1></body>`,
+			want: `[{"heading":"A","level":1,"sections":[{"heading":"A.1","level":2,"paragraphs":["x"],"sections":[{"heading":"A.1.a","level":3,"paragraphs":["y"]}]},{"heading":"A.2","level":2}]},{"heading":"B","level":1}]`,
+		},
+		{
+			name: "paragraphs break at block elements",
+			html: `<body><h2>List</h2><p>one<br>two</p><ul><li>three</li><li>four  and
+			more</li></ul></body>`,
+			want: `[{"heading":"List","level":2,"paragraphs":["one","two","three","four and more"]}]`,
+		},
+		{
+			name: "links are collected and resolved",
+			html: `<body><h1>Links</h1><p><a href="/a">First</a> and <a href="https://other.example/">second</a></p></body>`,
+			want: `[{"heading":"Links","level":1,"paragraphs":["First and second"],"links":[{"text":"First","url":"https://example.com/a"},{"text":"second","url":"https://other.example/"}]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ToDocument(tt.html, "https://example.com/page", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(doc.Sections)
+			if string(got) != tt.want {
+				t.Errorf("Sections =\n%s\nwant\n%s", got, tt.want)
+			}
+			if doc.URL != "https://example.com/page" {
+				t.Errorf("URL = %q", doc.URL)
+			}
+		})
+	}
+}
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "headings and paragraphs without markup",
+			html: `<html><head><title>Guide</title></head><body><h1>Guide</h1><p>Read <a href="/x">this</a> <img src="a.png" alt="pic">first.</p><h2>Next</h2><p>More</p></body></html>`,
+			want: "Guide\n\nRead this first.\n\nNext\n\nMore",
+		},
+		{
+			name: "a first heading other than the title is kept",
+			html: `<html><head><title>Site</title></head><body><h1>Article</h1><p>Text</p></body></html>`,
+			want: "Site\n\nArticle\n\nText",
+		},
+		{
+			name: "scripts are left out",
+			html: `<body><p>Visible</p><script>var hidden = 1</script></body>`,
+			want: "Visible",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToText(tt.html, "https://example.com/", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
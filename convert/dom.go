@@ -0,0 +1,104 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// parse parses an HTML document and returns it with the node to convert:
+// the <body>, or the main content when opts ask for it.
+func parse(document string, opts Options) (doc, root *html.Node, err error) {
+	doc, err = html.Parse(strings.NewReader(document))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	root = findFirst(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	if opts.MainContent {
+		root = MainContent(doc)
+	}
+	return doc, root, nil
+}
+
+// baseURL parses the page URL that relative references are resolved
+// against, or returns nil when it is not a valid URL.
+func baseURL(pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	return base
+}
+
+// visit walks the element subtree rooted at n depth-first. Returning false
+// from fn skips the children of that element.
+func visit(n *html.Node, fn func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		visit(child, fn)
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	visit(n, func(el *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if el.DataAtom == a {
+			found = el
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func findAll(n *html.Node, a atom.Atom) []*html.Node {
+	var found []*html.Node
+	visit(n, func(el *html.Node) bool {
+		if el.DataAtom == a {
+			found = append(found, el)
+		}
+		return true
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (child.DataAtom == atom.Script || child.DataAtom == atom.Style) {
+			continue
+		}
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func getAttr(n *html.Node, key string) string {
+	val, _ := attrValue(n, key)
+	return val
+}
@@ -0,0 +1,429 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type Options struct {
+	// MainContent keeps only the readability-style main content of the page
+	// instead of converting the whole body.
+	MainContent bool
+}
+
+type converter struct {
+	base      *url.URL
+	listDepth int
+}
+
+// ToMarkdown converts an HTML document into Markdown without calling an LLM.
+// Relative links and image sources are resolved against pageURL.
+func ToMarkdown(document string, pageURL string, opts Options) (string, error) {
+	doc, root, err := parse(document, opts)
+	if err != nil {
+		return "", err
+	}
+
+	c := &converter{base: baseURL(pageURL)}
+
+	w := newWriter()
+	if findFirst(root, atom.H1) == nil {
+		if title := documentTitle(doc); title != "" {
+			w.raw("# " + title)
+			w.breakLines(2)
+		}
+	}
+	c.walk(w, root)
+
+	return w.String(), nil
+}
+
+var skippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Button:   true,
+	atom.Input:    true,
+	atom.Select:   true,
+	atom.Textarea: true,
+}
+
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Aside:      true,
+	atom.Nav:        true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Form:       true,
+	atom.Fieldset:   true,
+	atom.Address:    true,
+	atom.Details:    true,
+	atom.Summary:    true,
+	atom.Dl:         true,
+}
+
+func (c *converter) walk(w *mdWriter, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		c.children(w, n)
+		return
+	}
+
+	if skippedElements[n.DataAtom] || hidden(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		heading := strings.TrimSpace(c.inlineText(n))
+		if heading == "" {
+			return
+		}
+		w.breakLines(2)
+		w.raw(strings.Repeat("#", level) + " " + heading)
+		w.breakLines(2)
+
+	case atom.Br:
+		w.breakLines(1)
+
+	case atom.Hr:
+		w.breakLines(2)
+		w.raw("---")
+		w.breakLines(2)
+
+	case atom.Strong, atom.B:
+		w.inline(c.inlineText(n), "**", "**")
+
+	case atom.Em, atom.I:
+		w.inline(c.inlineText(n), "_", "_")
+
+	case atom.Del, atom.S, atom.Strike:
+		w.inline(c.inlineText(n), "~~", "~~")
+
+	case atom.Code, atom.Kbd, atom.Samp:
+		code := strings.Join(strings.Fields(textContent(n)), " ")
+		if code == "" {
+			return
+		}
+		fence := "`"
+		if strings.Contains(code, "`") {
+			fence = "`` "
+			w.inline(code, fence, " ``")
+			return
+		}
+		w.inline(code, fence, fence)
+
+	case atom.Pre:
+		c.writeCodeBlock(w, n)
+
+	case atom.A:
+		c.writeLink(w, n)
+
+	case atom.Img:
+		if img := c.image(n); img != "" {
+			w.raw(img)
+		}
+
+	case atom.Ul, atom.Ol:
+		c.writeList(w, n)
+
+	case atom.Blockquote:
+		w.breakLines(2)
+		w.settle()
+		old := w.indent
+		w.indent = old + "> "
+		c.children(w, n)
+		w.indent = old
+		w.breakLines(2)
+
+	case atom.Table:
+		c.writeTable(w, n)
+
+	case atom.Dt:
+		w.breakLines(1)
+		w.inline(c.inlineText(n), "**", "**")
+		w.breakLines(1)
+
+	case atom.Dd:
+		w.breakLines(1)
+		c.children(w, n)
+		w.breakLines(1)
+
+	default:
+		if blockElements[n.DataAtom] {
+			w.breakLines(2)
+			c.children(w, n)
+			w.breakLines(2)
+			return
+		}
+		c.children(w, n)
+	}
+}
+
+func (c *converter) children(w *mdWriter, n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(w, child)
+	}
+}
+
+// inlineText renders n's children as a single line of Markdown.
+func (c *converter) inlineText(n *html.Node) string {
+	w := newWriter()
+	c.children(w, n)
+
+	s := strings.Join(strings.Fields(w.sb.String()), " ")
+	if s == "" {
+		return ""
+	}
+	// Keep the surrounding whitespace so the caller can space the fragment.
+	raw := textContent(n)
+	if raw != "" && isSpace(raw[0]) {
+		s = " " + s
+	}
+	if raw != "" && isSpace(raw[len(raw)-1]) {
+		s += " "
+	}
+	return s
+}
+
+func (c *converter) writeLink(w *mdWriter, n *html.Node) {
+	content := c.inlineText(n)
+	href := strings.TrimSpace(getAttr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "#") {
+		w.inline(content, "", "")
+		return
+	}
+	href = c.resolve(href)
+
+	if title := getAttr(n, "title"); title != "" {
+		w.inline(content, "[", fmt.Sprintf("](%s %q)", href, title))
+		return
+	}
+	w.inline(content, "[", "]("+href+")")
+}
+
+func (c *converter) image(n *html.Node) string {
+	src := getAttr(n, "src")
+	if src == "" || strings.HasPrefix(src, "data:") {
+		src = getAttr(n, "data-src")
+	}
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return ""
+	}
+
+	alt := strings.Join(strings.Fields(getAttr(n, "alt")), " ")
+	return fmt.Sprintf("![%s](%s)", alt, c.resolve(src))
+}
+
+func (c *converter) writeCodeBlock(w *mdWriter, n *html.Node) {
+	code := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return
+	}
+
+	lang := codeLanguage(n)
+	if inner := findFirst(n, atom.Code); inner != nil && lang == "" {
+		lang = codeLanguage(inner)
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	w.breakLines(2)
+	w.raw(fence + lang)
+	for _, line := range strings.Split(code, "\n") {
+		w.breakLines(1)
+		w.raw(strings.TrimRight(line, " \t\r"))
+	}
+	w.breakLines(1)
+	w.raw(fence)
+	w.breakLines(2)
+}
+
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(getAttr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+func (c *converter) writeList(w *mdWriter, n *html.Node) {
+	if c.listDepth > 0 {
+		w.breakLines(1)
+	} else {
+		w.breakLines(2)
+	}
+
+	ordered := n.DataAtom == atom.Ol
+	index := 1
+	if start, err := strconv.Atoi(getAttr(n, "start")); err == nil {
+		index = start
+	}
+
+	c.listDepth++
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(index) + ". "
+			index++
+		}
+
+		w.breakLines(1)
+		w.raw(marker)
+
+		old := w.indent
+		w.indent = old + strings.Repeat(" ", len(marker))
+		w.holdBreaks = true
+		c.children(w, li)
+		w.holdBreaks = false
+		w.indent = old
+	}
+	c.listDepth--
+
+	if c.listDepth > 0 {
+		w.breakLines(1)
+	} else {
+		w.breakLines(2)
+	}
+}
+
+func (c *converter) writeTable(w *mdWriter, n *html.Node) {
+	var rows [][]string
+	var layout bool
+	columns := 0
+
+	visit(n, func(row *html.Node) bool {
+		if row != n && row.DataAtom == atom.Table {
+			layout = true
+			return false
+		}
+		if row.DataAtom != atom.Tr {
+			return true
+		}
+
+		var cells []string
+		for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom != atom.Td && cell.DataAtom != atom.Th {
+				continue
+			}
+			if findFirst(cell, atom.Table) != nil {
+				layout = true
+			}
+			text := strings.TrimSpace(c.inlineText(cell))
+			cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+		}
+		if len(cells) > columns {
+			columns = len(cells)
+		}
+		rows = append(rows, cells)
+		return false
+	})
+
+	// Tables used for page layout are converted as ordinary blocks.
+	if layout || columns < 2 || len(rows) == 0 {
+		w.breakLines(2)
+		visit(n, func(cell *html.Node) bool {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				w.breakLines(2)
+				c.children(w, cell)
+				w.breakLines(2)
+				return false
+			}
+			return true
+		})
+		w.breakLines(2)
+		return
+	}
+
+	writeRow := func(cells []string) {
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		w.breakLines(1)
+		w.raw("| " + strings.Join(cells, " | ") + " |")
+	}
+
+	w.breakLines(2)
+	if caption := findFirst(n, atom.Caption); caption != nil {
+		if text := strings.TrimSpace(c.inlineText(caption)); text != "" {
+			w.raw("**" + text + "**")
+			w.breakLines(2)
+		}
+	}
+
+	writeRow(rows[0])
+	separator := make([]string, columns)
+	for i := range separator {
+		separator[i] = "---"
+	}
+	writeRow(separator)
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	w.breakLines(2)
+}
+
+func (c *converter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || c.base == nil {
+		return ref
+	}
+
+	u, err := c.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func hidden(n *html.Node) bool {
+	if _, ok := attrValue(n, "hidden"); ok {
+		return true
+	}
+	if getAttr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(getAttr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+func documentTitle(doc *html.Node) string {
+	title := findFirst(doc, atom.Title)
+	if title == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(textContent(title)), " ")
+}
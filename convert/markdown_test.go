@@ -0,0 +1,116 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "heading and inline formatting",
+			html: `<html><head><title>T</title></head><body><h1>Hello</h1><p>Some <strong>bold</strong> and <em>it</em> text.</p></body></html>`,
+			want: "# Hello\n\nSome **bold** and _it_ text.",
+		},
+		{
+			name: "title stands in for a missing h1",
+			html: `<html><head><title>Page Title</title></head><body><p>x</p></body></html>`,
+			want: "# Page Title\n\nx",
+		},
+		{
+			name: "links resolved, fragments and titles",
+			html: `<body><p>See <a href="/docs">the docs</a> and <a href="#top">top</a> and <a href="https://x.com" title="X">x</a>.</p></body>`,
+			want: "See [the docs](https://example.com/docs) and top and [x](https://x.com \"X\").",
+		},
+		{
+			name: "images with lazy sources",
+			html: `<body><img src="/a.png" alt="An  image"><img src="data:xx" data-src="b.png" alt="b"></body>`,
+			want: "![An image](https://example.com/a.png)![b](https://example.com/base/b.png)",
+		},
+		{
+			name: "nested and numbered lists",
+			html: `<body><ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul><ol start="3"><li>three</li><li>four</li></ol></body>`,
+			want: "- one\n- two\n  - nested\n\n3. three\n4. four",
+		},
+		{
+			name: "code block keeps language and indentation",
+			html: "<body><pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}</code></pre></body>",
+			want: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		},
+		{
+			name: "data table with caption, escaped pipes and short rows",
+			html: `<body><table><caption>Cap</caption><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>x|y</td></tr><tr><td>2</td></tr></table></body>`,
+			want: "**Cap**\n\n| A | B |\n| --- | --- |\n| 1 | x\\|y |\n| 2 |  |",
+		},
+		{
+			name: "single-column table is a layout table",
+			html: `<body><table><tr><td>only</td></tr></table></body>`,
+			want: "only",
+		},
+		{
+			name: "hidden and interactive elements dropped",
+			html: `<body><p>visible</p><p hidden>h</p><p style="display: none">d</p><div aria-hidden="true">a</div><script>var x</script><button>b</button></body>`,
+			want: "visible",
+		},
+		{
+			name: "blockquote, rule, backticks in code and line breaks",
+			html: "<body><blockquote><p>quoted</p><p>two</p></blockquote><hr><p>Use <code>a `b</code> here</p><p>line<br>break</p></body>",
+			want: "> quoted\n>\n> two\n\n---\n\nUse `` a `b `` here\n\nline\nbreak",
+		},
+		{
+			name: "definition list and strikethrough",
+			html: `<body><dl><dt>Term</dt><dd>Definition</dd></dl><p><del>old</del></p></body>`,
+			want: "**Term**\nDefinition\n\n~~old~~",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToMarkdown(tt.html, "https://example.com/base/page", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToMarkdownMainContent(t *testing.T) {
+	page := `<html><head><title>Article</title></head><body>
+		<nav><a href="/">Home</a><a href="/a">A</a><a href="/b">B</a></nav>
+		<div class="sidebar"><p>Ads ads</p></div>
+		<article>
+			<h1>Real Title</h1>
+			<p>This is the first paragraph of the article with plenty of text, commas, and words so it scores well.</p>
+			<p>A second paragraph, also long enough, with more commas, and content for the readability scorer.</p>
+		</article>
+		<footer><p>Copyright</p></footer>
+	</body></html>`
+
+	got, err := ToMarkdown(page, "https://example.com/", Options{MainContent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "# Real Title\n\nThis is the first paragraph") {
+		t.Errorf("main content missing:\n%s", got)
+	}
+	for _, boilerplate := range []string{"Home", "Ads ads", "Copyright"} {
+		if strings.Contains(got, boilerplate) {
+			t.Errorf("boilerplate %q kept:\n%s", boilerplate, got)
+		}
+	}
+
+	whole, err := ToMarkdown(page, "https://example.com/", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(whole, "Copyright") {
+		t.Errorf("whole page lost its footer:\n%s", whole)
+	}
+}
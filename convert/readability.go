@@ -0,0 +1,185 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|consent|disqus|footer|header|legends|menu|modal|nav|newsletter|popup|promo|related|remark|replies|share|shoutbox|sidebar|skip|social|sponsor|subscribe|ad-break|advert|agegate|pagination|pager`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|post|entry|text|story`)
+	positiveHint       = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeHint       = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|ad-|advert`)
+)
+
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Footer: true,
+	atom.Aside:  true,
+	atom.Form:   true,
+	atom.Dialog: true,
+}
+
+// MainContent returns the node most likely to hold the page's article, in
+// the spirit of Mozilla's Readability. Boilerplate such as navigation,
+// footers and sidebars is removed from the document in place. If no
+// convincing candidate is found the <body> is returned.
+func MainContent(doc *html.Node) *html.Node {
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+
+	stripBoilerplate(body)
+
+	var landmarks []*html.Node
+	visit(body, func(n *html.Node) bool {
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main || getAttr(n, "role") == "main" {
+			landmarks = append(landmarks, n)
+			return false
+		}
+		return true
+	})
+	if best := longestText(landmarks); best != nil && textLength(best) > 200 {
+		return best
+	}
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = classWeight(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	visit(body, func(n *html.Node) bool {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td && n.DataAtom != atom.Blockquote {
+			return true
+		}
+
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 {
+			return false
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + minFloat(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+
+	if top == nil || textLength(top) < 200 {
+		return body
+	}
+	return top
+}
+
+func stripBoilerplate(root *html.Node) {
+	var remove []*html.Node
+	visit(root, func(n *html.Node) bool {
+		if n == root {
+			return true
+		}
+		if boilerplateElements[n.DataAtom] || skippedElements[n.DataAtom] || hidden(n) {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom == atom.Header && !insideArticle(n) {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom != atom.Body && n.DataAtom != atom.Article && n.DataAtom != atom.Main && n.DataAtom != atom.A {
+			hint := getAttr(n, "class") + " " + getAttr(n, "id") + " " + getAttr(n, "role")
+			if unlikelyCandidates.MatchString(hint) && !maybeCandidate.MatchString(hint) {
+				remove = append(remove, n)
+				return false
+			}
+		}
+		return true
+	})
+
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+func insideArticle(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Article || p.DataAtom == atom.Main {
+			return true
+		}
+	}
+	return false
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, hint := range []string{getAttr(n, "class"), getAttr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHint.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(hint) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := textLength(n)
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	for _, a := range findAll(n, atom.A) {
+		linked += len(strings.TrimSpace(textContent(a)))
+	}
+	return float64(linked) / float64(total)
+}
+
+func textLength(n *html.Node) int {
+	return len(strings.Join(strings.Fields(textContent(n)), " "))
+}
+
+func longestText(nodes []*html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	for _, n := range nodes {
+		if l := textLength(n); l > bestLen {
+			best, bestLen = n, l
+		}
+	}
+	return best
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
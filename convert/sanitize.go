@@ -0,0 +1,116 @@
+package convert
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedElements are kept by ToHTML with the attributes listed for them.
+// Other elements are dropped but their content is kept.
+var allowedElements = map[atom.Atom][]string{
+	atom.Article: nil, atom.Section: nil, atom.Div: nil, atom.P: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Blockquote: {"cite"}, atom.Pre: nil, atom.Code: nil, atom.Hr: nil, atom.Br: nil,
+	atom.Ul: nil, atom.Ol: {"start"}, atom.Li: nil, atom.Dl: nil, atom.Dt: nil, atom.Dd: nil,
+	atom.Strong: nil, atom.B: nil, atom.Em: nil, atom.I: nil, atom.U: nil, atom.S: nil,
+	atom.Del: nil, atom.Ins: nil, atom.Sub: nil, atom.Sup: nil, atom.Mark: nil, atom.Small: nil,
+	atom.Abbr: {"title"}, atom.Q: nil, atom.Cite: nil, atom.Kbd: nil, atom.Samp: nil, atom.Time: {"datetime"},
+	atom.A: {"href", "title"}, atom.Img: {"src", "alt", "title", "width", "height"},
+	atom.Figure: nil, atom.Figcaption: nil,
+	atom.Table: nil, atom.Caption: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tfoot: nil,
+	atom.Tr: nil, atom.Th: {"colspan", "rowspan", "scope"}, atom.Td: {"colspan", "rowspan"},
+}
+
+// urlAttributes must hold http(s) URLs, or mailto: for links.
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true}
+
+// ToHTML returns the document's content as sanitized HTML that is safe to
+// display: scripts, styles, forms, event handlers and other attributes are
+// removed, unknown elements are unwrapped and URLs are made absolute.
+func ToHTML(document string, pageURL string, opts Options) (string, error) {
+	_, root, err := parse(document, opts)
+	if err != nil {
+		return "", err
+	}
+
+	c := &converter{base: baseURL(pageURL)}
+	var sb strings.Builder
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		for _, n := range c.sanitize(child) {
+			if err := html.Render(&sb, n); err != nil {
+				return "", err
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// sanitize returns the clean copy of n: itself, its clean children when n
+// is not allowed, or nothing.
+func (c *converter) sanitize(n *html.Node) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if skippedElements[n.DataAtom] || hidden(n) {
+		return nil
+	}
+
+	var children []*html.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, c.sanitize(child)...)
+	}
+
+	allowed, ok := allowedElements[n.DataAtom]
+	if !ok {
+		return children
+	}
+
+	clean := &html.Node{Type: html.ElementNode, DataAtom: n.DataAtom, Data: n.DataAtom.String()}
+	for _, key := range allowed {
+		value, ok := attrValue(n, key)
+		if !ok {
+			continue
+		}
+		if urlAttributes[key] {
+			if value = c.safeURL(value, n.DataAtom == atom.A); value == "" {
+				continue
+			}
+		}
+		clean.Attr = append(clean.Attr, html.Attribute{Key: key, Val: value})
+	}
+	if n.DataAtom == atom.Img && getAttr(clean, "src") == "" {
+		return nil
+	}
+
+	for _, child := range children {
+		clean.AppendChild(child)
+	}
+	return []*html.Node{clean}
+}
+
+// safeURL resolves ref and returns it if it is an http(s) URL, or a mailto:
+// link when mail is set. Anything else, like javascript:, gives "".
+func (c *converter) safeURL(ref string, mail bool) string {
+	resolved := c.resolve(ref)
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return resolved
+	case "mailto":
+		if mail {
+			return resolved
+		}
+	}
+	return ""
+}
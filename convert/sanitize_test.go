@@ -0,0 +1,59 @@
+package convert
+
+import "testing"
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "scripts, styles and forms are dropped",
+			html: `<body><script>alert(1)</script><style>p{}</style><form><input name="q"></form><p>Text</p></body>`,
+			want: `<p>Text</p>`,
+		},
+		{
+			name: "attributes outside the allow list are removed",
+			html: `<body><p class="x" onclick="steal()" style="color:red">Text</p><td colspan="2" onmouseover="x()">cell</td></body>`,
+			want: `<p>Text</p>cell`,
+		},
+		{
+			name: "unknown elements are unwrapped",
+			html: `<body><custom-card><span>Inner <b>bold</b></span></custom-card></body>`,
+			want: `Inner <b>bold</b>`,
+		},
+		{
+			name: "URLs are resolved",
+			html: `<body><a href="/docs" title="Docs">d</a><img src="pic.png" alt="p"></body>`,
+			want: `<a href="https://example.com/docs" title="Docs">d</a><img src="https://example.com/base/pic.png" alt="p"/>`,
+		},
+		{
+			name: "unsafe URLs are removed",
+			html: `<body><a href="javascript:alert(1)">x</a><a href="mailto:a@example.com">mail</a><img src="javascript:x"></body>`,
+			want: `<a>x</a><a href="mailto:a@example.com">mail</a>`,
+		},
+		{
+			name: "hidden elements are dropped",
+			html: `<body><div hidden>secret</div><p aria-hidden="true">also</p><p>shown</p></body>`,
+			want: `<p>shown</p>`,
+		},
+		{
+			name: "tables keep their structure",
+			html: `<body><table><tr><th scope="col">A</th></tr><tr><td rowspan="2">1</td></tr></table></body>`,
+			want: `<table><tbody><tr><th scope="col">A</th></tr><tr><td rowspan="2">1</td></tr></tbody></table>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToHTML(tt.html, "https://example.com/base/page", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ToHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
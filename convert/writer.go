@@ -0,0 +1,118 @@
+package convert
+
+import "strings"
+
+// mdWriter accumulates Markdown while tracking line breaks, collapsed
+// whitespace and the indentation prefix of the current list or quote.
+type mdWriter struct {
+	sb        strings.Builder
+	indent    string
+	newlines  int
+	space     bool
+	lineStart bool
+	// holdBreaks swallows the block break that would otherwise follow a list
+	// marker, so "- " and the item's first paragraph share a line.
+	holdBreaks bool
+}
+
+func newWriter() *mdWriter {
+	return &mdWriter{lineStart: true}
+}
+
+func (w *mdWriter) breakLines(n int) {
+	// Nothing to break at the start of the output or right after settle.
+	if w.holdBreaks || w.lineStart {
+		return
+	}
+	if n > w.newlines {
+		w.newlines = n
+	}
+	w.space = false
+}
+
+func (w *mdWriter) flush() {
+	w.settle()
+	if w.lineStart {
+		w.sb.WriteString(w.indent)
+		w.lineStart = false
+		w.space = false
+	}
+	if w.space {
+		w.sb.WriteByte(' ')
+		w.space = false
+	}
+	w.holdBreaks = false
+}
+
+// settle writes any pending line breaks with the current indentation so the
+// indentation can change for what follows.
+func (w *mdWriter) settle() {
+	if w.newlines == 0 {
+		return
+	}
+	for i := 0; i < w.newlines; i++ {
+		if i > 0 {
+			w.sb.WriteString(strings.TrimRight(w.indent, " "))
+		}
+		w.sb.WriteByte('\n')
+	}
+	w.newlines = 0
+	w.lineStart = true
+}
+
+// text writes inline text, collapsing runs of whitespace to one space.
+func (w *mdWriter) text(s string) {
+	if s == "" {
+		return
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if w.sb.Len() > 0 && w.newlines == 0 {
+			w.space = true
+		}
+		return
+	}
+
+	if isSpace(s[0]) && w.sb.Len() > 0 && w.newlines == 0 {
+		w.space = true
+	}
+	w.raw(strings.Join(fields, " "))
+	if isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+// raw writes s verbatim after any pending breaks and spacing.
+func (w *mdWriter) raw(s string) {
+	w.flush()
+	w.sb.WriteString(s)
+}
+
+// inline writes an already rendered inline fragment, keeping the spacing
+// around it but trimming it inside.
+func (w *mdWriter) inline(content, open, close string) {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		if content != "" && w.sb.Len() > 0 && w.newlines == 0 {
+			w.space = true
+		}
+		return
+	}
+
+	if isSpace(content[0]) && w.sb.Len() > 0 && w.newlines == 0 {
+		w.space = true
+	}
+	w.raw(open + trimmed + close)
+	if isSpace(content[len(content)-1]) {
+		w.space = true
+	}
+}
+
+func (w *mdWriter) String() string {
+	return strings.TrimSpace(w.sb.String())
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r' || b == '\f'
+}
@@ -6,12 +6,24 @@ import (
 )
 
 var (
-	ErrInvalidURL      = errors.New("invalid URL")
-	ErrPageLoad        = errors.New("failed to load page")
-	ErrTimeout         = errors.New("operation timed out")
-	ErrProxyFailure    = errors.New("proxy connection failed")
-	ErrCloudflareBlock = errors.New("blocked by Cloudflare protection")
-	ErrLLMAPIFailure   = errors.New("LLM API call failed")
+	ErrInvalidURL       = errors.New("invalid URL")
+	ErrInvalidParams    = errors.New("invalid parameters")
+	ErrPageLoad         = errors.New("failed to load page")
+	ErrTimeout          = errors.New("operation timed out")
+	ErrProxyFailure     = errors.New("proxy connection failed")
+	ErrCloudflareBlock  = errors.New("blocked by Cloudflare protection")
+	ErrLLMAPIFailure    = errors.New("LLM API call failed")
+	ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+	ErrRateLimited      = errors.New("rate limit exceeded")
+)
+
+// Classified LLM failures. Each wraps ErrLLMAPIFailure.
+var (
+	ErrLLMRateLimited   = fmt.Errorf("%w: rate limited by provider", ErrLLMAPIFailure)
+	ErrLLMAuth          = fmt.Errorf("%w: authentication failed", ErrLLMAPIFailure)
+	ErrLLMContextLength = fmt.Errorf("%w: input exceeds the model's context length", ErrLLMAPIFailure)
+	ErrLLMServer        = fmt.Errorf("%w: provider unavailable", ErrLLMAPIFailure)
+	ErrLLMCircuitOpen   = fmt.Errorf("%w: circuit breaker open", ErrLLMAPIFailure)
 )
 
 func WithCause(err error, format string, args ...interface{}) error {
@@ -21,6 +33,18 @@ func WithCause(err error, format string, args ...interface{}) error {
 	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
 }
 
+// Wrap marks cause as an err, keeping both for IsType and AsType.
+func Wrap(err, cause error) error {
+	if cause == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", err, cause)
+}
+
 func IsType(err, target error) bool {
 	return errors.Is(err, target)
 }
+
+func AsType(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
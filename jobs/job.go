@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/Sagn1k/scarab/scraper"
+)
+
+type Status string
+
+const (
+	StatusQueued     Status = "queued"
+	StatusRendering  Status = "rendering"
+	StatusConverting Status = "converting"
+	StatusDone       Status = "done"
+	StatusFailed     Status = "failed"
+	StatusCancelled  Status = "cancelled"
+)
+
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCancelled
+}
+
+type Job struct {
+	ID string `json:"id"`
+	// Owner is the ID of the API key that submitted the job, empty when
+	// authentication is off.
+	Owner      string                `json:"-"`
+	URL        string                `json:"url"`
+	Params     scraper.ScrapeOptions `json:"params"`
+	Status     Status                `json:"status"`
+	Attempts   int                   `json:"attempts"`
+	Error      string                `json:"error,omitempty"`
+	Result     *scraper.ScrapeResult `json:"-"`
+	CreatedAt  time.Time             `json:"createdAt"`
+	UpdatedAt  time.Time             `json:"updatedAt"`
+	StartedAt  *time.Time            `json:"startedAt,omitempty"`
+	FinishedAt *time.Time            `json:"finishedAt,omitempty"`
+}
@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/artifacts"
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/scraper"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrJobFinished = errors.New("job has already finished")
+)
+
+type Scraper interface {
+	Scrape(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error)
+}
+
+// Manager queues scrape jobs and runs them on a fixed pool of workers.
+type Manager struct {
+	store     Store
+	scraper   Scraper
+	queue     chan string
+	retention time.Duration
+
+	mu        sync.Mutex
+	cancels   map[string]context.CancelFunc
+	recorders map[string][]llm.UsageRecorder
+}
+
+func NewManager(cfg *config.Config, store Store, s Scraper) *Manager {
+	workers := cfg.JobWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	queueSize := cfg.JobQueueSize
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	m := &Manager{
+		store:     store,
+		scraper:   s,
+		queue:     make(chan string, queueSize),
+		retention: time.Duration(cfg.JobRetentionMinutes) * time.Minute,
+		cancels:   make(map[string]context.CancelFunc),
+		recorders: make(map[string][]llm.UsageRecorder),
+	}
+
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+
+	return m
+}
+
+// Submit queues a scrape for owner. The job runs detached from ctx, but the
+// LLM usage recorders attached to it are carried over so the job's spend is
+// attributed to the caller.
+func (m *Manager) Submit(ctx context.Context, owner, url string, params scraper.ScrapeOptions) (*Job, error) {
+	now := time.Now()
+	job := &Job{
+		ID:        uuid.NewString(),
+		Owner:     owner,
+		URL:       url,
+		Params:    params,
+		Status:    StatusQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := m.store.Create(job); err != nil {
+		return nil, err
+	}
+
+	if recorders := llm.UsageRecorders(ctx); len(recorders) > 0 {
+		m.mu.Lock()
+		m.recorders[job.ID] = recorders
+		m.mu.Unlock()
+	}
+
+	select {
+	case m.queue <- job.ID:
+	default:
+		_ = m.store.Delete(job.ID)
+		m.mu.Lock()
+		delete(m.recorders, job.ID)
+		m.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	return job, nil
+}
+
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.store.Get(id)
+}
+
+// Cancel stops a queued or running job. Jobs that already finished are left
+// untouched and ErrJobFinished is returned.
+func (m *Manager) Cancel(id string) (*Job, error) {
+	finished := false
+	job, err := m.store.Update(id, func(job *Job) {
+		if job.Status.Finished() {
+			finished = true
+			return
+		}
+		if job.Status == StatusQueued {
+			m.finish(job, StatusCancelled, nil, "cancelled before start")
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if finished {
+		return job, ErrJobFinished
+	}
+
+	m.mu.Lock()
+	cancel, running := m.cancels[id]
+	m.mu.Unlock()
+	if running {
+		cancel()
+	}
+
+	return job, nil
+}
+
+func (m *Manager) worker() {
+	for id := range m.queue {
+		m.run(id)
+	}
+}
+
+func (m *Manager) run(id string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.mu.Lock()
+	m.cancels[id] = cancel
+	for _, r := range m.recorders[id] {
+		ctx = llm.WithUsageRecorder(ctx, r)
+	}
+	delete(m.recorders, id)
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.cancels, id)
+		m.mu.Unlock()
+	}()
+
+	started := false
+	job, err := m.store.Update(id, func(job *Job) {
+		if job.Status != StatusQueued {
+			return
+		}
+		now := time.Now()
+		job.Status = StatusRendering
+		job.StartedAt = &now
+		job.UpdatedAt = now
+		started = true
+	})
+	if err != nil || !started {
+		return
+	}
+
+	ctx = artifacts.WithOwner(ctx, job.Owner)
+	ctx = scraper.WithProgress(ctx, func(stage scraper.Stage) {
+		_, _ = m.store.Update(id, func(job *Job) {
+			switch stage {
+			case scraper.StageNavigating:
+				job.Status = StatusRendering
+				job.Attempts++
+			case scraper.StageConverting:
+				job.Status = StatusConverting
+			}
+			job.UpdatedAt = time.Now()
+		})
+	})
+
+	result, scrapeErr := m.scraper.Scrape(ctx, job.URL, job.Params)
+
+	_, _ = m.store.Update(id, func(job *Job) {
+		switch {
+		case scrapeErr == nil:
+			m.finish(job, StatusDone, result, "")
+		case errors.Is(ctx.Err(), context.Canceled):
+			m.finish(job, StatusCancelled, nil, scrapeErr.Error())
+		default:
+			m.finish(job, StatusFailed, nil, scrapeErr.Error())
+		}
+	})
+}
+
+func (m *Manager) finish(job *Job, status Status, result *scraper.ScrapeResult, errMsg string) {
+	now := time.Now()
+	job.Status = status
+	job.Result = result
+	job.Error = errMsg
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+
+	if m.retention > 0 {
+		id := job.ID
+		time.AfterFunc(m.retention, func() {
+			_ = m.store.Delete(id)
+		})
+	}
+}
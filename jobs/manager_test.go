@@ -0,0 +1,277 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/scraper"
+)
+
+// scrapeFunc adapts a function to the Scraper interface.
+type scrapeFunc func(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error)
+
+func (f scrapeFunc) Scrape(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error) {
+	return f(ctx, url, opts)
+}
+
+// blockingScraper waits until release is closed or the job is cancelled.
+func blockingScraper(started chan<- string, release <-chan struct{}) Scraper {
+	return scrapeFunc(func(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error) {
+		started <- url
+		select {
+		case <-release:
+			return &scraper.ScrapeResult{URL: url}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+}
+
+// flakyRenderer fails its first renders and then serves a plain page.
+type flakyRenderer struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (r *flakyRenderer) RenderPage(ctx context.Context, url string, options *scraper.RenderOptions) (*scraper.RenderResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		return nil, errors.New("page crashed")
+	}
+	return &scraper.RenderResult{HTML: "<h1>Page</h1>", URL: url}, nil
+}
+
+// renderingScraper is a ScraperService that renders with r and converts
+// natively, so it reports its stages like a real scrape.
+func renderingScraper(r scraper.Renderer) Scraper {
+	s := scraper.NewScraperService(&config.Config{Converter: scraper.ConverterNative})
+	s.SetRenderer(r)
+	return s
+}
+
+// waitFinished polls the job until it finishes.
+func waitFinished(t *testing.T, m *Manager, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestManagerRunsJobs(t *testing.T) {
+	tests := []struct {
+		name         string
+		scrape       Scraper
+		wantStatus   Status
+		wantError    string
+		wantAttempts int
+	}{
+		{
+			"success",
+			scrapeFunc(func(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error) {
+				return &scraper.ScrapeResult{URL: url, Markdown: "# Page"}, nil
+			}),
+			StatusDone, "", 0,
+		},
+		{
+			"failure",
+			scrapeFunc(func(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error) {
+				return nil, errors.New("page failed to load")
+			}),
+			StatusFailed, "page failed to load", 0,
+		},
+		{
+			"counts render attempts",
+			renderingScraper(&flakyRenderer{failures: 1}),
+			StatusDone, "", 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(&config.Config{}, NewMemoryStore(), tt.scrape)
+
+			submitted, err := m.Submit(context.Background(), "", "https://example.com/", scraper.ScrapeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if submitted.Status != StatusQueued {
+				t.Errorf("submitted Status = %q, want queued", submitted.Status)
+			}
+
+			job := waitFinished(t, m, submitted.ID)
+			if job.Status != tt.wantStatus || job.Error != tt.wantError || job.Attempts != tt.wantAttempts {
+				t.Errorf("job = %s %q after %d attempts, want %s %q after %d", job.Status, job.Error, job.Attempts, tt.wantStatus, tt.wantError, tt.wantAttempts)
+			}
+			if (job.Result != nil) != (tt.wantStatus == StatusDone) {
+				t.Errorf("Result = %v", job.Result)
+			}
+			if job.StartedAt == nil || job.FinishedAt == nil {
+				t.Errorf("StartedAt, FinishedAt = %v, %v", job.StartedAt, job.FinishedAt)
+			}
+		})
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	m := NewManager(&config.Config{JobWorkers: 1, JobQueueSize: 2}, NewMemoryStore(), blockingScraper(started, release))
+	ctx := context.Background()
+
+	running, err := m.Submit(ctx, "", "https://example.com/running", scraper.ScrapeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	queued, err := m.Submit(ctx, "", "https://example.com/queued", scraper.ScrapeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The only worker is busy, so the second job is still queued.
+	job, err := m.Cancel(queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusCancelled || job.Error != "cancelled before start" {
+		t.Errorf("queued job = %s %q, want cancelled before start", job.Status, job.Error)
+	}
+
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, running.ID)
+	if job.Status != StatusCancelled || job.Error != context.Canceled.Error() {
+		t.Errorf("running job = %s %q, want cancelled", job.Status, job.Error)
+	}
+
+	if _, err := m.Cancel(running.ID); err != ErrJobFinished {
+		t.Errorf("cancelling a finished job = %v, want ErrJobFinished", err)
+	}
+	if _, err := m.Cancel("missing"); err != ErrJobNotFound {
+		t.Errorf("cancelling a missing job = %v, want ErrJobNotFound", err)
+	}
+
+	// The cancelled job is skipped rather than run.
+	close(release)
+	select {
+	case url := <-started:
+		t.Errorf("%s was run after being cancelled", url)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	defer close(release)
+	m := NewManager(&config.Config{JobWorkers: 1, JobQueueSize: 1}, NewMemoryStore(), blockingScraper(started, release))
+	ctx := context.Background()
+
+	if _, err := m.Submit(ctx, "", "https://example.com/1", scraper.ScrapeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if _, err := m.Submit(ctx, "", "https://example.com/2", scraper.ScrapeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := m.Submit(ctx, "", "https://example.com/3", scraper.ScrapeOptions{})
+	if err != ErrQueueFull || job != nil {
+		t.Fatalf("Submit = %v, %v, want ErrQueueFull", job, err)
+	}
+}
+
+type usageCounter struct {
+	calls chan llm.Usage
+}
+
+func (c *usageCounter) RecordUsage(model string, usage llm.Usage) {
+	c.calls <- usage
+}
+
+func TestManagerCarriesUsageRecorders(t *testing.T) {
+	m := NewManager(&config.Config{}, NewMemoryStore(), scrapeFunc(func(ctx context.Context, url string, opts scraper.ScrapeOptions) (*scraper.ScrapeResult, error) {
+		for _, r := range llm.UsageRecorders(ctx) {
+			r.RecordUsage("model", llm.Usage{TotalTokens: 42})
+		}
+		return &scraper.ScrapeResult{URL: url}, nil
+	}))
+
+	counter := &usageCounter{calls: make(chan llm.Usage, 1)}
+	ctx, cancel := context.WithCancel(llm.WithUsageRecorder(context.Background(), counter))
+	job, err := m.Submit(ctx, "", "https://example.com/", scraper.ScrapeOptions{})
+	// The job outlives the request that submitted it.
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFinished(t, m, job.ID)
+	select {
+	case usage := <-counter.calls:
+		if usage.TotalTokens != 42 {
+			t.Errorf("TotalTokens = %d, want 42", usage.TotalTokens)
+		}
+	default:
+		t.Error("the job's usage was not recorded")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	job := &Job{ID: "a", Status: StatusQueued}
+	if err := store.Create(job); err != nil {
+		t.Fatal(err)
+	}
+
+	// The store keeps its own copy.
+	job.Status = StatusDone
+	got, err := store.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusQueued {
+		t.Errorf("Status = %q, changed through the created job", got.Status)
+	}
+	got.Status = StatusFailed
+
+	updated, err := store.Update("a", func(job *Job) { job.Attempts++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != StatusQueued || updated.Attempts != 1 {
+		t.Errorf("updated = %s after %d attempts", updated.Status, updated.Attempts)
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"Get":    func() error { _, err := store.Get("a"); return err }(),
+		"Update": func() error { _, err := store.Update("a", func(*Job) {}); return err }(),
+		"Delete": store.Delete("a"),
+	} {
+		if err != ErrJobNotFound {
+			t.Errorf("%s of a deleted job = %v, want ErrJobNotFound", name, err)
+		}
+	}
+}
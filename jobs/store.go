@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Store persists jobs between the API and the workers. Update applies fn to
+// the stored job atomically.
+type Store interface {
+	Create(job *Job) error
+	Get(id string) (*Job, error)
+	Update(id string, fn func(job *Job)) (*Job, error)
+	Delete(id string) error
+}
+
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+func (s *MemoryStore) Create(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	copied := *job
+	return &copied, nil
+}
+
+func (s *MemoryStore) Update(id string, fn func(job *Job)) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	fn(job)
+
+	copied := *job
+	return &copied, nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+
+	delete(s.jobs, id)
+	return nil
+}
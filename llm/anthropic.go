@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+type AnthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type AnthropicResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      AnthropicUsage `json:"usage"`
+}
+
+// AnthropicStreamEvent covers the fields used from the message_start,
+// content_block_delta, message_delta and error events of a stream.
+type AnthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *AnthropicResponse `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *AnthropicUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type anthropicErrorBody struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// AnthropicProvider speaks the Anthropic Messages API.
+type AnthropicProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func NewAnthropicProvider(baseURL, apiKey, model string, httpClient *http.Client) *AnthropicProvider {
+	return &AnthropicProvider{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: httpClient,
+	}
+}
+
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+func (p *AnthropicProvider) DefaultModel() string {
+	return p.model
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, p.request(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	var apiResp AnthropicResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	var content strings.Builder
+	for _, block := range apiResp.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	return &CompletionResponse{
+		Content:   content.String(),
+		Model:     apiResp.Model,
+		Truncated: apiResp.StopReason == "max_tokens",
+		Usage: Usage{
+			PromptTokens:     apiResp.Usage.InputTokens,
+			CompletionTokens: apiResp.Usage.OutputTokens,
+			TotalTokens:      apiResp.Usage.InputTokens + apiResp.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (p *AnthropicProvider) CompleteStream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error) {
+	request := p.request(req)
+	request.Stream = true
+
+	resp, err := p.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &CompletionResponse{}
+	var content strings.Builder
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev AnthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("error parsing stream: %w", err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				result.Model = ev.Message.Model
+				result.Usage.PromptTokens = ev.Message.Usage.InputTokens
+			}
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				content.WriteString(ev.Delta.Text)
+				onDelta(ev.Delta.Text)
+			}
+		case "message_delta":
+			if ev.Delta.StopReason == "max_tokens" {
+				result.Truncated = true
+			}
+			if ev.Usage != nil {
+				result.Usage.CompletionTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			// Errors after the stream started arrive as events, mostly
+			// overloaded_error.
+			apiErr := &APIError{Provider: p.Name(), StatusCode: http.StatusServiceUnavailable}
+			if ev.Error != nil {
+				apiErr.Type, apiErr.Message = ev.Error.Type, ev.Error.Message
+				if ev.Error.Type == "rate_limit_error" {
+					apiErr.StatusCode = http.StatusTooManyRequests
+				}
+			}
+			return apiErr
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, streamError(ctx, err)
+	}
+
+	result.Content = content.String()
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return result, nil
+}
+
+func (p *AnthropicProvider) request(req CompletionRequest) AnthropicRequest {
+	system := req.System
+	if req.JSON {
+		// The Messages API has no JSON mode; ask for it in the prompt instead.
+		system += "\n\nRespond with a single JSON value and nothing else."
+	}
+
+	request := AnthropicRequest{
+		Model:       req.Model,
+		System:      system,
+		Messages:    req.Messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if request.MaxTokens <= 0 {
+		request.MaxTokens = 4096
+	}
+	return request
+}
+
+// send posts req and returns the response if it succeeded. The caller
+// closes the body.
+func (p *AnthropicProvider) send(ctx context.Context, req AnthropicRequest) (*http.Response, error) {
+	apiURL := strings.TrimSuffix(p.baseURL, "/") + "/v1/messages"
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	return postJSON(ctx, p.httpClient, p.Name(), apiURL, headers, req, parseAnthropicError)
+}
+
+func parseAnthropicError(apiErr *APIError, body []byte) {
+	var errBody anthropicErrorBody
+	if json.Unmarshal(body, &errBody) == nil && errBody.Error.Message != "" {
+		apiErr.Message = errBody.Error.Message
+		apiErr.Type = errBody.Error.Type
+	}
+}
@@ -0,0 +1,301 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var droppedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+	atom.Iframe:   true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+var keptAttributes = map[string]bool{
+	"href":    true,
+	"src":     true,
+	"alt":     true,
+	"title":   true,
+	"colspan": true,
+	"rowspan": true,
+	"lang":    true,
+}
+
+// rewrappedElements are split into pieces that each keep the element's
+// opening and closing tags, so that table rows and list items stay inside a
+// table or list.
+var rewrappedElements = map[atom.Atom]bool{
+	atom.Table: true,
+	atom.Thead: true,
+	atom.Tbody: true,
+	atom.Tfoot: true,
+	atom.Tr:    true,
+	atom.Ul:    true,
+	atom.Ol:    true,
+	atom.Dl:    true,
+}
+
+// ChunkHTML strips markup that carries no content (scripts, styles, most
+// attributes) and splits the body into pieces of at most maxChars, breaking
+// only between elements. Elements larger than maxChars are split between
+// their children, and oversized text between words. Tables and lists that
+// are split keep their tags in every piece, and tables repeat their <thead>.
+func ChunkHTML(document string, maxChars int) []string {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return ChunkText(document, maxChars)
+	}
+
+	root := doc
+	visitHTML(doc, func(n *html.Node) bool {
+		if n.DataAtom == atom.Body {
+			root = n
+			return false
+		}
+		return true
+	})
+	cleanNode(root)
+
+	s := &splitter{max: maxChars}
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		s.add(child)
+	}
+	s.flush()
+
+	return s.chunks
+}
+
+// ChunkText splits text between paragraphs, falling back to lines and then
+// words for paragraphs longer than maxChars.
+func ChunkText(text string, maxChars int) []string {
+	s := &splitter{max: maxChars}
+	for _, para := range strings.SplitAfter(text, "\n\n") {
+		s.addText(para)
+	}
+	s.flush()
+
+	return s.chunks
+}
+
+type splitter struct {
+	max    int
+	chunks []string
+	cur    strings.Builder
+	// wrap holds the tags of the elements being split, outermost first.
+	// Every chunk is wrapped in them.
+	wrap []wrapper
+}
+
+type wrapper struct {
+	open, close string
+}
+
+func (s *splitter) flush() {
+	if strings.TrimSpace(s.cur.String()) != "" {
+		var sb strings.Builder
+		for _, w := range s.wrap {
+			sb.WriteString(w.open)
+		}
+		sb.WriteString(s.cur.String())
+		for i := len(s.wrap) - 1; i >= 0; i-- {
+			sb.WriteString(s.wrap[i].close)
+		}
+		s.chunks = append(s.chunks, sb.String())
+	}
+	s.cur.Reset()
+}
+
+// overhead is the length of the tags every chunk is wrapped in.
+func (s *splitter) overhead() int {
+	n := 0
+	for _, w := range s.wrap {
+		n += len(w.open) + len(w.close)
+	}
+	return n
+}
+
+// room is how much content fits in a chunk besides its wrapping tags.
+func (s *splitter) room() int {
+	return s.max - s.overhead()
+}
+
+func (s *splitter) fits(n int) bool {
+	return s.max <= 0 || s.cur.Len()+n <= s.room()
+}
+
+func (s *splitter) add(n *html.Node) {
+	var sb strings.Builder
+	if err := html.Render(&sb, n); err != nil {
+		return
+	}
+	rendered := sb.String()
+
+	if s.fits(len(rendered)) {
+		s.cur.WriteString(rendered)
+		return
+	}
+	if len(rendered) <= s.room() {
+		s.flush()
+		s.cur.WriteString(rendered)
+		return
+	}
+
+	if n.Type == html.ElementNode && n.FirstChild != nil {
+		s.split(n)
+		return
+	}
+
+	s.addText(rendered)
+}
+
+// split adds the children of n one by one. Tables and lists start a new
+// chunk and wrap each of their pieces in their own tags.
+func (s *splitter) split(n *html.Node) {
+	w, skip, ok := s.wrapperFor(n)
+	if !ok {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			s.add(child)
+		}
+		return
+	}
+
+	s.flush()
+	s.wrap = append(s.wrap, w)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child != skip {
+			s.add(child)
+		}
+	}
+	s.flush()
+	s.wrap = s.wrap[:len(s.wrap)-1]
+}
+
+// wrapperFor returns the tags each piece of n is wrapped in, and the
+// <thead> of a table when it is repeated as part of them. Tags are only
+// repeated while they take up at most half a chunk.
+func (s *splitter) wrapperFor(n *html.Node) (w wrapper, skip *html.Node, ok bool) {
+	if !rewrappedElements[n.DataAtom] {
+		return wrapper{}, nil, false
+	}
+
+	shallow := &html.Node{Type: html.ElementNode, DataAtom: n.DataAtom, Data: n.Data, Attr: n.Attr}
+	var sb strings.Builder
+	if err := html.Render(&sb, shallow); err != nil {
+		return wrapper{}, nil, false
+	}
+	w.close = "</" + n.Data + ">"
+	w.open = strings.TrimSuffix(sb.String(), w.close)
+
+	limit := s.max/2 - s.overhead()
+	if len(w.open)+len(w.close) > limit {
+		return wrapper{}, nil, false
+	}
+
+	if n.DataAtom == atom.Table {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || child.DataAtom != atom.Thead {
+				continue
+			}
+			var head strings.Builder
+			if err := html.Render(&head, child); err == nil && len(w.open)+head.Len()+len(w.close) <= limit {
+				w.open += head.String()
+				skip = child
+			}
+			break
+		}
+	}
+
+	return w, skip, true
+}
+
+func (s *splitter) addText(text string) {
+	if s.fits(len(text)) {
+		s.cur.WriteString(text)
+		return
+	}
+	room := s.room()
+	if len(text) <= room {
+		s.flush()
+		s.cur.WriteString(text)
+		return
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if len(line) <= room {
+			s.addText(line)
+			continue
+		}
+		for _, word := range strings.SplitAfter(line, " ") {
+			for len(word) > room {
+				s.flush()
+				cut := runeCut(word, room)
+				s.cur.WriteString(word[:cut])
+				word = word[cut:]
+			}
+			s.addText(word)
+		}
+	}
+}
+
+// runeCut returns where to cut s to keep at most n bytes without splitting a
+// rune, keeping at least the first rune.
+func runeCut(s string, n int) int {
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		_, cut = utf8.DecodeRuneInString(s)
+	}
+	return cut
+}
+
+func cleanNode(n *html.Node) {
+	var next *html.Node
+	for child := n.FirstChild; child != nil; child = next {
+		next = child.NextSibling
+
+		switch child.Type {
+		case html.CommentNode:
+			n.RemoveChild(child)
+			continue
+		case html.ElementNode:
+			if droppedElements[child.DataAtom] {
+				n.RemoveChild(child)
+				continue
+			}
+			attrs := child.Attr[:0]
+			for _, a := range child.Attr {
+				if keptAttributes[a.Key] && !strings.HasPrefix(a.Val, "data:") {
+					attrs = append(attrs, a)
+				}
+			}
+			child.Attr = attrs
+		case html.TextNode:
+			if strings.TrimSpace(child.Data) == "" {
+				child.Data = "\n"
+			}
+		}
+
+		cleanNode(child)
+	}
+}
+
+func visitHTML(n *html.Node, fn func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		visitHTML(child, fn)
+	}
+}
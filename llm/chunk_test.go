@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkHTML(t *testing.T) {
+	rows := func(n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "<tr><td>row %02d</td></tr>", i)
+		}
+		return sb.String()
+	}
+	items := func(n int) string {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "<li>item %02d</li>", i)
+		}
+		return sb.String()
+	}
+
+	tests := []struct {
+		name     string
+		html     string
+		max      int
+		want     []string
+		wantEach func(t *testing.T, chunk string)
+	}{
+		{
+			name: "fits in one chunk",
+			html: `<p>one</p><p>two</p>`,
+			max:  100,
+			want: []string{"<p>one</p><p>two</p>"},
+		},
+		{
+			name: "drops scripts, styles, comments and attributes",
+			html: `<p class="x" onclick="y()">a<!-- c --><script>s()</script><style>p{}</style></p><a href="/b" id="z">b</a><img src="data:image/png;base64,AA">`,
+			max:  0,
+			want: []string{`<p>a</p><a href="/b">b</a><img/>`},
+		},
+		{
+			name: "breaks between elements",
+			html: `<p>aaaa</p><p>bbbb</p><p>cccc</p>`,
+			max:  24,
+			want: []string{"<p>aaaa</p><p>bbbb</p>", "<p>cccc</p>"},
+		},
+		{
+			name: "splits oversized text between words",
+			html: `<p>alpha beta gamma delta</p>`,
+			max:  12,
+			want: []string{"alpha beta ", "gamma delta"},
+		},
+		{
+			name: "table pieces keep the table and repeat its header",
+			html: `<table class="x" title="t"><thead><tr><th>name</th></tr></thead><tbody>` + rows(10) + `</tbody></table>`,
+			max:  160,
+			wantEach: func(t *testing.T, chunk string) {
+				prefix := `<table title="t"><thead><tr><th>name</th></tr></thead><tbody>`
+				if !strings.HasPrefix(chunk, prefix) || !strings.HasSuffix(chunk, "</tbody></table>") {
+					t.Errorf("chunk is not a whole table with its header: %q", chunk)
+				}
+				if strings.Count(chunk, "<thead>") != 1 {
+					t.Errorf("chunk has %d headers, want 1: %q", strings.Count(chunk, "<thead>"), chunk)
+				}
+			},
+		},
+		{
+			name: "list pieces keep the list",
+			html: `<ol start="3">` + items(12) + `</ol>`,
+			max:  80,
+			wantEach: func(t *testing.T, chunk string) {
+				if !strings.HasPrefix(chunk, "<ol>") || !strings.HasSuffix(chunk, "</ol>") {
+					t.Errorf("chunk is not a whole list: %q", chunk)
+				}
+			},
+		},
+		{
+			name: "nested list pieces keep both lists",
+			html: `<ul><li>top</li><li><ul>` + items(12) + `</ul></li></ul>`,
+			max:  100,
+			wantEach: func(t *testing.T, chunk string) {
+				if !strings.HasPrefix(chunk, "<ul>") || !strings.HasSuffix(chunk, "</ul>") {
+					t.Errorf("chunk is not a whole list: %q", chunk)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkHTML(tt.html, tt.max)
+
+			if tt.want != nil {
+				if fmt.Sprint(chunks) != fmt.Sprint(tt.want) {
+					t.Errorf("ChunkHTML() = %q, want %q", chunks, tt.want)
+				}
+				return
+			}
+
+			if len(chunks) < 2 {
+				t.Fatalf("ChunkHTML() = %q, want several chunks", chunks)
+			}
+			for _, chunk := range chunks {
+				if len(chunk) > tt.max {
+					t.Errorf("chunk is %d chars, over the limit of %d: %q", len(chunk), tt.max, chunk)
+				}
+				tt.wantEach(t, chunk)
+			}
+		})
+	}
+}
+
+func TestChunkHTMLKeepsEveryRow(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<table><thead><tr><th>h</th></tr></thead><tbody>")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&sb, "<tr><td>row %02d</td></tr>", i)
+	}
+	sb.WriteString("</tbody></table>")
+
+	joined := strings.Join(ChunkHTML(sb.String(), 200), "")
+	for i := 0; i < 40; i++ {
+		if row := fmt.Sprintf("<tr><td>row %02d</td></tr>", i); strings.Count(joined, row) != 1 {
+			t.Errorf("row %d appears %d times, want 1", i, strings.Count(joined, row))
+		}
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []string
+	}{
+		{
+			name: "no limit",
+			text: "one\n\ntwo",
+			max:  0,
+			want: []string{"one\n\ntwo"},
+		},
+		{
+			name: "breaks between paragraphs",
+			text: "first para\n\nsecond para\n\nthird",
+			max:  25,
+			want: []string{"first para\n\nsecond para\n\n", "third"},
+		},
+		{
+			name: "breaks long paragraphs between lines",
+			text: "line one\nline two\nline three",
+			max:  18,
+			want: []string{"line one\nline two\n", "line three"},
+		},
+		{
+			name: "cuts words longer than the limit",
+			text: "abcdefghij",
+			max:  4,
+			want: []string{"abcd", "efgh", "ij"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChunkText(tt.text, tt.max); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ChunkText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkingKeepsRunesWhole(t *testing.T) {
+	text := strings.Repeat("漢字かな", 200)
+
+	for name, chunks := range map[string][]string{
+		"ChunkText": ChunkText(text, 100),
+		"ChunkHTML": ChunkHTML("<p>"+text+"</p>", 100),
+	} {
+		if len(chunks) < 2 {
+			t.Fatalf("%s() made %d chunks, want several", name, len(chunks))
+		}
+		for i, chunk := range chunks {
+			if !utf8.ValidString(chunk) {
+				t.Errorf("%s() chunk %d is not valid UTF-8: %q", name, i, chunk)
+			}
+		}
+	}
+
+	if got := tail(text, 100); !utf8.ValidString(got) || len(got) > 100 {
+		t.Errorf("tail() = %q, want at most 100 bytes of whole runes", got)
+	}
+}
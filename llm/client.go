@@ -1,58 +1,126 @@
 package llm
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Sagn1k/scarab/config"
 )
 
 type Client struct {
-	config     *config.Config
-	httpClient *http.Client
+	config    *config.Config
+	providers map[string]Provider
+	provider  Provider
+	model     string
+	breakers  *breakers
 }
 
 func NewClient(cfg *config.Config) *Client {
+	httpClient := &http.Client{}
+
+	providers := map[string]Provider{}
+	for _, p := range []Provider{
+		NewOpenAIProvider(cfg.LLMAPIBaseURL, cfg.LLMAPIKey, cfg.LLMModel, httpClient),
+		NewAnthropicProvider(cfg.AnthropicBaseURL, cfg.AnthropicAPIKey, cfg.AnthropicModel, httpClient),
+		NewOllamaProvider(cfg.OllamaBaseURL, cfg.OllamaModel, httpClient),
+	} {
+		providers[p.Name()] = p
+	}
+
+	provider, ok := providers[cfg.LLMProvider]
+	if !ok {
+		provider = providers["openai"]
+	}
+
 	return &Client{
-		config:     cfg,
-		httpClient: &http.Client{},
+		config:    cfg,
+		providers: providers,
+		provider:  provider,
+		model:     provider.DefaultModel(),
+		breakers:  newBreakers(cfg.LLMBreakerThreshold, time.Duration(cfg.LLMBreakerCooldownSeconds)*time.Second),
 	}
 }
 
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// WithProvider returns a client that sends its calls to the named provider
+// and model. Empty arguments keep the current provider or use the
+// provider's default model.
+func (c *Client) WithProvider(name, model string) (*Client, error) {
+	provider := c.provider
+	if name != "" {
+		p, ok := c.providers[name]
+		if !ok {
+			return nil, unknownProvider(name)
+		}
+		provider = p
+	}
+
+	if model == "" {
+		model = provider.DefaultModel()
+		if provider == c.provider {
+			model = c.model
+		}
+	}
+
+	return &Client{
+		config:    c.config,
+		providers: c.providers,
+		provider:  provider,
+		model:     model,
+		breakers:  c.breakers,
+	}, nil
 }
 
-type OpenAIRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
+// RegisterProvider adds or replaces a provider, e.g. a FakeProvider in tests.
+// Replacing the client's current provider, or registering the one
+// LLM_PROVIDER names, switches the client to it.
+func (c *Client) RegisterProvider(p Provider) {
+	c.providers[p.Name()] = p
+	if c.provider.Name() == p.Name() || c.config.LLMProvider == p.Name() {
+		if c.model == c.provider.DefaultModel() {
+			c.model = p.DefaultModel()
+		}
+		c.provider = p
+	}
 }
 
-type OpenAIResponse struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	Choices []struct {
-		Index        int     `json:"index"`
-		Message      Message `json:"message"`
-		FinishReason string  `json:"finish_reason"`
-	} `json:"choices"`
+// HasProvider reports whether a provider called name is registered.
+func (c *Client) HasProvider(name string) bool {
+	_, ok := c.providers[name]
+	return ok
 }
 
-func (c *Client) HTMLToMarkdown(ctx context.Context, html string, url string) (string, error) {
-	if len(html) > 100000 {
-		html = html[:100000] + "..."
-	}
+func (c *Client) Provider() string {
+	return c.provider.Name()
+}
 
+func (c *Client) Model() string {
+	return c.model
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Conversion is the markdown produced for a page together with what, if
+// anything, had to be left out to fit the model.
+type Conversion struct {
+	Markdown string
+	Chunks   int
+	// Truncated is set when chunks were dropped or the model stopped at its
+	// output token limit.
+	Truncated    bool
+	DroppedChars int
+}
+
+func (c *Client) HTMLToMarkdown(ctx context.Context, html string, url string) (*Conversion, error) {
 	systemPrompt := fmt.Sprintf(`You are an expert web content extractor. 
 Your task is to analyze the given HTML content from the URL: %s
 and convert it to clean, well-formatted markdown. 
@@ -69,64 +137,235 @@ Focus on extracting the meaningful content:
 
 Return ONLY the markdown content with no additional explanations or notes.`, url)
 
-	userMessage := fmt.Sprintf("Here is the HTML content to convert to markdown:\n\n%s", html)
+	chunks := ChunkHTML(html, c.config.LLMChunkChars)
+	return c.convertChunks(ctx, chunks, systemPrompt, "Here is the HTML content to convert to markdown:\n\n")
+}
 
-	request := OpenAIRequest{
-		Model:       c.config.LLMModel,
-		MaxTokens:   c.config.LLMMaxTokens,
-		Temperature: 0.1,
-		Messages: []Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userMessage},
-		},
+// CleanupMarkdown asks the LLM to tidy Markdown produced by the native
+// converter. It is much cheaper than HTMLToMarkdown since the page has
+// already been stripped of markup and boilerplate.
+func (c *Client) CleanupMarkdown(ctx context.Context, markdown string, url string) (*Conversion, error) {
+	systemPrompt := fmt.Sprintf(`You are an expert content editor.
+You are given markdown that was converted automatically from the web page at: %s
+
+Clean it up:
+1. Remove leftover navigation, advertisements, cookie notices and other boilerplate
+2. Fix broken formatting (headers, lists, tables, code blocks)
+3. Keep all meaningful content, links and images; do not summarize or invent content
+
+Return ONLY the markdown content with no additional explanations or notes.`, url)
+
+	chunks := ChunkText(markdown, c.config.LLMChunkChars)
+	return c.convertChunks(ctx, chunks, systemPrompt, "")
+}
+
+// convertChunks converts each chunk with the same system prompt and joins
+// the results. Chunks beyond LLMMaxChunks are dropped and reported. In
+// sequential mode each call sees the end of the previous part's output so
+// the pieces join up; in parallel mode the calls run concurrently.
+func (c *Client) convertChunks(ctx context.Context, chunks []string, systemPrompt, userPrefix string) (*Conversion, error) {
+	conversion := &Conversion{}
+	if max := c.config.LLMMaxChunks; max > 0 && len(chunks) > max {
+		for _, dropped := range chunks[max:] {
+			conversion.DroppedChars += len(dropped)
+		}
+		conversion.Truncated = true
+		chunks = chunks[:max]
 	}
+	conversion.Chunks = len(chunks)
 
-	response, err := c.callAPI(ctx, request)
-	if err != nil {
-		return "", fmt.Errorf("LLM API error: %w", err)
+	if len(chunks) == 0 {
+		return nil, errors.New("no content to convert")
 	}
 
-	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
-		return "", errors.New("LLM returned empty response")
+	partPrompt := func(part int, previous string) string {
+		if len(chunks) == 1 {
+			return systemPrompt
+		}
+		prompt := systemPrompt + fmt.Sprintf(`
+
+The content is split into %d parts and this is part %d. Convert only this part. It may start or end in the middle of a section, list or table; do not add introductions, summaries or closing remarks.`, len(chunks), part+1)
+		if previous != "" {
+			prompt += fmt.Sprintf("\n\nThe markdown for the previous part ended with:\n---\n%s\n---\nContinue from there without repeating it.", previous)
+		}
+		return prompt
 	}
 
-	return response.Choices[0].Message.Content, nil
+	results := make([]string, len(chunks))
+	truncated := make([]bool, len(chunks))
+
+	// Streamed conversions run in order so the deltas join up.
+	onDelta := Deltas(ctx)
+
+	if c.config.LLMChunkMode == "parallel" && len(chunks) > 1 && onDelta == nil {
+		concurrency := c.config.LLMChunkConcurrency
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var (
+			wg       sync.WaitGroup
+			errOnce  sync.Once
+			firstErr error
+			sem      = make(chan struct{}, concurrency)
+		)
+		for i, chunk := range chunks {
+			wg.Add(1)
+			go func(i int, chunk string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				if ctx.Err() != nil {
+					return
+				}
+				content, cut, err := c.complete(ctx, partPrompt(i, ""), userPrefix+chunk, nil)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("part %d of %d: %w", i+1, len(chunks), err)
+						cancel()
+					})
+					return
+				}
+				results[i], truncated[i] = content, cut
+			}(i, chunk)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return nil, firstErr
+		}
+	} else {
+		previous := ""
+		for i, chunk := range chunks {
+			var partDelta DeltaFunc
+			if onDelta != nil {
+				started := i == 0
+				partDelta = func(delta string) {
+					if !started {
+						onDelta("\n\n")
+						started = true
+					}
+					onDelta(delta)
+				}
+			}
+
+			content, cut, err := c.complete(ctx, partPrompt(i, previous), userPrefix+chunk, partDelta)
+			if err != nil {
+				if len(chunks) > 1 {
+					return nil, fmt.Errorf("part %d of %d: %w", i+1, len(chunks), err)
+				}
+				return nil, err
+			}
+			results[i], truncated[i] = content, cut
+			previous = tail(content, 1000)
+		}
+	}
+
+	var parts []string
+	for i, result := range results {
+		if truncated[i] {
+			conversion.Truncated = true
+		}
+		if strings.TrimSpace(result) != "" {
+			parts = append(parts, strings.TrimSpace(result))
+		}
+	}
+	conversion.Markdown = strings.Join(parts, "\n\n")
+
+	return conversion, nil
 }
 
-func (c *Client) callAPI(ctx context.Context, req OpenAIRequest) (*OpenAIResponse, error) {
-	reqBody, err := json.Marshal(req)
+// complete sends one system and user message and reports whether the
+// answer was cut off by the output token limit. The answer is streamed to
+// onDelta when it is set.
+func (c *Client) complete(ctx context.Context, systemPrompt, userMessage string, onDelta DeltaFunc) (string, bool, error) {
+	response, err := c.call(ctx, CompletionRequest{
+		Model:       c.model,
+		System:      systemPrompt,
+		Messages:    []Message{{Role: "user", Content: userMessage}},
+		MaxTokens:   c.config.LLMMaxTokens,
+		Temperature: 0.1,
+	}, onDelta)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling request: %w", err)
+		return "", false, fmt.Errorf("LLM API error: %w", err)
 	}
 
-	apiURL := strings.TrimSuffix(c.config.LLMAPIBaseURL, "/") + "/v1/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+	if response.Content == "" {
+		return "", false, errEmptyResponse
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.config.LLMAPIKey)
+	return response.Content, response.Truncated, nil
+}
 
-	resp, err := c.httpClient.Do(httpReq)
+// ExtractJSON asks the LLM for a JSON document matching schema, filled from
+// the page content. On a retry, previous is the rejected answer and problems
+// lists what was wrong with it so the model can correct itself.
+func (c *Client) ExtractJSON(ctx context.Context, content, url string, schema map[string]interface{}, previous string, problems []string) (string, error) {
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
+		return "", fmt.Errorf("error marshaling schema: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response: %w", err)
+	systemPrompt := fmt.Sprintf(`You are an expert data extractor.
+Your task is to read the content of the web page at: %s
+and extract the data described by this JSON Schema:
+
+%s
+
+Rules:
+1. Return a single JSON value that validates against the schema
+2. Use only information present in the content; leave out optional properties whose values are missing
+3. Use the exact property names from the schema
+4. Numbers must be JSON numbers without currency symbols or thousands separators
+5. Dates must use ISO 8601 unless the schema says otherwise
+
+Return ONLY the JSON with no additional explanations, notes or code fences.`, url, schemaJSON)
+
+	messages := []Message{
+		{Role: "user", Content: fmt.Sprintf("Here is the page content:\n\n%s", content)},
+	}
+	if previous != "" {
+		messages = append(messages,
+			Message{Role: "assistant", Content: previous},
+			Message{Role: "user", Content: fmt.Sprintf("That JSON does not validate against the schema:\n- %s\n\nReturn the corrected JSON only.", strings.Join(problems, "\n- "))},
+		)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned non-200 status: %d, body: %s", resp.StatusCode, string(body))
+	response, err := c.call(ctx, CompletionRequest{
+		Model:       c.model,
+		System:      systemPrompt,
+		Messages:    messages,
+		MaxTokens:   c.config.LLMMaxTokens,
+		Temperature: 0,
+		// Providers' JSON modes only produce objects.
+		JSON: schema["type"] == "object",
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("LLM API error: %w", err)
 	}
 
-	var apiResp OpenAIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("error parsing response: %w", err)
+	if response.Content == "" {
+		return "", errEmptyResponse
 	}
 
-	return &apiResp, nil
+	return response.Content, nil
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	s = s[start:]
+	if i := strings.IndexAny(s, " \n"); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
 }
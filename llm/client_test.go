@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+func newFakeClient(cfg *config.Config, fake *FakeProvider) *Client {
+	cfg.LLMProvider = "fake"
+	client := NewClient(cfg)
+	client.RegisterProvider(fake)
+	client, _ = client.WithProvider("fake", "")
+	return client
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   map[string]interface{}
+		previous string
+		problems []string
+		wantJSON bool
+		wantMsgs int
+	}{
+		{
+			name:     "object schema uses JSON mode",
+			schema:   map[string]interface{}{"type": "object"},
+			wantJSON: true,
+			wantMsgs: 1,
+		},
+		{
+			name:     "array schema does not",
+			schema:   map[string]interface{}{"type": "array"},
+			wantJSON: false,
+			wantMsgs: 1,
+		},
+		{
+			name:     "schema without a type does not",
+			schema:   map[string]interface{}{"anyOf": []interface{}{}},
+			wantJSON: false,
+			wantMsgs: 1,
+		},
+		{
+			name:     "retry sends the problems back",
+			schema:   map[string]interface{}{"type": "object"},
+			previous: `{"price":"1"}`,
+			problems: []string{"/price: expected number, got string"},
+			wantJSON: true,
+			wantMsgs: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeProvider(`{"price":1}`)
+			client := newFakeClient(&config.Config{}, fake)
+
+			answer, err := client.ExtractJSON(context.Background(), "price: 1", "https://example.com", tt.schema, tt.previous, tt.problems)
+			if err != nil {
+				t.Fatalf("ExtractJSON() error = %v", err)
+			}
+			if answer != `{"price":1}` {
+				t.Errorf("ExtractJSON() = %q", answer)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("provider got %d requests, want 1", len(requests))
+			}
+			req := requests[0]
+			if req.JSON != tt.wantJSON {
+				t.Errorf("JSON = %v, want %v", req.JSON, tt.wantJSON)
+			}
+			if len(req.Messages) != tt.wantMsgs {
+				t.Errorf("got %d messages, want %d", len(req.Messages), tt.wantMsgs)
+			}
+			if strings.Contains(req.System, "null") {
+				t.Errorf("prompt asks for null values: %s", req.System)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(req.Messages[len(req.Messages)-1].Content, problem) {
+					t.Errorf("retry message does not list %q", problem)
+				}
+			}
+		})
+	}
+}
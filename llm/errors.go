@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sagn1k/scarab/errors"
+)
+
+// APIError is a non-success response from a provider, with the message
+// extracted from its error body. It unwraps to one of the classified LLM
+// errors in the errors package.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func newAPIError(provider string, resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%s API returned status %d (%s): %s", e.Provider, e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s API returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	detail := strings.ToLower(e.Type + " " + e.Message)
+
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return errors.ErrLLMRateLimited
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return errors.ErrLLMAuth
+	case e.StatusCode >= 500:
+		return errors.ErrLLMServer
+	case strings.Contains(detail, "context_length") ||
+		strings.Contains(detail, "context length") ||
+		strings.Contains(detail, "maximum context") ||
+		strings.Contains(detail, "prompt is too long") ||
+		strings.Contains(detail, "too many tokens") ||
+		e.StatusCode == http.StatusRequestEntityTooLarge:
+		return errors.ErrLLMContextLength
+	}
+	return errors.ErrLLMAPIFailure
+}
+
+// errEmptyResponse is returned for a successful call without any content.
+var errEmptyResponse = errors.WithCause(errors.ErrLLMAPIFailure, "LLM returned empty response")
+
+// unknownProvider is returned for a provider name that was asked for, for
+// example in the request params, but is not configured.
+func unknownProvider(name string) error {
+	return errors.WithCause(errors.ErrInvalidParams, "unknown LLM provider %q", name)
+}
+
+// apiFailure marks an error the provider call ended with as an LLM failure,
+// unless it is classified already.
+func apiFailure(err error) error {
+	if errors.IsType(err, errors.ErrLLMAPIFailure) {
+		return err
+	}
+	return errors.WithCause(errors.ErrLLMAPIFailure, "%v", err)
+}
+
+// retryable reports whether err may succeed if sent again unchanged.
+func retryable(err error) bool {
+	return errors.IsType(err, errors.ErrLLMRateLimited) || errors.IsType(err, errors.ErrLLMServer)
+}
+
+// parseRetryAfter reads either delay-seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
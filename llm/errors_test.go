@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/Sagn1k/scarab/errors"
+)
+
+func TestAPIErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		want error
+	}{
+		{"rate limited", &APIError{StatusCode: 429}, errors.ErrLLMRateLimited},
+		{"unauthorized", &APIError{StatusCode: 401}, errors.ErrLLMAuth},
+		{"forbidden", &APIError{StatusCode: 403}, errors.ErrLLMAuth},
+		{"server error", &APIError{StatusCode: 500}, errors.ErrLLMServer},
+		{"overloaded", &APIError{StatusCode: 529}, errors.ErrLLMServer},
+		{"openai context length", &APIError{StatusCode: 400, Type: "context_length_exceeded"}, errors.ErrLLMContextLength},
+		{"anthropic prompt too long", &APIError{StatusCode: 400, Message: "prompt is too long: 210000 tokens"}, errors.ErrLLMContextLength},
+		{"ollama context", &APIError{StatusCode: 400, Message: "input exceeds maximum context length"}, errors.ErrLLMContextLength},
+		{"too large", &APIError{StatusCode: 413}, errors.ErrLLMContextLength},
+		{"other request error", &APIError{StatusCode: 400, Message: "bad model"}, errors.ErrLLMAPIFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Unwrap(); got != tt.want {
+				t.Errorf("Unwrap() = %v, want %v", got, tt.want)
+			}
+			if !errors.IsType(tt.err, errors.ErrLLMAPIFailure) {
+				t.Error("APIError does not wrap ErrLLMAPIFailure")
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{statusError(429, 0), true},
+		{statusError(503, 0), true},
+		{statusError(401, 0), false},
+		{statusError(400, 0), false},
+		{errEmptyResponse, false},
+		{errors.ErrTimeout, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAPIFailure(t *testing.T) {
+	if err := apiFailure(statusError(429, 0)); !errors.IsType(err, errors.ErrLLMRateLimited) {
+		t.Errorf("apiFailure() lost the class: %v", err)
+	}
+	if err := apiFailure(errors.ErrTimeout); !errors.IsType(err, errors.ErrLLMAPIFailure) {
+		t.Errorf("apiFailure() = %v, want an LLM failure", err)
+	}
+}
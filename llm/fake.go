@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"unicode/utf8"
+)
+
+// FakeProvider answers without any network calls, for tests. NewClient does
+// not register it; tests add it with RegisterProvider. It returns the
+// queued responses in order and, once they run out, echoes the last user
+// message back ("{}" for JSON requests). Errors queued with FailNext are
+// returned first.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []string
+	errs      []error
+	requests  []CompletionRequest
+}
+
+func NewFakeProvider(responses ...string) *FakeProvider {
+	return &FakeProvider{responses: responses}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) DefaultModel() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+
+	content := ""
+	switch {
+	case len(p.responses) > 0:
+		content = p.responses[0]
+		p.responses = p.responses[1:]
+	case req.JSON:
+		content = "{}"
+	case len(req.Messages) > 0:
+		content = req.Messages[len(req.Messages)-1].Content
+	}
+
+	// Roughly four characters per token, so usage accounting has numbers to
+	// work with.
+	prompt := len(req.System)
+	for _, m := range req.Messages {
+		prompt += len(m.Content)
+	}
+	usage := Usage{PromptTokens: prompt / 4, CompletionTokens: len(content) / 4}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return &CompletionResponse{Content: content, Model: req.Model, Usage: usage}, nil
+}
+
+// CompleteStream answers like Complete and delivers the content in a few
+// pieces.
+func (p *FakeProvider) CompleteStream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error) {
+	response, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	content := response.Content
+	for len(content) > 0 {
+		n := 64
+		if n > len(content) {
+			n = len(content)
+		}
+		for n < len(content) && !utf8.RuneStart(content[n]) {
+			n++
+		}
+		onDelta(content[:n])
+		content = content[n:]
+	}
+	return response, nil
+}
+
+// FailNext makes the next calls fail with errs, one per call.
+func (p *FakeProvider) FailNext(errs ...error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.errs = append(p.errs, errs...)
+}
+
+// Requests returns every request the provider has received.
+func (p *FakeProvider) Requests() []CompletionRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]CompletionRequest(nil), p.requests...)
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type OllamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type OllamaResponse struct {
+	Model      string  `json:"model"`
+	Message    Message `json:"message"`
+	Done       bool    `json:"done"`
+	DoneReason string  `json:"done_reason"`
+	// Token counts; Ollama calls them eval counts.
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// OllamaProvider speaks the /api/chat endpoint of a local Ollama server. It
+// needs no API key.
+type OllamaProvider struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func NewOllamaProvider(baseURL, model string, httpClient *http.Client) *OllamaProvider {
+	return &OllamaProvider{
+		baseURL:    baseURL,
+		model:      model,
+		httpClient: httpClient,
+	}
+}
+
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+func (p *OllamaProvider) DefaultModel() string {
+	return p.model
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, p.request(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	var apiResp OllamaResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return apiResp.completion(apiResp.Message.Content), nil
+}
+
+// CompleteStream reads Ollama's stream, one JSON object per line. The last
+// object has Done set and carries the token counts.
+func (p *OllamaProvider) CompleteStream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error) {
+	request := p.request(req)
+	request.Stream = true
+
+	resp, err := p.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk OllamaResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, streamError(ctx, err)
+		}
+
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			onDelta(chunk.Message.Content)
+		}
+		if chunk.Done {
+			return chunk.completion(content.String()), nil
+		}
+	}
+}
+
+func (r *OllamaResponse) completion(content string) *CompletionResponse {
+	return &CompletionResponse{
+		Content:   content,
+		Model:     r.Model,
+		Truncated: r.DoneReason == "length",
+		Usage: Usage{
+			PromptTokens:     r.PromptEvalCount,
+			CompletionTokens: r.EvalCount,
+			TotalTokens:      r.PromptEvalCount + r.EvalCount,
+		},
+	}
+}
+
+func (p *OllamaProvider) request(req CompletionRequest) OllamaRequest {
+	request := OllamaRequest{
+		Model:    req.Model,
+		Messages: append([]Message{{Role: "system", Content: req.System}}, req.Messages...),
+		Options: map[string]interface{}{
+			"temperature": req.Temperature,
+		},
+	}
+	if req.MaxTokens > 0 {
+		request.Options["num_predict"] = req.MaxTokens
+	}
+	if req.JSON {
+		request.Format = "json"
+	}
+	return request
+}
+
+// send posts req and returns the response if it succeeded. The caller
+// closes the body.
+func (p *OllamaProvider) send(ctx context.Context, req OllamaRequest) (*http.Response, error) {
+	apiURL := strings.TrimSuffix(p.baseURL, "/") + "/api/chat"
+	return postJSON(ctx, p.httpClient, p.Name(), apiURL, nil, req, parseOllamaError)
+}
+
+func parseOllamaError(apiErr *APIError, body []byte) {
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errBody) == nil && errBody.Error != "" {
+		apiErr.Message = errBody.Error
+	}
+}
@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type OpenAIRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Temperature    float64         `json:"temperature,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+}
+
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type OpenAIResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index        int     `json:"index"`
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage OpenAIUsage `json:"usage"`
+}
+
+// OpenAIStreamChunk is one server-sent event of a streamed completion. The
+// last chunk carries no choices, only the usage.
+type OpenAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *OpenAIUsage `json:"usage"`
+}
+
+type openAIErrorBody struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"`
+	} `json:"error"`
+}
+
+// OpenAIProvider speaks /v1/chat/completions, which most hosted and
+// self-hosted APIs also implement.
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func NewOpenAIProvider(baseURL, apiKey, model string, httpClient *http.Client) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: httpClient,
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+func (p *OpenAIProvider) DefaultModel() string {
+	return p.model
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	response, err := p.callAPI(ctx, p.request(req))
+	if err != nil {
+		return nil, err
+	}
+
+	usage := Usage{
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		TotalTokens:      response.Usage.TotalTokens,
+	}
+
+	if len(response.Choices) == 0 {
+		return &CompletionResponse{Model: response.Model, Usage: usage}, nil
+	}
+
+	choice := response.Choices[0]
+	return &CompletionResponse{
+		Content:   choice.Message.Content,
+		Model:     response.Model,
+		Truncated: choice.FinishReason == "length",
+		Usage:     usage,
+	}, nil
+}
+
+func (p *OpenAIProvider) CompleteStream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error) {
+	request := p.request(req)
+	request.Stream = true
+	request.StreamOptions = &StreamOptions{IncludeUsage: true}
+
+	resp, err := p.send(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &CompletionResponse{}
+	var content strings.Builder
+	err = readSSE(resp.Body, func(event, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("error parsing stream: %w", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) > 0 {
+			choice := chunk.Choices[0]
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+			if choice.FinishReason == "length" {
+				result.Truncated = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, streamError(ctx, err)
+	}
+
+	result.Content = content.String()
+	return result, nil
+}
+
+func (p *OpenAIProvider) request(req CompletionRequest) OpenAIRequest {
+	request := OpenAIRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Messages:    append([]Message{{Role: "system", Content: req.System}}, req.Messages...),
+	}
+	if req.JSON {
+		request.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+	return request
+}
+
+func (p *OpenAIProvider) callAPI(ctx context.Context, req OpenAIRequest) (*OpenAIResponse, error) {
+	resp, err := p.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	var apiResp OpenAIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return &apiResp, nil
+}
+
+// send posts req and returns the response if it succeeded. The caller
+// closes the body.
+func (p *OpenAIProvider) send(ctx context.Context, req OpenAIRequest) (*http.Response, error) {
+	apiURL := strings.TrimSuffix(p.baseURL, "/") + "/v1/chat/completions"
+	headers := map[string]string{"Authorization": "Bearer " + p.apiKey}
+	return postJSON(ctx, p.httpClient, p.Name(), apiURL, headers, req, parseOpenAIError)
+}
+
+func parseOpenAIError(apiErr *APIError, body []byte) {
+	var errBody openAIErrorBody
+	if json.Unmarshal(body, &errBody) == nil && errBody.Error.Message != "" {
+		apiErr.Message = errBody.Error.Message
+		apiErr.Type = errBody.Error.Type
+		if code, ok := errBody.Error.Code.(string); ok && code != "" {
+			apiErr.Type = code
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Sagn1k/scarab/errors"
+)
+
+// CompletionRequest is a provider-neutral chat completion. System carries
+// the system prompt; Messages holds the user and assistant turns.
+type CompletionRequest struct {
+	Model       string
+	System      string
+	Messages    []Message
+	MaxTokens   int
+	Temperature float64
+	// JSON asks the provider to constrain the answer to a JSON value.
+	JSON bool
+}
+
+type CompletionResponse struct {
+	Content string
+	Model   string
+	// Truncated is set when the answer stopped at the output token limit.
+	Truncated bool
+	// Usage holds the token counts reported by the provider.
+	Usage Usage
+}
+
+// Provider is an LLM API. Implementations deal with their own request
+// shapes, authentication and error bodies.
+type Provider interface {
+	Name() string
+	DefaultModel() string
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+}
+
+// postJSON posts body as JSON to url with the provider's headers and returns
+// the response if it succeeded. The caller closes the body. Transport
+// failures are reported as errors.ErrLLMServer, and other statuses as an
+// *APIError whose type and message parseError reads from the error body.
+func postJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body interface{}, parseError func(apiErr *APIError, body []byte)) (*http.Response, error) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, errors.WithCause(errors.ErrLLMServer, "error sending request: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		errBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading response: %w", err)
+		}
+
+		apiErr := newAPIError(provider, resp, errBody)
+		parseError(apiErr, errBody)
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+// providerCase describes one provider: the endpoint and headers it must
+// use, what it must send for testRequest, and canned replies.
+type providerCase struct {
+	name     string
+	new      func(baseURL string) StreamingProvider
+	path     string
+	headers  map[string]string
+	wantBody map[string]interface{}
+	reply    string
+	stream   string
+}
+
+var testRequest = CompletionRequest{
+	Model:       "m",
+	System:      "be brief",
+	Messages:    []Message{{Role: "user", Content: "hi"}},
+	MaxTokens:   100,
+	Temperature: 0.5,
+	JSON:        true,
+}
+
+var wantCompletion = &CompletionResponse{
+	Content:   "hello there",
+	Model:     "m-1",
+	Truncated: true,
+	Usage:     Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+}
+
+var providerCases = []providerCase{
+	{
+		name: "openai",
+		new: func(baseURL string) StreamingProvider {
+			return NewOpenAIProvider(baseURL, "key", "m", http.DefaultClient)
+		},
+		path:    "/v1/chat/completions",
+		headers: map[string]string{"Authorization": "Bearer key"},
+		wantBody: map[string]interface{}{
+			"model":           "m",
+			"max_tokens":      100.0,
+			"temperature":     0.5,
+			"response_format": map[string]interface{}{"type": "json_object"},
+			"messages": []interface{}{
+				map[string]interface{}{"role": "system", "content": "be brief"},
+				map[string]interface{}{"role": "user", "content": "hi"},
+			},
+		},
+		reply: `{"model":"m-1","choices":[{"message":{"role":"assistant","content":"hello there"},"finish_reason":"length"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		stream: "data: {\"model\":\"m-1\",\"choices\":[{\"delta\":{\"content\":\"hello \"}}]}\n\n" +
+			"data: {\"model\":\"m-1\",\"choices\":[{\"delta\":{\"content\":\"there\"},\"finish_reason\":\"length\"}]}\n\n" +
+			"data: {\"model\":\"m-1\",\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n" +
+			"data: [DONE]\n\n",
+	},
+	{
+		name: "anthropic",
+		new: func(baseURL string) StreamingProvider {
+			return NewAnthropicProvider(baseURL, "key", "m", http.DefaultClient)
+		},
+		path:    "/v1/messages",
+		headers: map[string]string{"x-api-key": "key", "anthropic-version": anthropicVersion},
+		wantBody: map[string]interface{}{
+			"model":       "m",
+			"system":      "be brief\n\nRespond with a single JSON value and nothing else.",
+			"max_tokens":  100.0,
+			"temperature": 0.5,
+			"messages": []interface{}{
+				map[string]interface{}{"role": "user", "content": "hi"},
+			},
+		},
+		reply: `{"model":"m-1","content":[{"type":"text","text":"hello "},{"type":"tool_use"},{"type":"text","text":"there"}],"stop_reason":"max_tokens","usage":{"input_tokens":3,"output_tokens":2}}`,
+		stream: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"m-1\",\"usage\":{\"input_tokens\":3}}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"hello \"}}\n\n" +
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"there\"}}\n\n" +
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+	},
+	{
+		name: "ollama",
+		new: func(baseURL string) StreamingProvider {
+			return NewOllamaProvider(baseURL, "m", http.DefaultClient)
+		},
+		path: "/api/chat",
+		wantBody: map[string]interface{}{
+			"model":   "m",
+			"stream":  false,
+			"format":  "json",
+			"options": map[string]interface{}{"temperature": 0.5, "num_predict": 100.0},
+			"messages": []interface{}{
+				map[string]interface{}{"role": "system", "content": "be brief"},
+				map[string]interface{}{"role": "user", "content": "hi"},
+			},
+		},
+		reply: `{"model":"m-1","message":{"role":"assistant","content":"hello there"},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}`,
+		stream: `{"model":"m-1","message":{"content":"hello "},"done":false}` + "\n" +
+			`{"model":"m-1","message":{"content":"there"},"done":false}` + "\n" +
+			`{"model":"m-1","message":{"content":""},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}` + "\n",
+	},
+}
+
+func (tc providerCase) server(t *testing.T, stream bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tc.path {
+			t.Errorf("path = %s, want %s", r.URL.Path, tc.path)
+		}
+		for name, want := range tc.headers {
+			if got := r.Header.Get(name); got != want {
+				t.Errorf("header %s = %q, want %q", name, got, want)
+			}
+		}
+
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("request body is not JSON: %s", data)
+		}
+		for key, want := range tc.wantBody {
+			got := body[key]
+			if key == "stream" && stream {
+				want = true
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("request %s = %#v, want %#v", key, got, want)
+			}
+		}
+
+		if stream {
+			_, _ = io.WriteString(w, tc.stream)
+			return
+		}
+		_, _ = io.WriteString(w, tc.reply)
+	}))
+}
+
+func TestProviderComplete(t *testing.T) {
+	for _, tc := range providerCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := tc.server(t, false)
+			defer server.Close()
+
+			got, err := tc.new(server.URL).Complete(context.Background(), testRequest)
+			if err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, wantCompletion) {
+				t.Errorf("Complete() = %+v, want %+v", got, wantCompletion)
+			}
+		})
+	}
+}
+
+func TestProviderCompleteStream(t *testing.T) {
+	for _, tc := range providerCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := tc.server(t, true)
+			defer server.Close()
+
+			var deltas []string
+			got, err := tc.new(server.URL).CompleteStream(context.Background(), testRequest, func(delta string) {
+				deltas = append(deltas, delta)
+			})
+			if err != nil {
+				t.Fatalf("CompleteStream() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, wantCompletion) {
+				t.Errorf("CompleteStream() = %+v, want %+v", got, wantCompletion)
+			}
+			if strings.Join(deltas, "|") != "hello |there" {
+				t.Errorf("deltas = %q", deltas)
+			}
+		})
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		provider string
+		status   int
+		body     string
+		want     string
+	}{
+		{"openai", 400, `{"error":{"message":"bad model","type":"invalid_request_error","code":"model_not_found"}}`, "openai API returned status 400 (model_not_found): bad model"},
+		{"openai", 502, `upstream down`, "openai API returned status 502: upstream down"},
+		{"anthropic", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "anthropic API returned status 529 (overloaded_error): Overloaded"},
+		{"ollama", 404, `{"error":"model \"m\" not found"}`, `ollama API returned status 404: model "m" not found`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			var provider Provider
+			for _, tc := range providerCases {
+				if tc.name == tt.provider {
+					provider = tc.new(server.URL)
+				}
+			}
+
+			_, err := provider.Complete(context.Background(), testRequest)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Complete() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientProviders(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		ask        string
+		want       string
+		wantErr    bool
+	}{
+		{"configured provider", "anthropic", "", "anthropic", false},
+		{"unknown configured provider falls back to openai", "nope", "", "openai", false},
+		{"asked for provider", "openai", "ollama", "ollama", false},
+		{"unknown asked for provider", "openai", "nope", "", true},
+		{"fake provider is not built in", "fake", "fake", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&config.Config{LLMProvider: tt.configured})
+			client, err := client.WithProvider(tt.ask, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && client.Provider() != tt.want {
+				t.Errorf("Provider() = %s, want %s", client.Provider(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterProviderReplacesCurrent(t *testing.T) {
+	client := NewClient(&config.Config{LLMProvider: "fake"})
+	fake := NewFakeProvider("replaced")
+	client.RegisterProvider(fake)
+
+	response, err := client.call(context.Background(), CompletionRequest{}, nil)
+	if err != nil || response.Content != "replaced" {
+		t.Errorf("call() = %v, %v, want the registered provider's answer", response, err)
+	}
+	if len(fake.Requests()) != 1 {
+		t.Errorf("registered provider got %d calls, want 1", len(fake.Requests()))
+	}
+}
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/errors"
+)
+
+// call sends req to the client's provider through its circuit breaker,
+// retrying rate-limited and server failures with exponential backoff. With
+// onDelta set the answer is streamed if the provider supports it; a stream
+// that fails after delivering text is not retried, since the text cannot be
+// taken back.
+func (c *Client) call(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error) {
+	breaker := c.breakers.get(c.provider.Name())
+	streamer, canStream := c.provider.(StreamingProvider)
+
+	delivered := false
+	for attempt := 0; ; attempt++ {
+		if !breaker.allow() {
+			return nil, errors.WithCause(errors.ErrLLMCircuitOpen, "%s", c.provider.Name())
+		}
+
+		var response *CompletionResponse
+		var err error
+		switch {
+		case onDelta != nil && canStream:
+			response, err = streamer.CompleteStream(ctx, req, func(delta string) {
+				delivered = true
+				onDelta(delta)
+			})
+		case onDelta != nil:
+			if response, err = c.provider.Complete(ctx, req); err == nil {
+				onDelta(response.Content)
+			}
+		default:
+			response, err = c.provider.Complete(ctx, req)
+		}
+		if err == nil {
+			breaker.success()
+			c.recordUsage(ctx, req.Model, response)
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			breaker.abort()
+			return nil, ctx.Err()
+		}
+
+		// Context-length and other request errors say nothing about the
+		// provider's health.
+		if retryable(err) || errors.IsType(err, errors.ErrLLMAuth) {
+			breaker.failure()
+		} else {
+			breaker.success()
+		}
+
+		if !retryable(err) || delivered || attempt >= c.config.LLMMaxRetries {
+			return nil, apiFailure(err)
+		}
+
+		if err := sleep(ctx, c.backoff(attempt, err)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// recordUsage prices a successful call and reports it to the recorders
+// attached to ctx.
+func (c *Client) recordUsage(ctx context.Context, model string, response *CompletionResponse) {
+	if response.Model != "" {
+		model = response.Model
+	}
+
+	usage := response.Usage
+	usage.Calls = 1
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	usage.CostUSD = estimateCost(c.config.LLMPrices, model, usage)
+	response.Usage = usage
+
+	recordUsage(ctx, model, usage)
+}
+
+// backoff returns the delay before retry attempt+1: full jitter over an
+// exponentially growing window, but never less than the provider's
+// Retry-After, and never more than LLMRetryMaxMS.
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	base := time.Duration(c.config.LLMRetryBaseMS) * time.Millisecond
+	max := time.Duration(c.config.LLMRetryMaxMS) * time.Millisecond
+
+	window := base << uint(attempt)
+	if window <= 0 || (max > 0 && window > max) {
+		window = max
+	}
+	var delay time.Duration
+	if window > 0 {
+		delay = window/2 + time.Duration(rand.Int63n(int64(window/2)+1))
+	}
+
+	var apiErr *APIError
+	if errors.AsType(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// breaker is a per-provider circuit breaker. After threshold consecutive
+// failures it opens and rejects calls until cooldown has passed, then lets
+// one trial call through: success closes it, failure opens it again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// abort gives up a trial call that ended without a verdict, e.g. because
+// the caller went away.
+func (b *breaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+	b.trial = false
+}
+
+// breakers holds one breaker per provider, shared by every Client derived
+// from the same NewClient call.
+type breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu     sync.Mutex
+	byName map[string]*breaker
+}
+
+func newBreakers(threshold int, cooldown time.Duration) *breakers {
+	return &breakers{threshold: threshold, cooldown: cooldown, byName: map[string]*breaker{}}
+}
+
+func (b *breakers) get(name string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.byName[name]
+	if !ok {
+		br = &breaker{threshold: b.threshold, cooldown: b.cooldown}
+		b.byName[name] = br
+	}
+	return br
+}
@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+func statusError(status int, retryAfter time.Duration) error {
+	return &APIError{Provider: "fake", StatusCode: status, Message: "failed", RetryAfter: retryAfter}
+}
+
+func TestCallRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		retries   int
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", nil, 3, 1, nil},
+		{"rate limited then success", []error{statusError(429, 0)}, 3, 2, nil},
+		{"server errors then success", []error{statusError(500, 0), statusError(503, 0)}, 3, 3, nil},
+		{"gives up after the retries", []error{statusError(502, 0), statusError(502, 0), statusError(502, 0)}, 2, 3, errors.ErrLLMServer},
+		{"no retries", []error{statusError(429, 0)}, 0, 1, errors.ErrLLMRateLimited},
+		{"auth errors are not retried", []error{statusError(401, 0)}, 3, 1, errors.ErrLLMAuth},
+		{"context length is not retried", []error{&APIError{Provider: "fake", StatusCode: 400, Message: "maximum context length exceeded"}}, 3, 1, errors.ErrLLMContextLength},
+		{"unclassified errors are not retried", []error{errors.ErrTimeout}, 3, 1, errors.ErrLLMAPIFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeProvider("ok")
+			fake.FailNext(tt.errs...)
+			client := newFakeClient(&config.Config{LLMMaxRetries: tt.retries, LLMRetryBaseMS: 1, LLMRetryMaxMS: 5}, fake)
+
+			response, err := client.call(context.Background(), CompletionRequest{}, nil)
+			if calls := len(fake.Requests()); calls != tt.wantCalls {
+				t.Errorf("provider got %d calls, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil {
+				if err != nil || response.Content != "ok" {
+					t.Errorf("call() = %v, %v, want ok", response, err)
+				}
+				return
+			}
+			if !errors.IsType(err, tt.wantErr) {
+				t.Errorf("call() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallStopsWhenCancelled(t *testing.T) {
+	fake := NewFakeProvider("ok")
+	fake.FailNext(statusError(429, time.Minute))
+	client := newFakeClient(&config.Config{LLMMaxRetries: 3, LLMRetryBaseMS: 1, LLMRetryMaxMS: 60000}, fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := client.call(ctx, CompletionRequest{}, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("call() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("call() returned after %v, want it to stop waiting when cancelled", elapsed)
+	}
+	if calls := len(fake.Requests()); calls != 1 {
+		t.Errorf("provider got %d calls, want 1", calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    int
+		max     int
+		attempt int
+		err     error
+		min     time.Duration
+		want    time.Duration
+	}{
+		{"first attempt", 100, 10000, 0, statusError(500, 0), 50 * time.Millisecond, 100 * time.Millisecond},
+		{"grows exponentially", 100, 10000, 3, statusError(500, 0), 400 * time.Millisecond, 800 * time.Millisecond},
+		{"capped", 100, 1000, 10, statusError(500, 0), 500 * time.Millisecond, time.Second},
+		{"retry-after wins", 100, 10000, 0, statusError(429, 3*time.Second), 3 * time.Second, 3 * time.Second},
+		{"retry-after capped", 100, 1000, 0, statusError(429, time.Minute), time.Second, time.Second},
+		{"no delay configured", 0, 0, 2, statusError(500, 0), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&config.Config{LLMRetryBaseMS: tt.base, LLMRetryMaxMS: tt.max})
+			for i := 0; i < 20; i++ {
+				if got := client.backoff(tt.attempt, tt.err); got < tt.min || got > tt.want {
+					t.Fatalf("backoff() = %v, want between %v and %v", got, tt.min, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"2", 2 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"-1", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v, want about an hour", future, got)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	fake := NewFakeProvider()
+	client := newFakeClient(&config.Config{LLMBreakerThreshold: 2, LLMBreakerCooldownSeconds: 30}, fake)
+	breaker := client.breakers.get("fake")
+
+	call := func() error {
+		_, err := client.call(context.Background(), CompletionRequest{}, nil)
+		return err
+	}
+
+	steps := []struct {
+		name      string
+		fail      error
+		backdate  bool
+		wantErr   error
+		wantCalls int
+	}{
+		{"first failure", statusError(500, 0), false, errors.ErrLLMServer, 1},
+		{"request errors reset the count", statusError(400, 0), false, errors.ErrLLMAPIFailure, 2},
+		{"failure after the reset", statusError(500, 0), false, errors.ErrLLMServer, 3},
+		{"second failure opens", statusError(500, 0), false, errors.ErrLLMServer, 4},
+		{"open rejects without calling", nil, false, errors.ErrLLMCircuitOpen, 4},
+		{"failed trial opens again", statusError(503, 0), true, errors.ErrLLMServer, 5},
+		{"open again", nil, false, errors.ErrLLMCircuitOpen, 5},
+		{"successful trial closes", nil, true, nil, 6},
+		{"closed", nil, false, nil, 7},
+	}
+
+	for _, step := range steps {
+		if step.fail != nil {
+			fake.FailNext(step.fail)
+		}
+		if step.backdate {
+			breaker.mu.Lock()
+			breaker.openedAt = breaker.openedAt.Add(-time.Minute)
+			breaker.mu.Unlock()
+		}
+
+		err := call()
+		switch {
+		case step.wantErr == nil && err != nil:
+			t.Errorf("%s: call() error = %v", step.name, err)
+		case step.wantErr != nil && !errors.IsType(err, step.wantErr):
+			t.Errorf("%s: call() error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if calls := len(fake.Requests()); calls != step.wantCalls {
+			t.Errorf("%s: provider got %d calls, want %d", step.name, calls, step.wantCalls)
+		}
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := &breaker{threshold: 0}
+	for i := 0; i < 10; i++ {
+		b.failure()
+		if !b.allow() {
+			t.Fatalf("disabled breaker rejected a call after %d failures", i+1)
+		}
+	}
+}
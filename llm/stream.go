@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Sagn1k/scarab/errors"
+)
+
+// DeltaFunc receives the text of a streamed answer as it arrives.
+type DeltaFunc func(delta string)
+
+// StreamingProvider is implemented by providers that can stream their
+// answers. Client falls back to Complete for providers that do not.
+type StreamingProvider interface {
+	Provider
+	CompleteStream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error)
+}
+
+type deltaKey struct{}
+
+// WithDeltas returns a context whose markdown conversions stream their
+// output to fn. Chunks are then converted sequentially so the deltas arrive
+// in document order.
+func WithDeltas(ctx context.Context, fn DeltaFunc) context.Context {
+	return context.WithValue(ctx, deltaKey{}, fn)
+}
+
+// Deltas returns the function passed to WithDeltas, or nil.
+func Deltas(ctx context.Context) DeltaFunc {
+	fn, _ := ctx.Value(deltaKey{}).(DeltaFunc)
+	return fn
+}
+
+// errStreamDone ends readSSE early without an error.
+var errStreamDone = fmt.Errorf("stream done")
+
+// readSSE calls fn for every event in a server-sent event stream until the
+// stream ends or fn returns an error. Returning errStreamDone stops cleanly.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if err == errStreamDone {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment, used as a keep-alive.
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := dispatch(); err != nil && err != errStreamDone {
+		return err
+	}
+	return nil
+}
+
+// streamError turns a failure while reading a stream into a retryable
+// server error, unless the caller gave up.
+func streamError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.IsType(err, errors.ErrLLMAPIFailure) {
+		return err
+	}
+	return errors.WithCause(errors.ErrLLMServer, "stream interrupted: %v", err)
+}
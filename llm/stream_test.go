@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+func TestReadSSE(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		stop    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "events and data",
+			stream: "event: a\ndata: 1\n\ndata: 2\n\n",
+			want:   []string{"a=1", "=2"},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: one\ndata:two\n\n",
+			want:   []string{"=one\ntwo"},
+		},
+		{
+			name:   "comments and empty events are skipped",
+			stream: ": keep-alive\n\nevent: ping\n\ndata: x\n\n",
+			want:   []string{"=x"},
+		},
+		{
+			name:   "last event without a blank line",
+			stream: "data: 1\n\ndata: 2",
+			want:   []string{"=1", "=2"},
+		},
+		{
+			name:   "stops at done",
+			stream: "data: 1\n\ndata: [DONE]\n\ndata: 3\n\n",
+			stop:   "[DONE]",
+			want:   []string{"=1"},
+		},
+		{
+			name:    "callback error",
+			stream:  "data: bad\n\n",
+			stop:    "bad",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readSSE(strings.NewReader(tt.stream), func(event, data string) error {
+				if data == tt.stop {
+					if tt.wantErr {
+						return fmt.Errorf("bad event")
+					}
+					return errStreamDone
+				}
+				got = append(got, event+"="+data)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readSSE() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{"interrupted stream is retryable", context.Background(), io.ErrUnexpectedEOF, errors.ErrLLMServer},
+		{"classified errors are kept", context.Background(), statusError(429, 0), errors.ErrLLMRateLimited},
+		{"cancellation wins", cancelled, io.ErrUnexpectedEOF, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamError(tt.ctx, tt.err); !errors.IsType(got, tt.want) {
+				t.Errorf("streamError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// brokenStream delivers part of an answer and then fails.
+type brokenStream struct {
+	*FakeProvider
+	calls int
+}
+
+func (p *brokenStream) CompleteStream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*CompletionResponse, error) {
+	p.calls++
+	onDelta("partial")
+	return nil, streamError(ctx, io.ErrUnexpectedEOF)
+}
+
+func TestCallStreaming(t *testing.T) {
+	t.Run("deltas add up to the answer", func(t *testing.T) {
+		answer := strings.Repeat("streamed ", 20)
+		client := newFakeClient(&config.Config{}, NewFakeProvider(answer))
+
+		var deltas []string
+		response, err := client.call(context.Background(), CompletionRequest{}, func(delta string) {
+			deltas = append(deltas, delta)
+		})
+		if err != nil {
+			t.Fatalf("call() error = %v", err)
+		}
+		if len(deltas) < 2 || strings.Join(deltas, "") != answer || response.Content != answer {
+			t.Errorf("deltas = %q, response = %q", deltas, response.Content)
+		}
+	})
+
+	t.Run("failed streams are not retried once text was delivered", func(t *testing.T) {
+		broken := &brokenStream{FakeProvider: NewFakeProvider()}
+		client := newFakeClient(&config.Config{LLMMaxRetries: 3, LLMRetryBaseMS: 1}, NewFakeProvider())
+		client.RegisterProvider(broken)
+
+		_, err := client.call(context.Background(), CompletionRequest{}, func(string) {})
+		if !errors.IsType(err, errors.ErrLLMServer) {
+			t.Errorf("call() error = %v, want %v", err, errors.ErrLLMServer)
+		}
+		if broken.calls != 1 {
+			t.Errorf("stream was tried %d times, want 1", broken.calls)
+		}
+	})
+}
+
+func TestHTMLToMarkdownStreamsDeltas(t *testing.T) {
+	fake := NewFakeProvider("# One", "# Two")
+	client := newFakeClient(&config.Config{LLMChunkChars: 40}, fake)
+
+	var streamed strings.Builder
+	ctx := WithDeltas(context.Background(), func(delta string) {
+		streamed.WriteString(delta)
+	})
+	page := "<html><body><p>" + strings.Repeat("a", 30) + "</p><p>" + strings.Repeat("b", 30) + "</p></body></html>"
+
+	conversion, err := client.HTMLToMarkdown(ctx, page, "https://example.com")
+	if err != nil {
+		t.Fatalf("HTMLToMarkdown() error = %v", err)
+	}
+	if conversion.Chunks != 2 {
+		t.Errorf("Chunks = %d, want 2", conversion.Chunks)
+	}
+	if streamed.String() != conversion.Markdown {
+		t.Errorf("streamed %q, but the markdown is %q", streamed.String(), conversion.Markdown)
+	}
+}
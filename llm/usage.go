@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+// Usage counts LLM calls, their tokens and the estimated cost in US dollars.
+type Usage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+func (u *Usage) Add(other Usage) {
+	u.Calls += other.Calls
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.CostUSD += other.CostUSD
+}
+
+// UsageRecorder receives the usage of every LLM call made with a context
+// passed to WithUsageRecorder.
+type UsageRecorder interface {
+	RecordUsage(model string, usage Usage)
+}
+
+type usageKey struct{}
+
+// WithUsageRecorder returns a context whose LLM calls are reported to r, in
+// addition to any recorders already attached to ctx.
+func WithUsageRecorder(ctx context.Context, r UsageRecorder) context.Context {
+	existing, _ := ctx.Value(usageKey{}).([]UsageRecorder)
+	recorders := append(append([]UsageRecorder(nil), existing...), r)
+	return context.WithValue(ctx, usageKey{}, recorders)
+}
+
+// UsageRecorders returns the recorders attached to ctx, so work that outlives
+// a request can carry them over to its own context.
+func UsageRecorders(ctx context.Context) []UsageRecorder {
+	recorders, _ := ctx.Value(usageKey{}).([]UsageRecorder)
+	return recorders
+}
+
+func recordUsage(ctx context.Context, model string, usage Usage) {
+	for _, r := range UsageRecorders(ctx) {
+		r.RecordUsage(model, usage)
+	}
+}
+
+// Meter adds up the usage recorded to it.
+type Meter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+func (m *Meter) RecordUsage(model string, usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.usage.Add(usage)
+}
+
+func (m *Meter) Usage() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.usage
+}
+
+// estimateCost prices usage with the configured per-million-token rates.
+// Dated model names fall back to the longest configured prefix, so
+// "gpt-4o-2024-08-06" is priced as "gpt-4o". Unknown models cost nothing.
+func estimateCost(prices map[string]config.ModelPrice, model string, usage Usage) float64 {
+	price, ok := prices[model]
+	if !ok {
+		best := ""
+		for name, p := range prices {
+			if strings.HasPrefix(model, name) && len(name) > len(best) {
+				best, price = name, p
+			}
+		}
+		if best == "" {
+			return 0
+		}
+	}
+
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+func TestEstimateCost(t *testing.T) {
+	prices := map[string]config.ModelPrice{
+		"gpt-4o":      {Input: 2.5, Output: 10},
+		"gpt-4o-mini": {Input: 0.15, Output: 0.6},
+	}
+	usage := Usage{PromptTokens: 1000000, CompletionTokens: 500000}
+
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"gpt-4o", 7.5},
+		{"gpt-4o-mini", 0.45},
+		{"gpt-4o-2024-08-06", 7.5},
+		{"gpt-4o-mini-2024-07-18", 0.45},
+		{"claude", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := estimateCost(prices, tt.model, usage); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("estimateCost(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+type modelRecorder struct {
+	models []string
+	Meter
+}
+
+func (r *modelRecorder) RecordUsage(model string, usage Usage) {
+	r.models = append(r.models, model)
+	r.Meter.RecordUsage(model, usage)
+}
+
+func TestCallRecordsUsage(t *testing.T) {
+	fake := NewFakeProvider("12345678", "1234")
+	fake.FailNext(statusError(500, 0))
+	cfg := &config.Config{
+		LLMMaxRetries:  1,
+		LLMRetryBaseMS: 1,
+		LLMPrices:      map[string]config.ModelPrice{"m": {Input: 1e6, Output: 2e6}},
+	}
+	client := newFakeClient(cfg, fake)
+
+	outer := &Meter{}
+	inner := &modelRecorder{}
+	ctx := WithUsageRecorder(context.Background(), outer)
+	ctx = WithUsageRecorder(ctx, inner)
+
+	// 16 characters of prompt and 8 of answer are 4 and 2 tokens to the fake.
+	req := CompletionRequest{Model: "m", System: "0123456789abcdef"}
+	if _, err := client.call(ctx, req, nil); err != nil {
+		t.Fatalf("call() error = %v", err)
+	}
+	// Only the outer recorder sees a call made without the inner one.
+	if _, err := client.call(WithUsageRecorder(context.Background(), outer), req, nil); err != nil {
+		t.Fatalf("call() error = %v", err)
+	}
+
+	want := Usage{Calls: 1, PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6, CostUSD: 8}
+	if got := inner.Usage(); got != want {
+		t.Errorf("inner usage = %+v, want %+v", got, want)
+	}
+	if len(inner.models) != 1 || inner.models[0] != "m" {
+		t.Errorf("inner models = %v, want [m]", inner.models)
+	}
+
+	want.Add(Usage{Calls: 1, PromptTokens: 4, CompletionTokens: 1, TotalTokens: 5, CostUSD: 6})
+	if got := outer.Usage(); got != want {
+		t.Errorf("outer usage = %+v, want %+v", got, want)
+	}
+}
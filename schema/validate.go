@@ -0,0 +1,376 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError describes one place where a value does not match its
+// schema. Path is a JSON Pointer into the value.
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate checks value, as decoded by encoding/json, against a JSON Schema.
+// It covers the keywords that matter for extraction: type, properties,
+// required, additionalProperties, items, enum, const, the numeric, string
+// and array bounds, pattern, format and the allOf/anyOf/oneOf combinators.
+func Validate(schema map[string]interface{}, value interface{}) []ValidationError {
+	v := &validator{}
+	v.validate("", schema, value)
+	return v.errors
+}
+
+// Check reports whether schema is itself usable, catching the mistakes that
+// would otherwise only surface while validating.
+func Check(schema map[string]interface{}) error {
+	if len(schema) == 0 {
+		return fmt.Errorf("schema is empty")
+	}
+
+	var check func(path string, s map[string]interface{}) error
+	check = func(path string, s map[string]interface{}) error {
+		if pattern, ok := s["pattern"].(string); ok {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("%s/pattern: %w", path, err)
+			}
+		}
+		if props, ok := s["properties"].(map[string]interface{}); ok {
+			for name, prop := range props {
+				sub, ok := prop.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s/properties/%s: must be an object", path, name)
+				}
+				if err := check(path+"/properties/"+name, sub); err != nil {
+					return err
+				}
+			}
+		}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			if err := check(path+"/items", items); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return check("#", schema)
+}
+
+// DropNulls removes null values of optional properties that the schema does
+// not allow to be null, treating them as missing, in objects and arrays at
+// any depth. Models often answer null for a value they could not find.
+func DropNulls(schema map[string]interface{}, value interface{}) {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		required := map[string]bool{}
+		if names, ok := schema["required"].([]interface{}); ok {
+			for _, name := range names {
+				if key, ok := name.(string); ok {
+					required[key] = true
+				}
+			}
+		}
+		for key, item := range val {
+			propSchema, _ := props[key].(map[string]interface{})
+			if item == nil {
+				if propSchema != nil && !required[key] && len(Validate(propSchema, nil)) > 0 {
+					delete(val, key)
+				}
+				continue
+			}
+			DropNulls(propSchema, item)
+		}
+	case []interface{}:
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for _, item := range val {
+				DropNulls(itemSchema, item)
+			}
+		}
+	}
+}
+
+type validator struct {
+	errors []ValidationError
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "/"
+	}
+	v.errors = append(v.errors, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(path string, schema map[string]interface{}, value interface{}) {
+	if schema == nil {
+		return
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), typeName(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if equal(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", compact(enum))
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !equal(constant, value) {
+		v.fail(path, "must equal %s", compact(constant))
+	}
+
+	switch val := value.(type) {
+	case float64:
+		v.validateNumber(path, schema, val)
+	case string:
+		v.validateString(path, schema, val)
+	case []interface{}:
+		v.validateArray(path, schema, val)
+	case map[string]interface{}:
+		v.validateObject(path, schema, val)
+	}
+
+	v.validateCombinators(path, schema, value)
+}
+
+func (v *validator) validateNumber(path string, schema map[string]interface{}, n float64) {
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		v.fail(path, "must be >= %v", min)
+	}
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		v.fail(path, "must be <= %v", max)
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		v.fail(path, "must be > %v", min)
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		v.fail(path, "must be < %v", max)
+	}
+}
+
+func (v *validator) validateString(path string, schema map[string]interface{}, s string) {
+	length := len([]rune(s))
+	if min, ok := schema["minLength"].(float64); ok && float64(length) < min {
+		v.fail(path, "must be at least %v characters", min)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && float64(length) > max {
+		v.fail(path, "must be at most %v characters", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok && !validFormat(format, s) {
+		v.fail(path, "must be a valid %s", format)
+	}
+}
+
+func (v *validator) validateArray(path string, schema map[string]interface{}, items []interface{}) {
+	if min, ok := schema["minItems"].(float64); ok && float64(len(items)) < min {
+		v.fail(path, "must have at least %v items", min)
+	}
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(items)) > max {
+		v.fail(path, "must have at most %v items", max)
+	}
+	if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range items {
+			v.validate(fmt.Sprintf("%s/%d", path, i), itemSchema, item)
+		}
+	}
+}
+
+func (v *validator) validateObject(path string, schema map[string]interface{}, obj map[string]interface{}) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := obj[key]; !present {
+				v.fail(path+"/"+key, "is required")
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propSchema, ok := props[key].(map[string]interface{}); ok {
+			v.validate(path+"/"+key, propSchema, obj[key])
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(path+"/"+key, "is not allowed")
+			}
+		case map[string]interface{}:
+			v.validate(path+"/"+key, additional, obj[key])
+		}
+	}
+}
+
+func (v *validator) validateCombinators(path string, schema map[string]interface{}, value interface{}) {
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if s, ok := sub.(map[string]interface{}); ok {
+				v.validate(path, s, value)
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if matching(anyOf, value) == 0 {
+			v.fail(path, "must match at least one schema in anyOf")
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if n := matching(oneOf, value); n != 1 {
+			v.fail(path, "must match exactly one schema in oneOf, matched %d", n)
+		}
+	}
+}
+
+func matching(schemas []interface{}, value interface{}) int {
+	n := 0
+	for _, sub := range schemas {
+		if s, ok := sub.(map[string]interface{}); ok && len(Validate(s, value)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func schemaTypes(t interface{}) []string {
+	switch val := t.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		var types []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		if !datePattern.MatchString(s) {
+			return false
+		}
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "email":
+		_, err := mail.ParseAddress(s)
+		return err == nil
+	case "uri", "url":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}
+	// Unknown formats are annotations only.
+	return true
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func compact(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
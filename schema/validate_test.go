@@ -0,0 +1,172 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{"type matches", `{"type":"string"}`, `"a"`, nil},
+		{"type mismatch", `{"type":"string"}`, `1`, []string{"/: expected string, got number"}},
+		{"type list", `{"type":["string","null"]}`, `null`, nil},
+		{"integer", `{"type":"integer"}`, `1.5`, []string{"/: expected integer, got number"}},
+		{"enum", `{"enum":["a","b"]}`, `"c"`, []string{`/: must be one of ["a","b"]`}},
+		{"const", `{"const":3}`, `4`, []string{"/: must equal 3"}},
+		{"minimum", `{"minimum":1}`, `0`, []string{"/: must be >= 1"}},
+		{"exclusive maximum", `{"exclusiveMaximum":5}`, `5`, []string{"/: must be < 5"}},
+		{"max length counts runes", `{"maxLength":2}`, `"éé"`, nil},
+		{"min length", `{"minLength":3}`, `"ab"`, []string{"/: must be at least 3 characters"}},
+		{"pattern", `{"pattern":"^[0-9]+$"}`, `"12a"`, []string{`/: must match pattern "^[0-9]+$"`}},
+		{"date format", `{"format":"date"}`, `"2024-02-30"`, []string{"/: must be a valid date"}},
+		{"date-time format", `{"format":"date-time"}`, `"2024-02-03T04:05:06Z"`, nil},
+		{"email format", `{"format":"email"}`, `"nobody"`, []string{"/: must be a valid email"}},
+		{"uri format", `{"format":"uri"}`, `"/relative"`, []string{"/: must be a valid uri"}},
+		{"unknown format", `{"format":"color"}`, `"red"`, nil},
+		{"array bounds", `{"minItems":2}`, `[1]`, []string{"/: must have at least 2 items"}},
+		{
+			"array items",
+			`{"items":{"type":"number"}}`,
+			`[1,"x",2,true]`,
+			[]string{"/1: expected number, got string", "/3: expected number, got boolean"},
+		},
+		{
+			"required and nested",
+			`{"type":"object","required":["name"],"properties":{"price":{"type":"object","properties":{"amount":{"type":"number"}}}}}`,
+			`{"price":{"amount":"12"}}`,
+			[]string{"/name: is required", "/price/amount: expected number, got string"},
+		},
+		{
+			"additional properties",
+			`{"properties":{"a":{}},"additionalProperties":false}`,
+			`{"a":1,"b":2}`,
+			[]string{"/b: is not allowed"},
+		},
+		{
+			"additional properties schema",
+			`{"additionalProperties":{"type":"string"}}`,
+			`{"a":1}`,
+			[]string{"/a: expected string, got number"},
+		},
+		{"allOf", `{"allOf":[{"minimum":1},{"maximum":3}]}`, `4`, []string{"/: must be <= 3"}},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"type":"null"}]}`, `1`, []string{"/: must match at least one schema in anyOf"}},
+		{"oneOf", `{"oneOf":[{"type":"number"},{"minimum":0}]}`, `1`, []string{"/: must match exactly one schema in oneOf, matched 2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := decode(t, tt.schema).(map[string]interface{})
+			var got []string
+			for _, err := range Validate(schema, decode(t, tt.value)) {
+				got = append(got, err.Error())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  map[string]interface{}
+		wantErr string
+	}{
+		{"empty", map[string]interface{}{}, "schema is empty"},
+		{"valid", map[string]interface{}{"type": "object"}, ""},
+		{
+			"bad nested pattern",
+			map[string]interface{}{"properties": map[string]interface{}{"a": map[string]interface{}{"pattern": "("}}},
+			"#/properties/a/pattern: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			"property not an object",
+			map[string]interface{}{"properties": map[string]interface{}{"a": "string"}},
+			"#/properties/a: must be an object",
+		},
+		{
+			"bad items pattern",
+			map[string]interface{}{"items": map[string]interface{}{"pattern": "["}},
+			"#/items/pattern: error parsing regexp: missing closing ]: `[`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.schema)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("Check() = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDropNulls(t *testing.T) {
+	schema := decode(t, `{
+		"type": "object",
+		"required": ["name", "note"],
+		"properties": {
+			"name": {"type": "string"},
+			"note": {"type": "string"},
+			"price": {"type": "number"},
+			"sku": {"type": ["string", "null"]},
+			"variants": {
+				"type": "array",
+				"items": {"type": "object", "properties": {"color": {"type": "string"}}}
+			}
+		}
+	}`).(map[string]interface{})
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"optional null dropped", `{"name":"a","note":"b","price":null}`, `{"name":"a","note":"b"}`},
+		{"nullable kept", `{"name":"a","note":"b","sku":null}`, `{"name":"a","note":"b","sku":null}`},
+		{"required kept", `{"name":"a","note":null}`, `{"name":"a","note":null}`},
+		{"unknown kept", `{"name":"a","note":"b","extra":null}`, `{"extra":null,"name":"a","note":"b"}`},
+		{"inside arrays", `{"name":"a","note":"b","variants":[{"color":null},{"color":"red"}]}`, `{"name":"a","note":"b","variants":[{},{"color":"red"}]}`},
+		{"not an object", `[null]`, `[null]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := decode(t, tt.value)
+			DropNulls(schema, value)
+			got, _ := json.Marshal(value)
+			if string(got) != string(mustCompact(t, tt.want)) {
+				t.Errorf("DropNulls() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustCompact(t *testing.T, data string) []byte {
+	t.Helper()
+	out, err := json.Marshal(decode(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
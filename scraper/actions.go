@@ -0,0 +1,287 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sagn1k/scarab/errors"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Action types for the "actions" param.
+const (
+	ActionClick  = "click"
+	ActionType   = "type"
+	ActionPress  = "press"
+	ActionScroll = "scroll"
+	ActionWait   = "wait"
+	ActionSelect = "select"
+	ActionHover  = "hover"
+	ActionEval   = "eval"
+)
+
+var actionTypes = []string{ActionClick, ActionType, ActionPress, ActionScroll, ActionWait, ActionSelect, ActionHover, ActionEval}
+
+const (
+	maxActions           = 50
+	maxActionTimeout     = 60000
+	defaultActionTimeout = 10 * time.Second
+)
+
+// Action is one step run on the page after it loads and before its HTML is
+// read. Which fields apply depends on Type:
+//
+//   - click, hover: Selector
+//   - type: Selector and Text
+//   - press: Key, after focusing Selector if set
+//   - scroll: Selector into view, or to the bottom of the page without one
+//   - wait: one of Selector, NetworkIdle or Milliseconds
+//   - select: Selector of a <select> and the Value or text of an option
+//   - eval: Script, whose last expression is returned
+type Action struct {
+	Type     string `json:"type"`
+	Selector string `json:"selector,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Script   string `json:"script,omitempty"`
+	// NetworkIdle waits until no requests have been made for 500ms.
+	NetworkIdle  bool `json:"networkIdle,omitempty"`
+	Milliseconds int  `json:"milliseconds,omitempty"`
+	// Timeout bounds the action in milliseconds, 10 seconds by default.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// ActionResult reports how one action went. Failed actions do not stop
+// the ones after them.
+type ActionResult struct {
+	Index   int    `json:"index"`
+	Type    string `json:"type"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Value is what an eval action's script returned.
+	Value      interface{} `json:"value,omitempty"`
+	DurationMS int64       `json:"durationMs"`
+}
+
+// namedKeys are the keys a press action can name besides single
+// characters.
+var namedKeys = map[string]input.Key{
+	"Enter":      input.Enter,
+	"Tab":        input.Tab,
+	"Escape":     input.Escape,
+	"Backspace":  input.Backspace,
+	"Delete":     input.Delete,
+	"Space":      input.Space,
+	"ArrowUp":    input.ArrowUp,
+	"ArrowDown":  input.ArrowDown,
+	"ArrowLeft":  input.ArrowLeft,
+	"ArrowRight": input.ArrowRight,
+	"Home":       input.Home,
+	"End":        input.End,
+	"PageUp":     input.PageUp,
+	"PageDown":   input.PageDown,
+}
+
+func lookupKey(name string) (input.Key, bool) {
+	if key, ok := namedKeys[name]; ok {
+		return key, true
+	}
+	if len(name) == 1 && name[0] >= ' ' && name[0] <= '~' {
+		return input.Key(name[0]), true
+	}
+	return 0, false
+}
+
+// replayable reports whether actions only wait, scroll or hover. A page
+// cached after such actions, with their results, stands in for running them
+// again; clicks, typing and scripts must run on every request.
+func replayable(actions []Action) bool {
+	for _, action := range actions {
+		switch action.Type {
+		case ActionWait, ActionScroll, ActionHover:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validateActions(invalid *OptionsError, actions []Action) {
+	if len(actions) > maxActions {
+		invalid.add("actions", "must have at most %d entries", maxActions)
+		return
+	}
+
+	for i, action := range actions {
+		field := func(name string) string {
+			return fmt.Sprintf("actions[%d].%s", i, name)
+		}
+		require := func(name, value string) {
+			if strings.TrimSpace(value) == "" {
+				invalid.add(field(name), "is required for %s", action.Type)
+			}
+		}
+
+		switch action.Type {
+		case ActionClick, ActionHover:
+			require("selector", action.Selector)
+		case ActionType:
+			require("selector", action.Selector)
+			require("text", action.Text)
+		case ActionPress:
+			if _, ok := lookupKey(action.Key); !ok {
+				invalid.add(field("key"), "must be a single character or one of %s", strings.Join(keyNames(), ", "))
+			}
+		case ActionScroll:
+		case ActionWait:
+			set := 0
+			if action.Selector != "" {
+				set++
+			}
+			if action.NetworkIdle {
+				set++
+			}
+			if action.Milliseconds != 0 {
+				set++
+				if action.Milliseconds < 0 || action.Milliseconds > maxWaitTime {
+					invalid.add(field("milliseconds"), "must be between 1 and %d", maxWaitTime)
+				}
+			}
+			if set != 1 {
+				invalid.add(fmt.Sprintf("actions[%d]", i), "wait needs exactly one of selector, networkIdle or milliseconds")
+			}
+		case ActionSelect:
+			require("selector", action.Selector)
+			require("value", action.Value)
+		case ActionEval:
+			require("script", action.Script)
+		default:
+			invalid.add(field("type"), "must be one of %s", strings.Join(actionTypes, ", "))
+		}
+
+		if action.Timeout < 0 || action.Timeout > maxActionTimeout {
+			invalid.add(field("timeout"), "must be between 0 and %d milliseconds", maxActionTimeout)
+		}
+	}
+}
+
+func keyNames() []string {
+	return []string{"Enter", "Tab", "Escape", "Backspace", "Delete", "Space", "ArrowUp", "ArrowDown", "ArrowLeft", "ArrowRight", "Home", "End", "PageUp", "PageDown"}
+}
+
+// runActions runs the actions in order and reports each. It stops early
+// only when ctx is done.
+func runActions(ctx context.Context, page *rod.Page, actions []Action) []ActionResult {
+	results := make([]ActionResult, 0, len(actions))
+	for i, action := range actions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		timeout := defaultActionTimeout
+		if action.Timeout > 0 {
+			timeout = time.Duration(action.Timeout) * time.Millisecond
+		}
+
+		started := time.Now()
+		var value interface{}
+		err := rod.Try(func() {
+			var err error
+			value, err = runAction(page.Timeout(timeout), action)
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		result := ActionResult{
+			Index:      i,
+			Type:       action.Type,
+			Success:    err == nil,
+			Value:      value,
+			DurationMS: time.Since(started).Milliseconds(),
+		}
+		if err != nil {
+			if errors.IsType(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				err = fmt.Errorf("timed out after %v", timeout)
+			}
+			result.Error = err.Error()
+			fmt.Printf("Warning: action %d (%s) failed: %v\n", i, action.Type, err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func runAction(page *rod.Page, action Action) (interface{}, error) {
+	var el *rod.Element
+	if action.Selector != "" {
+		var err error
+		if el, err = page.Element(action.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	switch action.Type {
+	case ActionClick:
+		return nil, el.Click(proto.InputMouseButtonLeft, 1)
+
+	case ActionHover:
+		return nil, el.Hover()
+
+	case ActionType:
+		return nil, el.Input(action.Text)
+
+	case ActionPress:
+		if el != nil {
+			if err := el.Focus(); err != nil {
+				return nil, err
+			}
+		}
+		key, _ := lookupKey(action.Key)
+		return nil, page.Keyboard.Press(key)
+
+	case ActionScroll:
+		if el != nil {
+			return nil, el.ScrollIntoView()
+		}
+		_, err := page.Eval(`() => window.scrollTo(0, document.documentElement.scrollHeight)`)
+		return nil, err
+
+	case ActionWait:
+		switch {
+		case el != nil:
+			// Finding the element was the wait.
+			return nil, nil
+		case action.NetworkIdle:
+			page.WaitRequestIdle(500*time.Millisecond, nil, nil, nil)()
+			return nil, page.GetContext().Err()
+		default:
+			return nil, sleepContext(page.GetContext(), time.Duration(action.Milliseconds)*time.Millisecond)
+		}
+
+	case ActionSelect:
+		// Match the option's value first, then its text.
+		byValue := "option[value=" + strconv.Quote(action.Value) + "]"
+		if err := el.Select([]string{byValue}, true, rod.SelectorTypeCSSSector); err == nil {
+			return nil, nil
+		}
+		return nil, el.Select([]string{action.Value}, true, rod.SelectorTypeText)
+
+	case ActionEval:
+		// Indirect eval runs the snippet as a script, so its last
+		// expression is the result; promises are awaited.
+		res, err := page.Eval(`(script) => (0, eval)(script)`, action.Script)
+		if err != nil {
+			return nil, err
+		}
+		return res.Value.Val(), nil
+	}
+
+	return nil, fmt.Errorf("unknown action %q", action.Type)
+}
@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/go-rod/rod/lib/input"
+)
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   input.Key
+		wantOK bool
+	}{
+		{"Enter", input.Enter, true},
+		{"PageDown", input.PageDown, true},
+		{"a", input.Key('a'), true},
+		{"/", input.Key('/'), true},
+		{" ", input.Key(' '), true},
+		{"", 0, false},
+		{"enter", 0, false},
+		{"ab", 0, false},
+		{"é", 0, false},
+		{"\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := lookupKey(tt.name)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("lookupKey(%q) = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestKeyNamesListsNamedKeys(t *testing.T) {
+	var want []string
+	for name := range namedKeys {
+		want = append(want, name)
+	}
+	sort.Strings(want)
+
+	got := keyNames()
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("keyNames() = %v, want %v", got, want)
+	}
+}
+
+// actionRenderer reports every action as run, recording the actions it was
+// asked to run and how often it rendered.
+type actionRenderer struct {
+	actions []Action
+	renders int
+}
+
+func (r *actionRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
+	r.renders++
+	r.actions = options.Actions
+	result := &RenderResult{HTML: "<h1>Page</h1>", URL: url}
+	for i, action := range options.Actions {
+		result.Actions = append(result.Actions, ActionResult{Index: i, Type: action.Type, Success: true})
+	}
+	return result, nil
+}
+
+func TestScrapeActions(t *testing.T) {
+	tests := []struct {
+		name        string
+		actions     []Action
+		wantErr     bool
+		wantResults []ActionResult
+	}{
+		{
+			name: "no actions",
+		},
+		{
+			name: "actions are run in order",
+			actions: []Action{
+				{Type: ActionClick, Selector: "#more"},
+				{Type: ActionWait, NetworkIdle: true},
+				{Type: ActionPress, Key: "Enter"},
+			},
+			wantResults: []ActionResult{
+				{Index: 0, Type: ActionClick, Success: true},
+				{Index: 1, Type: ActionWait, Success: true},
+				{Index: 2, Type: ActionPress, Success: true},
+			},
+		},
+		{
+			name:    "invalid actions are not run",
+			actions: []Action{{Type: ActionClick, Selector: "#more"}, {Type: ActionPress, Key: "Return"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{Converter: ConverterNative})
+			renderer := &actionRenderer{}
+			s.SetRenderer(renderer)
+
+			result, err := s.Scrape(context.Background(), "https://example.com/", ScrapeOptions{Actions: tt.actions})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scrape() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if renderer.actions != nil {
+					t.Errorf("renderer ran %v", renderer.actions)
+				}
+				return
+			}
+			if !reflect.DeepEqual(renderer.actions, tt.actions) {
+				t.Errorf("renderer ran %v, want %v", renderer.actions, tt.actions)
+			}
+			if !reflect.DeepEqual(result.Actions, tt.wantResults) {
+				t.Errorf("Actions = %v, want %v", result.Actions, tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestScrapeActionsCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		actions     []Action
+		wantRenders int
+	}{
+		{"no actions are cached", nil, 1},
+		{"waits and scrolls are cached", []Action{{Type: ActionWait, Milliseconds: 10}, {Type: ActionScroll}}, 1},
+		{"clicks run every time", []Action{{Type: ActionScroll}, {Type: ActionClick, Selector: "#more"}}, 3},
+		{"scripts run every time", []Action{{Type: ActionEval, Script: "1"}}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{Converter: ConverterNative, CacheBackend: "memory"})
+			renderer := &actionRenderer{}
+			s.SetRenderer(renderer)
+
+			for i := 0; i < 3; i++ {
+				result, err := s.Scrape(context.Background(), "https://example.com/", ScrapeOptions{Actions: tt.actions})
+				if err != nil {
+					t.Fatalf("Scrape() #%d error = %v", i+1, err)
+				}
+				if tt.wantRenders > 1 && result.Cache != "" {
+					t.Errorf("Scrape() #%d Cache = %q, want a fresh render", i+1, result.Cache)
+				}
+			}
+			if renderer.renders != tt.wantRenders {
+				t.Errorf("rendered %d times, want %d", renderer.renders, tt.wantRenders)
+			}
+		})
+	}
+}
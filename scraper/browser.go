@@ -8,9 +8,9 @@ import (
 	"time"
 
 	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/input"
-	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
 )
 
@@ -18,11 +18,54 @@ type RenderOptions struct {
 	WaitTime  int
 	Selectors []string
 	BypassCF  bool
+	// Headers, UserAgent and Cookies override or add to the rotated
+	// defaults.
+	Headers   map[string]string
+	Cookies   []Cookie
+	Viewport  Viewport
+	UserAgent string
+	// Screenshot and PDF, when set, are captured once the page is ready.
+	Screenshot *ScreenshotOptions
+	PDF        *PDFOptions
+	// Actions run after the selectors are waited for.
+	Actions []Action
+	// Paginate runs after the actions. CheckURL, when set, is asked before
+	// each next-page link is followed, and may wait for the host's limits.
+	Paginate *PaginateOptions
+	CheckURL func(ctx context.Context, url string) error
+}
+
+type RenderResult struct {
+	HTML string
+	// URL is the address the page ended up on after redirects.
+	URL string
+	// Metadata is read from the rendered page, so it includes tags added
+	// by scripts.
+	Metadata *Metadata
+	// Actions reports the actions run on the page, one per action.
+	Actions []ActionResult
+	// Pagination reports what Paginate loaded. In next mode HTML holds
+	// every page while the captures show the first, taken before any
+	// next-page link is followed.
+	Pagination *PaginationResult
+	// Screenshot and PDF hold the captures asked for in RenderOptions.
+	// They are not cached.
+	Screenshot []byte `json:"-"`
+	PDF        []byte `json:"-"`
+	// FromCache is set when the page was not rendered but read from the
+	// HTML cache.
+	FromCache bool `json:"-"`
+}
+
+// Renderer loads a page and returns it as rendered. BrowserRenderer is the
+// implementation the service uses.
+type Renderer interface {
+	RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error)
 }
 
 type BrowserRenderer struct {
 	config        *config.Config
-	browser       *rod.Browser
+	pool          *BrowserPool
 	proxyRotator  *ProxyRotator
 	headerRotator *HeaderRotator
 }
@@ -30,61 +73,47 @@ type BrowserRenderer struct {
 func NewBrowserRenderer(cfg *config.Config, pr *ProxyRotator, hr *HeaderRotator) *BrowserRenderer {
 	return &BrowserRenderer{
 		config:        cfg,
+		pool:          NewBrowserPool(cfg),
 		proxyRotator:  pr,
 		headerRotator: hr,
 	}
 }
 
-func (r *BrowserRenderer) initBrowser(ctx context.Context) error {
-	if r.browser != nil {
-		return nil
-	}
-
-	l := launcher.New().
-		Headless(true).
-		Set("--window-size", "1280,720")
-
-	// Uncomment if you want to use proxies
-	// proxy := r.proxyRotator.GetRandomProxy()
-	// if proxy != "" {
-	// 	l = l.Proxy(proxy)
-	// }
-
-	browserURL := l.MustLaunch()
-
-	browser := rod.New().Context(ctx).ControlURL(browserURL)
-	err := browser.Connect()
-	if err != nil {
-		return fmt.Errorf("failed to connect to browser: %w", err)
-	}
-
-	r.browser = browser
-	return nil
-}
-
-func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (string, error) {
-	if err := r.initBrowser(ctx); err != nil {
-		return "", err
-	}
-
+func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
 	timeoutDuration := time.Duration(r.config.BrowserTimeout) * time.Second
 	if options != nil && options.BypassCF {
 		timeoutDuration = time.Duration(r.config.BrowserTimeout*2) * time.Second
 	}
-	_, cancel := context.WithTimeout(ctx, timeoutDuration)
+	if options != nil && options.Paginate != nil {
+		timeoutDuration += milliseconds(options.Paginate.TimeBudget)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeoutDuration)
 	defer cancel()
 
-	page, err := r.browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	pooled, err := r.pool.Acquire(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to create page: %w", err)
+		return nil, renderError(ctx, fmt.Errorf("failed to acquire page: %w", err))
 	}
-	defer page.Close()
+	healthy := false
+	defer func() {
+		if healthy {
+			r.pool.Release(pooled)
+		} else {
+			r.pool.Discard(pooled)
+		}
+	}()
+
+	page := pooled.Page.Context(ctx)
 
+	viewport := defaultViewport
+	if options != nil && options.Viewport.Width > 0 {
+		viewport = options.Viewport
+	}
 	_ = (&proto.EmulationSetDeviceMetricsOverride{
-		Width:             1920,
-		Height:            1080,
-		DeviceScaleFactor: 1,
-		Mobile:            false,
+		Width:             viewport.Width,
+		Height:            viewport.Height,
+		DeviceScaleFactor: viewport.DeviceScaleFactor,
+		Mobile:            viewport.Mobile,
 	}).Call(page)
 
 	_ = rod.Try(func() {
@@ -125,31 +154,52 @@ func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *R
 	})
 
 	headers := r.headerRotator.GetHeaders()
+	if options != nil {
+		for name, value := range options.Headers {
+			setHeader(headers, name, value)
+		}
+		if options.UserAgent != "" {
+			setHeader(headers, "User-Agent", options.UserAgent)
+		}
+	}
 	headerPairs := []string{}
 	for key, value := range headers {
 		headerPairs = append(headerPairs, key, value)
 	}
 	_, headerErr := page.SetExtraHeaders(headerPairs)
 	if headerErr != nil {
-		return "", fmt.Errorf("failed to set headers: %w", headerErr)
+		return nil, loadError(ctx, fmt.Errorf("failed to set headers: %w", headerErr))
 	}
 
 	_ = proto.EmulationSetUserAgentOverride{
-		UserAgent:      headers["User-Agent"],
+		UserAgent:      headerValue(headers, "User-Agent"),
 		AcceptLanguage: "en-US,en;q=0.9",
 		Platform:       "Windows",
 	}.Call(page)
 
+	if options != nil && len(options.Cookies) > 0 {
+		cookies := cookieParams(url, options.Cookies)
+		// The page has a browser context of its own, so the cookies go
+		// with it.
+		if err := page.SetCookies(cookies); err != nil {
+			return nil, loadError(ctx, fmt.Errorf("failed to set cookies: %w", err))
+		}
+	}
+
+	waitLoad := page.WaitNavigation(proto.PageLifecycleEventNameLoad)
 	err = page.Navigate(url)
 	if err != nil {
-		return "", fmt.Errorf("failed to navigate to URL: %w", err)
+		return nil, loadError(ctx, fmt.Errorf("failed to navigate to URL: %w", err))
 	}
 
-	page.WaitNavigation(proto.PageLifecycleEventNameLoad)()
+	waitLoad()
+	if ctx.Err() != nil {
+		return nil, renderError(ctx, ctx.Err())
+	}
 
 	_ = rod.Try(func() {
 		page.Mouse.Scroll(0, float64(100+rand.Intn(300)), 5)
-		time.Sleep(time.Duration(300+rand.Intn(500)) * time.Millisecond)
+		_ = sleepContext(ctx, time.Duration(300+rand.Intn(500))*time.Millisecond)
 	})
 
 	cfWaitTime := r.config.CloudflareWaitMS
@@ -159,10 +209,13 @@ func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *R
 
 	if options == nil || options.BypassCF {
 		if err := r.handleCloudflare(page, cfWaitTime); err != nil {
+			if ctx.Err() != nil {
+				return nil, renderError(ctx, err)
+			}
 			fmt.Printf("Warning: Cloudflare bypass failed: %v\n", err)
 		}
-	} else {
-		time.Sleep(time.Duration(cfWaitTime) * time.Millisecond)
+	} else if err := sleepContext(ctx, time.Duration(cfWaitTime)*time.Millisecond); err != nil {
+		return nil, renderError(ctx, err)
 	}
 
 	_ = rod.Try(func() {
@@ -174,7 +227,7 @@ func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *R
 					strings.Contains(txtLower, "continue") ||
 					strings.Contains(txtLower, "agree") {
 					btn.Click(proto.InputMouseButtonLeft, 1)
-					time.Sleep(500 * time.Millisecond)
+					_ = sleepContext(ctx, 500*time.Millisecond)
 					break
 				}
 			}
@@ -187,11 +240,36 @@ func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *R
 				page.Timeout(5 * time.Second).MustElement(selector)
 			})
 			if err != nil {
+				if ctx.Err() != nil {
+					return nil, renderError(ctx, err)
+				}
 				fmt.Printf("Warning: selector %s not found: %v\n", selector, err)
 			}
 		}
 	}
 
+	var actions []ActionResult
+	if options != nil && len(options.Actions) > 0 {
+		actions = runActions(ctx, page, options.Actions)
+		if ctx.Err() != nil {
+			return nil, renderError(ctx, ctx.Err())
+		}
+	}
+
+	var paginate *PaginateOptions
+	var pagination *PaginationResult
+	if options != nil {
+		paginate = options.Paginate
+	}
+	if paginate != nil && paginate.Mode != PaginateNext {
+		pagination = loadMore(ctx, page, paginate)
+	} else if paginate != nil {
+		_ = markPagesContainer(page, paginate.ItemSelector)
+	}
+	if ctx.Err() != nil {
+		return nil, renderError(ctx, ctx.Err())
+	}
+
 	var html string
 	var htmlErr error
 	for attempts := 0; attempts < 3; attempts++ {
@@ -199,11 +277,13 @@ func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *R
 		if htmlErr == nil {
 			break
 		}
-		time.Sleep(time.Second)
+		if err := sleepContext(ctx, time.Second); err != nil {
+			return nil, renderError(ctx, err)
+		}
 	}
 
 	if htmlErr != nil {
-		return "", fmt.Errorf("failed to get HTML after multiple attempts: %w", htmlErr)
+		return nil, loadError(ctx, fmt.Errorf("failed to get HTML after multiple attempts: %w", htmlErr))
 	}
 
 	if (strings.Contains(html, "Just a moment") || strings.Contains(html, "checking your browser")) &&
@@ -211,10 +291,37 @@ func (r *BrowserRenderer) RenderPage(ctx context.Context, url string, options *R
 		fmt.Println("Warning: Still on Cloudflare challenge page after bypass attempt")
 	}
 
-	return html, nil
+	finalURL := url
+	if info, err := page.Info(); err == nil && info.URL != "" {
+		finalURL = info.URL
+	}
+
+	// Captures are taken before following next-page links, which leave the
+	// tab on the last page, so that they show the page the result is about.
+	result := &RenderResult{URL: finalURL, Actions: actions}
+	if options != nil {
+		if err := capture(page, options, result); err != nil {
+			return nil, loadError(ctx, err)
+		}
+	}
+
+	if paginate != nil && paginate.Mode == PaginateNext {
+		html, pagination = followNext(ctx, page, paginate, html, finalURL, options.CheckURL)
+		if ctx.Err() != nil {
+			return nil, renderError(ctx, ctx.Err())
+		}
+	}
+
+	result.HTML = html
+	result.Metadata = ExtractMetadata(html, finalURL)
+	result.Pagination = pagination
+
+	healthy = true
+	return result, nil
 }
 
 func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) error {
+	ctx := page.GetContext()
 	isCloudflare := false
 
 	_ = rod.Try(func() {
@@ -237,7 +344,7 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 
 	fmt.Println("Detected Cloudflare challenge, attempting to solve...")
 
-	time.Sleep(3 * time.Second)
+	_ = sleepContext(ctx, 3*time.Second)
 
 	checkboxSelectors := []string{
 		"input[type=checkbox]",
@@ -269,12 +376,12 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 						checkbox := frameObj.MustElement(selector)
 
 						checkbox.Hover()
-						time.Sleep(time.Duration(300+rand.Intn(500)) * time.Millisecond)
+						_ = sleepContext(ctx, time.Duration(300+rand.Intn(500))*time.Millisecond)
 
 						checkbox.Click(proto.InputMouseButtonLeft, 1)
 						fmt.Println("Clicked checkbox in iframe!")
 
-						time.Sleep(time.Duration(2000+rand.Intn(1000)) * time.Millisecond)
+						_ = sleepContext(ctx, time.Duration(2000+rand.Intn(1000))*time.Millisecond)
 						iframeHandled = true
 					})
 					if err == nil {
@@ -296,12 +403,12 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 
 				if checkbox.MustVisible() {
 					checkbox.Hover()
-					time.Sleep(time.Duration(200+rand.Intn(300)) * time.Millisecond)
+					_ = sleepContext(ctx, time.Duration(200+rand.Intn(300))*time.Millisecond)
 
 					checkbox.Click(proto.InputMouseButtonLeft, 1)
 					fmt.Println("Clicked checkbox on main page!")
 
-					time.Sleep(time.Duration(2000+rand.Intn(1000)) * time.Millisecond)
+					_ = sleepContext(ctx, time.Duration(2000+rand.Intn(1000))*time.Millisecond)
 				}
 			})
 
@@ -321,7 +428,7 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 					strings.Contains(txtLower, "i'm human") {
 					btn.Click(proto.InputMouseButtonLeft, 1)
 					fmt.Println("Clicked verification button!")
-					time.Sleep(2 * time.Second)
+					_ = sleepContext(ctx, 2*time.Second)
 					break
 				}
 			}
@@ -330,7 +437,7 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 
 	_ = rod.Try(func() {
 		page.Keyboard.Press(input.Enter)
-		time.Sleep(time.Second)
+		_ = sleepContext(ctx, time.Second)
 	})
 
 	fmt.Println("Waiting for Cloudflare verification to complete...")
@@ -338,7 +445,9 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 	if waitDuration < 10*time.Second {
 		waitDuration = 10 * time.Second
 	}
-	time.Sleep(waitDuration)
+	if err := sleepContext(ctx, waitDuration); err != nil {
+		return err
+	}
 
 	stillOnCloudflare := false
 	_ = rod.Try(func() {
@@ -366,10 +475,87 @@ func (r *BrowserRenderer) handleCloudflare(page *rod.Page, maxWaitTime int) erro
 	return nil
 }
 
+// setHeader replaces any header named name regardless of case.
+func setHeader(headers map[string]string, name, value string) {
+	for existing := range headers {
+		if strings.EqualFold(existing, name) {
+			delete(headers, existing)
+		}
+	}
+	headers[name] = value
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for existing, value := range headers {
+		if strings.EqualFold(existing, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// cookieParams scopes cookies without a domain to the page's URL.
+func cookieParams(pageURL string, cookies []Cookie) []*proto.NetworkCookieParam {
+	params := make([]*proto.NetworkCookieParam, len(cookies))
+	for i, cookie := range cookies {
+		param := &proto.NetworkCookieParam{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HTTPOnly,
+		}
+		if param.Domain == "" {
+			param.URL = pageURL
+		}
+		if param.Path == "" {
+			param.Path = "/"
+		}
+		params[i] = param
+	}
+	return params
+}
+
 func (r *BrowserRenderer) Close() error {
-	if r.browser == nil {
+	return r.pool.Close()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	return r.browser.Close()
+// renderError reports a timeout as errors.ErrTimeout so callers can tell an
+// exhausted deadline apart from a page that failed to load.
+func renderError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.WithCause(errors.Wrap(errors.ErrTimeout, err), "rendering page")
+	}
+	return err
+}
+
+// loadError is renderError for failures to load the page itself, which are
+// reported as errors.ErrProxyFailure when Chrome could not get through the
+// proxy and errors.ErrPageLoad otherwise.
+func loadError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return renderError(ctx, err)
+	}
+	// Chrome's net errors name the proxy, e.g. net::ERR_PROXY_CONNECTION_FAILED
+	// or net::ERR_TUNNEL_CONNECTION_FAILED.
+	msg := err.Error()
+	for _, code := range []string{"ERR_PROXY", "ERR_TUNNEL", "ERR_SOCKS", "ERR_MANDATORY_PROXY"} {
+		if strings.Contains(msg, code) {
+			return errors.Wrap(errors.ErrProxyFailure, err)
+		}
+	}
+	return errors.Wrap(errors.ErrPageLoad, err)
 }
@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sagn1k/scarab/cache"
+	"github.com/Sagn1k/scarab/config"
+)
+
+// Cache modes, set per request with the "cacheMode" param.
+const (
+	// CacheModeUse serves cached results and stores new ones.
+	CacheModeUse = "use"
+	// CacheModeRefresh ignores cached results but stores new ones.
+	CacheModeRefresh = "refresh"
+	// CacheModeBypass neither reads nor writes the cache.
+	CacheModeBypass = "bypass"
+)
+
+// What a result was served from, reported as ScrapeResult.Cache.
+const (
+	CacheHitHTML     = "html"
+	CacheHitMarkdown = "markdown"
+)
+
+// newCache builds the configured cache backend, or returns nil when caching
+// is off. A disk cache that cannot be opened falls back to memory.
+func newCache(cfg *config.Config) cache.Cache {
+	switch cfg.CacheBackend {
+	case "", "none":
+		return nil
+	case "disk":
+		disk, err := cache.NewDisk(cfg.CacheDir)
+		if err == nil {
+			return disk
+		}
+		log.Printf("Warning: %v, using the memory cache", err)
+	}
+	return cache.NewMemory(cfg.CacheMaxEntries)
+}
+
+// htmlCacheKey covers what changes the rendered HTML: the page, the
+// selectors waited for, the actions and pagination run and what the browser
+// sends and emulates. Wait times only affect whether rendering works.
+func htmlCacheKey(pageURL string, options *RenderOptions) string {
+	selectors := append([]string(nil), options.Selectors...)
+	sort.Strings(selectors)
+
+	// Maps marshal with sorted keys, so equal settings give equal keys.
+	browser, _ := json.Marshal(struct {
+		Headers   map[string]string
+		Cookies   []Cookie
+		Viewport  Viewport
+		UserAgent string
+		Actions   []Action
+		Paginate  *PaginateOptions
+	}{options.Headers, options.Cookies, options.Viewport, options.UserAgent, options.Actions, options.Paginate})
+
+	return cache.Key("html", normalizeCacheURL(pageURL), strings.Join(selectors, "\n"), string(browser))
+}
+
+// normalizeCacheURL makes equivalent URLs share a key: the scheme and host
+// are lowercased, default ports and the fragment dropped and the query
+// sorted.
+func normalizeCacheURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = u.Query().Encode()
+
+	return u.String()
+}
+
+// cachedMarkdown is what the markdown cache keeps of a ScrapeResult. Usage
+// is left out since a cached result costs nothing.
+type cachedMarkdown struct {
+	Markdown   string
+	Conversion ConversionInfo
+}
+
+// markdownCacheKey returns the key of the markdown for url converted as
+// opts ask, or "" if the request's model cannot be resolved. It extends
+// the HTML key with the converter and model, so a model change re-converts
+// the cached HTML instead of rendering again.
+func (s *ScraperService) markdownCacheKey(url string, opts ScrapeOptions) string {
+	converter := opts.Converter
+
+	var provider, model string
+	if converter != ConverterNative {
+		client, err := s.llmFor(opts)
+		if err != nil {
+			return ""
+		}
+		provider, model = client.Provider(), client.Model()
+	}
+
+	return cache.Key("markdown", htmlCacheKey(url, renderOptions(opts)), converter, provider, model)
+}
+
+func (s *ScraperService) cacheTTL() time.Duration {
+	return time.Duration(s.config.CacheTTLSeconds) * time.Second
+}
+
+func (s *ScraperService) cachedHTML(key string) (*RenderResult, bool) {
+	var rendered RenderResult
+	if !s.cacheGet(key, &rendered) {
+		return nil, false
+	}
+	rendered.FromCache = true
+	return &rendered, true
+}
+
+func (s *ScraperService) cacheGet(key string, value interface{}) bool {
+	if s.cache == nil {
+		return false
+	}
+	data, ok := s.cache.Get(key)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, value) == nil
+}
+
+func (s *ScraperService) cacheSet(key string, value interface{}) {
+	if s.cache == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	s.cache.Set(key, data, s.cacheTTL())
+}
@@ -0,0 +1,209 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Sagn1k/scarab/cache"
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
+)
+
+func TestNormalizeCacheURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"HTTPS://Example.COM", "https://example.com/"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"https://example.com/a#section", "https://example.com/a"},
+		{"https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/Path", "https://example.com/Path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := normalizeCacheURL(tt.raw); got != tt.want {
+				t.Errorf("normalizeCacheURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLCacheKey(t *testing.T) {
+	base := func() *RenderOptions {
+		return &RenderOptions{
+			Selectors: []string{"a", "b"},
+			Headers:   map[string]string{"X-A": "1", "X-B": "2"},
+			Viewport:  Viewport{Width: 1280, Height: 720},
+		}
+	}
+	key := htmlCacheKey("https://example.com/", base())
+
+	tests := []struct {
+		name   string
+		url    string
+		modify func(o *RenderOptions)
+		same   bool
+	}{
+		{"equivalent URL", "https://EXAMPLE.com#top", func(o *RenderOptions) {}, true},
+		{"selector order", "https://example.com/", func(o *RenderOptions) { o.Selectors = []string{"b", "a"} }, true},
+		{"wait time", "https://example.com/", func(o *RenderOptions) { o.WaitTime = 5000 }, true},
+		{"other page", "https://example.com/other", func(o *RenderOptions) {}, false},
+		{"headers", "https://example.com/", func(o *RenderOptions) { o.Headers["X-B"] = "3" }, false},
+		{"cookies", "https://example.com/", func(o *RenderOptions) { o.Cookies = []Cookie{{Name: "a", Value: "b"}} }, false},
+		{"viewport", "https://example.com/", func(o *RenderOptions) { o.Viewport.Width = 400 }, false},
+		{"user agent", "https://example.com/", func(o *RenderOptions) { o.UserAgent = "bot" }, false},
+		{"actions", "https://example.com/", func(o *RenderOptions) { o.Actions = []Action{{Type: ActionClick, Selector: "a"}} }, false},
+		{"pagination", "https://example.com/", func(o *RenderOptions) { o.Paginate = &PaginateOptions{Mode: PaginateScroll} }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := base()
+			tt.modify(options)
+			if got := htmlCacheKey(tt.url, options); (got == key) != tt.same {
+				t.Errorf("key equal = %v, want %v", got == key, tt.same)
+			}
+		})
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	tests := []struct {
+		backend string
+		dir     string
+		want    string
+	}{
+		{"", "", "<nil>"},
+		{"none", "", "<nil>"},
+		{"memory", "", "*cache.Memory"},
+		{"disk", t.TempDir(), "*cache.Disk"},
+		{"disk", "/dev/null/cache", "*cache.Memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			c := newCache(&config.Config{CacheBackend: tt.backend, CacheDir: tt.dir})
+			got := "<nil>"
+			switch c.(type) {
+			case *cache.Memory:
+				got = "*cache.Memory"
+			case *cache.Disk:
+				got = "*cache.Disk"
+			}
+			if got != tt.want {
+				t.Errorf("newCache(%q) = %s, want %s", tt.backend, got, tt.want)
+			}
+		})
+	}
+}
+
+// primeHTML stores html as the rendered page for url and opts, so that
+// scraping it needs no browser.
+func primeHTML(t *testing.T, s *ScraperService, url, html string, opts ScrapeOptions) {
+	t.Helper()
+	resolved, err := s.resolveOptions(opts)
+	if err != nil {
+		t.Fatalf("resolveOptions() error = %v", err)
+	}
+	s.cacheSet(htmlCacheKey(url, renderOptions(resolved)), RenderResult{HTML: html, URL: url})
+}
+
+func TestScrapeCache(t *testing.T) {
+	const url = "https://example.com/page"
+
+	tests := []struct {
+		name      string
+		opts      ScrapeOptions
+		wantCache []string
+		wantCalls int
+	}{
+		{
+			name:      "native conversions are cached",
+			opts:      ScrapeOptions{Converter: ConverterNative},
+			wantCache: []string{CacheHitHTML, CacheHitMarkdown, CacheHitMarkdown},
+		},
+		{
+			name:      "LLM conversions are cached",
+			opts:      ScrapeOptions{Converter: ConverterLLM},
+			wantCache: []string{CacheHitHTML, CacheHitMarkdown},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{CacheBackend: "memory", LLMProvider: "fake"})
+			fake := llm.NewFakeProvider("# Converted")
+			s.llmClient.RegisterProvider(fake)
+			primeHTML(t, s, url, testPage, tt.opts)
+
+			for i, want := range tt.wantCache {
+				result, err := s.Scrape(context.Background(), url, tt.opts)
+				if err != nil {
+					t.Fatalf("Scrape() #%d error = %v", i+1, err)
+				}
+				if result.Cache != want {
+					t.Errorf("Scrape() #%d Cache = %q, want %q", i+1, result.Cache, want)
+				}
+				if result.Markdown == "" {
+					t.Errorf("Scrape() #%d returned no markdown", i+1)
+				}
+			}
+			if calls := len(fake.Requests()); calls != tt.wantCalls {
+				t.Errorf("LLM got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestScrapeCacheKeysOnModel(t *testing.T) {
+	const url = "https://example.com/page"
+	s := NewScraperService(&config.Config{CacheBackend: "memory", LLMProvider: "fake"})
+	fake := llm.NewFakeProvider("# First", "# Second")
+	s.llmClient.RegisterProvider(fake)
+	primeHTML(t, s, url, testPage, ScrapeOptions{Converter: ConverterLLM})
+
+	for _, model := range []string{"", "other", "", "other"} {
+		if _, err := s.Scrape(context.Background(), url, ScrapeOptions{Converter: ConverterLLM, Model: model}); err != nil {
+			t.Fatalf("Scrape() with model %q error = %v", model, err)
+		}
+	}
+	if calls := len(fake.Requests()); calls != 2 {
+		t.Errorf("LLM got %d calls, want one per model", calls)
+	}
+}
+
+func TestScrapeCacheSkipsFallbackResults(t *testing.T) {
+	const url = "https://example.com/page"
+	s := NewScraperService(&config.Config{
+		CacheBackend: "memory",
+		LLMProvider:  "openai",
+		LLMFallback:  []config.LLMStep{{Provider: ConverterNative}},
+	})
+	openai := llm.NewFakeProvider("# From the LLM")
+	openai.FailNext(statusError(503))
+	s.llmClient.RegisterProvider(namedFake{openai, "openai"})
+
+	opts := ScrapeOptions{Converter: ConverterLLM}
+	primeHTML(t, s, url, testPage, opts)
+
+	first, err := s.Scrape(context.Background(), url, opts)
+	if err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if first.Conversion.Converter != ConverterNative || first.Cache != CacheHitHTML {
+		t.Errorf("first Scrape() = %+v, cache %q, want a native fallback", first.Conversion, first.Cache)
+	}
+
+	second, err := s.Scrape(context.Background(), url, opts)
+	if err != nil {
+		t.Fatalf("Scrape() error = %v", err)
+	}
+	if second.Markdown != "# From the LLM" || second.Cache != CacheHitHTML {
+		t.Errorf("second Scrape() = %q, cache %q, want a fresh LLM conversion", second.Markdown, second.Cache)
+	}
+}
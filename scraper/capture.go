@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/Sagn1k/scarab/artifacts"
+	"github.com/Sagn1k/scarab/config"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Paper sizes in inches, as the DevTools protocol takes them.
+var paperSizes = map[string][2]float64{
+	PaperLetter: {8.5, 11},
+	PaperLegal:  {8.5, 14},
+	PaperA4:     {8.27, 11.69},
+}
+
+// Artifact is a screenshot or PDF of a page, inline as base64 or at the URL
+// of the artifact store.
+type Artifact struct {
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	Data        string `json:"data,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// capture takes the screenshot and PDF the options ask for from the loaded
+// page.
+func capture(page *rod.Page, options *RenderOptions, result *RenderResult) error {
+	if shot := options.Screenshot; shot != nil {
+		req := &proto.PageCaptureScreenshot{Format: proto.PageCaptureScreenshotFormatPng}
+		if shot.Format == ScreenshotJPEG {
+			quality := shot.Quality
+			req.Format = proto.PageCaptureScreenshotFormatJpeg
+			req.Quality = &quality
+		}
+		data, err := page.Screenshot(shot.FullPage, req)
+		if err != nil {
+			return fmt.Errorf("failed to take screenshot: %w", err)
+		}
+		result.Screenshot = data
+	}
+
+	if pdf := options.PDF; pdf != nil {
+		size := paperSizes[pdf.PaperSize]
+		stream, err := page.PDF(&proto.PagePrintToPDF{
+			Landscape:       pdf.Landscape,
+			PrintBackground: pdf.PrintBackground,
+			PaperWidth:      &size[0],
+			PaperHeight:     &size[1],
+		})
+		if err != nil {
+			return fmt.Errorf("failed to print PDF: %w", err)
+		}
+		data, err := io.ReadAll(stream)
+		_ = stream.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read PDF: %w", err)
+		}
+		result.PDF = data
+	}
+
+	return nil
+}
+
+// newArtifactStore builds the configured artifact store, or returns nil to
+// return captures inline. A disk store that cannot be opened falls back to
+// inline.
+func newArtifactStore(cfg *config.Config) artifacts.Store {
+	if cfg.ArtifactStore != "disk" {
+		return nil
+	}
+	disk, err := artifacts.NewDisk(cfg.ArtifactDir, cfg.ArtifactBaseURL)
+	if err != nil {
+		log.Printf("Warning: %v, returning captures inline", err)
+		return nil
+	}
+	return disk
+}
+
+// artifact stores data in the artifact store, or encodes it inline when
+// there is none.
+func (s *ScraperService) artifact(ctx context.Context, contentType string, data []byte) (*Artifact, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	result := &Artifact{ContentType: contentType, Size: len(data)}
+	if s.artifacts == nil {
+		result.Data = base64.StdEncoding.EncodeToString(data)
+		return result, nil
+	}
+
+	url, err := s.artifacts.Put(ctx, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	result.URL = url
+	return result, nil
+}
+
+// Artifacts returns the store captures are written to, or nil when they
+// are returned inline.
+func (s *ScraperService) Artifacts() artifacts.Store {
+	return s.artifacts
+}
@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+// captureRenderer serves a plain page along with the captures asked for,
+// whose bytes name their format.
+type captureRenderer struct {
+	calls atomic.Int32
+}
+
+func (r *captureRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
+	r.calls.Add(1)
+	result := &RenderResult{HTML: "<h1>Page</h1>", URL: url}
+	if shot := options.Screenshot; shot != nil {
+		result.Screenshot = []byte("png")
+		if shot.Format == ScreenshotJPEG {
+			result.Screenshot = []byte("jpeg")
+		}
+	}
+	if options.PDF != nil {
+		result.PDF = []byte("pdf")
+	}
+	return result, nil
+}
+
+func TestScrapeCaptures(t *testing.T) {
+	const url = "https://example.com/page"
+
+	tests := []struct {
+		name           string
+		store          string
+		opts           ScrapeOptions
+		wantScreenshot string
+		wantPDF        string
+	}{
+		{
+			name: "no captures",
+		},
+		{
+			name:           "PNG screenshot inline",
+			opts:           ScrapeOptions{Screenshot: &ScreenshotOptions{}},
+			wantScreenshot: "image/png png",
+		},
+		{
+			name:           "JPEG screenshot and PDF inline",
+			opts:           ScrapeOptions{Screenshot: &ScreenshotOptions{Format: ScreenshotJPEG}, PDF: &PDFOptions{}},
+			wantScreenshot: "image/jpeg jpeg",
+			wantPDF:        "application/pdf pdf",
+		},
+		{
+			name:           "stored on disk",
+			store:          "disk",
+			opts:           ScrapeOptions{Screenshot: &ScreenshotOptions{}, PDF: &PDFOptions{}},
+			wantScreenshot: "image/png png",
+			wantPDF:        "application/pdf pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := NewScraperService(&config.Config{
+				Converter:       ConverterNative,
+				ArtifactStore:   tt.store,
+				ArtifactDir:     dir,
+				ArtifactBaseURL: "/artifacts",
+			})
+			s.SetRenderer(&captureRenderer{})
+
+			result, err := s.Scrape(context.Background(), url, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := artifactContent(t, dir, result.Screenshot); got != tt.wantScreenshot {
+				t.Errorf("Screenshot = %q, want %q", got, tt.wantScreenshot)
+			}
+			if got := artifactContent(t, dir, result.PDF); got != tt.wantPDF {
+				t.Errorf("PDF = %q, want %q", got, tt.wantPDF)
+			}
+		})
+	}
+}
+
+// artifactContent returns the content type and data of a, read from dir
+// when it was stored rather than returned inline.
+func artifactContent(t *testing.T, dir string, a *Artifact) string {
+	t.Helper()
+	if a == nil {
+		return ""
+	}
+
+	var data []byte
+	var err error
+	if a.URL != "" {
+		if a.Data != "" {
+			t.Errorf("artifact has both data and a URL")
+		}
+		if !strings.HasPrefix(a.URL, "/artifacts/") {
+			t.Errorf("URL = %q, want it under /artifacts/", a.URL)
+		}
+		data, err = os.ReadFile(dir + "/" + path.Base(a.URL))
+	} else {
+		data, err = base64.StdEncoding.DecodeString(a.Data)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Size != len(data) {
+		t.Errorf("Size = %d, want %d", a.Size, len(data))
+	}
+	return a.ContentType + " " + string(data)
+}
+
+func TestScrapeCapturesSkipHTMLCache(t *testing.T) {
+	const url = "https://example.com/page"
+
+	s := NewScraperService(&config.Config{Converter: ConverterNative, CacheBackend: "memory"})
+	renderer := &captureRenderer{}
+	s.SetRenderer(renderer)
+
+	opts := ScrapeOptions{Screenshot: &ScreenshotOptions{}}
+	primeHTML(t, s, url, "<h1>Cached</h1>", opts)
+
+	// The cache holds no captures, so the page is rendered again.
+	result, err := s.Scrape(context.Background(), url, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renderer.calls.Load() != 1 || result.Screenshot == nil {
+		t.Errorf("rendered %d times with Screenshot %v, want one render with a screenshot", renderer.calls.Load(), result.Screenshot)
+	}
+}
@@ -0,0 +1,155 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Sagn1k/scarab/errors"
+)
+
+type CrawlScope string
+
+const (
+	ScopeDomain CrawlScope = "domain"
+	ScopePrefix CrawlScope = "prefix"
+)
+
+type CrawlOptions struct {
+	MaxDepth int
+	MaxPages int
+	Scope    CrawlScope
+	Include  []*regexp.Regexp
+	Exclude  []*regexp.Regexp
+	Params   ScrapeOptions
+}
+
+type CrawlPage struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Markdown string `json:"markdown,omitempty"`
+	Outputs
+	Metadata   *Metadata         `json:"metadata,omitempty"`
+	Actions    []ActionResult    `json:"actions,omitempty"`
+	Pagination *PaginationResult `json:"pagination,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type crawlTarget struct {
+	url   string
+	depth int
+}
+
+// Crawl fetches seed and then follows links breadth-first until MaxDepth or
+// MaxPages is reached. onPage is called for every page fetched, including
+// the ones that failed.
+func (s *ScraperService) Crawl(ctx context.Context, seed string, opts CrawlOptions, onPage func(CrawlPage)) error {
+	seedURL, err := url.Parse(seed)
+	if err != nil || (seedURL.Scheme != "http" && seedURL.Scheme != "https") {
+		return errors.WithCause(errors.ErrInvalidURL, "seed %q", seed)
+	}
+	if err := opts.Params.Validate(); err != nil {
+		return err
+	}
+
+	start, _ := normalizeLink(seedURL, seed)
+	seedURL, _ = url.Parse(start)
+	queue := []crawlTarget{{url: start, depth: 0}}
+	seen := map[string]bool{start: true}
+	fetched := 0
+
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if opts.MaxPages > 0 && fetched >= opts.MaxPages {
+			return nil
+		}
+
+		target := queue[0]
+		queue = queue[1:]
+		fetched++
+
+		result := CrawlPage{URL: target.url, Depth: target.depth}
+
+		page, err := s.scrapePage(ctx, target.url, opts.Params)
+		if err != nil {
+			result.Error = err.Error()
+			onPage(result)
+			continue
+		}
+		result.Markdown = page.result.Markdown
+		result.Outputs = page.result.Outputs
+		result.Metadata = page.result.Metadata
+		result.Actions = page.result.Actions
+		result.Pagination = page.result.Pagination
+		onPage(result)
+
+		if target.depth >= opts.MaxDepth {
+			continue
+		}
+
+		for _, link := range ExtractLinks(page.rendered.HTML, page.rendered.URL) {
+			if seen[link] || !opts.allows(seedURL, link) {
+				continue
+			}
+			seen[link] = true
+			queue = append(queue, crawlTarget{url: link, depth: target.depth + 1})
+		}
+	}
+
+	return nil
+}
+
+func (o CrawlOptions) allows(seed *url.URL, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	if trimWWW(u.Hostname()) != trimWWW(seed.Hostname()) {
+		return false
+	}
+
+	if o.Scope == ScopePrefix && !underPath(u.Path, seed.Path) {
+		return false
+	}
+
+	if len(o.Include) > 0 {
+		matched := false
+		for _, re := range o.Include {
+			if re.MatchString(link) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, re := range o.Exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// underPath reports whether path is base or below it. A base of /docs
+// covers /docs and /docs/..., but not /docs-old.
+func underPath(path, base string) bool {
+	if base == "" || base == "/" {
+		return true
+	}
+	if strings.HasSuffix(base, "/") {
+		return strings.HasPrefix(path, base)
+	}
+	return path == base || strings.HasPrefix(path, base+"/")
+}
+
+func trimWWW(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
@@ -0,0 +1,212 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+func TestCrawlOptionsAllows(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CrawlOptions
+		seed string
+		link string
+		want bool
+	}{
+		{"same host", CrawlOptions{Scope: ScopeDomain}, "https://site.com/docs", "https://site.com/blog", true},
+		{"www is the same host", CrawlOptions{Scope: ScopeDomain}, "https://www.site.com/", "https://site.com/a", true},
+		{"other host", CrawlOptions{Scope: ScopeDomain}, "https://site.com/", "https://other.com/", false},
+		{"subdomain", CrawlOptions{Scope: ScopeDomain}, "https://site.com/", "https://docs.site.com/", false},
+
+		{"prefix without slash covers itself", CrawlOptions{Scope: ScopePrefix}, "https://site.com/docs", "https://site.com/docs", true},
+		{"prefix without slash covers children", CrawlOptions{Scope: ScopePrefix}, "https://site.com/docs", "https://site.com/docs/install", true},
+		{"prefix without slash excludes siblings", CrawlOptions{Scope: ScopePrefix}, "https://site.com/docs", "https://site.com/docs-old", false},
+		{"prefix without slash excludes the root", CrawlOptions{Scope: ScopePrefix}, "https://site.com/docs", "https://site.com/blog", false},
+		{"prefix with slash", CrawlOptions{Scope: ScopePrefix}, "https://site.com/docs/", "https://site.com/docs/a/b", true},
+		{"prefix with slash excludes parent", CrawlOptions{Scope: ScopePrefix}, "https://site.com/docs/", "https://site.com/docs", false},
+		{"root prefix", CrawlOptions{Scope: ScopePrefix}, "https://site.com/", "https://site.com/anything", true},
+
+		{"include matches", CrawlOptions{Include: []*regexp.Regexp{regexp.MustCompile(`/posts/`)}}, "https://site.com/", "https://site.com/posts/1", true},
+		{"include misses", CrawlOptions{Include: []*regexp.Regexp{regexp.MustCompile(`/posts/`)}}, "https://site.com/", "https://site.com/about", false},
+		{"exclude wins", CrawlOptions{Exclude: []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)}}, "https://site.com/", "https://site.com/a.pdf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seed, err := url.Parse(tt.seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.opts.allows(seed, tt.link); got != tt.want {
+				t.Errorf("allows(%q, %q) = %v, want %v", tt.seed, tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		pageURL  string
+		want     []string
+	}{
+		{
+			"relative and absolute",
+			`<a href="/a">A</a><a href="b">B</a><a href="https://Other.com">O</a>`,
+			"https://site.com/docs/",
+			[]string{"https://site.com/a", "https://site.com/docs/b", "https://other.com/"},
+		},
+		{
+			"fragments dropped and duplicates merged",
+			`<a href="/a#top">A</a><a href="/a">A again</a><a href="#">Self</a>`,
+			"https://site.com/",
+			[]string{"https://site.com/a", "https://site.com/"},
+		},
+		{
+			"base href",
+			`<base href="https://cdn.site.com/root/"><a href="page">P</a>`,
+			"https://site.com/",
+			[]string{"https://cdn.site.com/root/page"},
+		},
+		{
+			"skipped links",
+			`<a href="mailto:a@site.com">M</a><a href="javascript:void(0)">J</a><a href="/ad" rel="nofollow">N</a><a>None</a><link href="/style.css">`,
+			"https://site.com/",
+			nil,
+		},
+		{
+			"nofollow among other rel values",
+			`<a href="/a" rel="nofollow noopener">A</a><a href="/b" rel="NoFollow">B</a><a href="/c" rel="noopener">C</a>`,
+			"https://site.com/",
+			[]string{"https://site.com/c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractLinks(tt.document, tt.pageURL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// siteRenderer serves pages from a map and fails for the rest.
+type siteRenderer map[string]string
+
+func (r siteRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
+	html, ok := r[url]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", url)
+	}
+	return &RenderResult{HTML: html, URL: url}, nil
+}
+
+func TestCrawl(t *testing.T) {
+	site := siteRenderer{
+		"https://site.com/":            `<a href="/docs/">Docs</a><a href="/blog">Blog</a><a href="https://other.com/">Other</a>`,
+		"https://site.com/docs/":       `<a href="/docs/a">A</a><a href="/docs/missing">Missing</a><a href="/">Home</a>`,
+		"https://site.com/docs/a":      `<a href="/docs/a/deep">Deep</a>`,
+		"https://site.com/docs/a/deep": `<p>Deep</p>`,
+		"https://site.com/blog":        `<p>Blog</p>`,
+	}
+
+	tests := []struct {
+		name string
+		seed string
+		opts CrawlOptions
+		want []string
+	}{
+		{
+			"breadth first within depth",
+			"https://site.com/",
+			CrawlOptions{MaxDepth: 1, Scope: ScopeDomain},
+			[]string{"0 https://site.com/", "1 https://site.com/docs/", "1 https://site.com/blog"},
+		},
+		{
+			"failed pages are reported",
+			"https://site.com/docs/",
+			CrawlOptions{MaxDepth: 1, Scope: ScopePrefix},
+			[]string{"0 https://site.com/docs/", "1 https://site.com/docs/a", "1 https://site.com/docs/missing failed"},
+		},
+		{
+			"page limit",
+			"https://site.com/",
+			CrawlOptions{MaxDepth: 5, MaxPages: 2, Scope: ScopeDomain},
+			[]string{"0 https://site.com/", "1 https://site.com/docs/"},
+		},
+		{
+			"seed only",
+			"https://site.com/docs/a#intro",
+			CrawlOptions{Scope: ScopeDomain},
+			[]string{"0 https://site.com/docs/a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{Converter: ConverterNative})
+			s.SetRenderer(site)
+
+			var got []string
+			err := s.Crawl(context.Background(), tt.seed, tt.opts, func(page CrawlPage) {
+				entry := fmt.Sprintf("%d %s", page.Depth, page.URL)
+				if page.Error != "" {
+					entry += " failed"
+				}
+				got = append(got, entry)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("crawled %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlErrors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		seed    string
+		opts    CrawlOptions
+		wantErr error
+	}{
+		{"invalid seed", context.Background(), "ftp://site.com/", CrawlOptions{}, errors.ErrInvalidURL},
+		{"cancelled", cancelled, "https://site.com/", CrawlOptions{}, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{Converter: ConverterNative})
+			s.SetRenderer(siteRenderer{})
+
+			err := s.Crawl(tt.ctx, tt.seed, tt.opts, func(page CrawlPage) {
+				t.Errorf("crawled %s", page.URL)
+			})
+			if !errors.IsType(err, tt.wantErr) {
+				t.Errorf("Crawl() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	s := NewScraperService(&config.Config{})
+	err := s.Crawl(context.Background(), "https://site.com/", CrawlOptions{Params: ScrapeOptions{Converter: "magic"}}, func(CrawlPage) {})
+	var invalid *OptionsError
+	if !errors.AsType(err, &invalid) {
+		t.Errorf("Crawl() with invalid params error = %v, want an OptionsError", err)
+	}
+}
@@ -0,0 +1,146 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Sagn1k/scarab/convert"
+	"github.com/Sagn1k/scarab/llm"
+	"github.com/Sagn1k/scarab/schema"
+)
+
+type ExtractResult struct {
+	URL      string
+	Data     interface{}
+	Valid    bool
+	Errors   []schema.ValidationError
+	Attempts int
+	// Truncated is set when the page content was cut to fit the model.
+	Truncated bool
+	Usage     llm.Usage
+}
+
+// Extract renders url and has the LLM fill jsonSchema from the page. Answers
+// that are not valid JSON or do not match the schema are sent back to the
+// model with the problems listed, up to ExtractMaxAttempts times. The last
+// answer is returned with its validation errors if none passes.
+func (s *ScraperService) Extract(ctx context.Context, url string, jsonSchema map[string]interface{}, opts ScrapeOptions) (*ExtractResult, error) {
+	opts, err := s.resolveOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+
+	meter := &llm.Meter{}
+	ctx = llm.WithUsageRecorder(ctx, meter)
+
+	client, err := s.llmFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := s.render(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reportProgress(ctx, StageConverting)
+
+	// The whole page is kept since fields such as prices often sit outside
+	// the main article.
+	content, err := convert.ToMarkdown(rendered.HTML, rendered.URL, convert.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExtractResult{URL: rendered.URL}
+	if limit := s.config.LLMChunkChars; limit > 0 && len(content) > limit {
+		content = truncateContent(content, limit)
+		result.Truncated = true
+	}
+
+	maxAttempts := s.config.ExtractMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var previous string
+	var problems []string
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		result.Attempts = attempt
+
+		answer, err := client.ExtractJSON(ctx, content, rendered.URL, jsonSchema, previous, problems)
+		result.Usage = meter.Usage()
+		if err != nil {
+			return nil, scrapeError(ctx, fmt.Errorf("failed to extract data: %w", err))
+		}
+
+		data, err := parseJSONAnswer(answer)
+		if err != nil {
+			previous = answer
+			problems = []string{"the response is not valid JSON: " + err.Error()}
+			result.Data = nil
+			result.Errors = []schema.ValidationError{{Path: "/", Message: problems[0]}}
+			continue
+		}
+
+		schema.DropNulls(jsonSchema, data)
+		result.Data = data
+		result.Errors = schema.Validate(jsonSchema, data)
+		if len(result.Errors) == 0 {
+			result.Valid = true
+			return result, nil
+		}
+
+		previous = answer
+		problems = problems[:0]
+		for _, validationErr := range result.Errors {
+			problems = append(problems, validationErr.Error())
+		}
+	}
+
+	return result, nil
+}
+
+// truncateContent cuts content to at most limit bytes, at the last line break
+// when there is one in the second half, and otherwise between runes.
+func truncateContent(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	if i := strings.LastIndexByte(content[:cut], '\n'); i >= limit/2 {
+		cut = i + 1
+	}
+	return content[:cut]
+}
+
+// parseJSONAnswer decodes the model's answer, tolerating code fences and
+// text around the JSON value.
+func parseJSONAnswer(answer string) (interface{}, error) {
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "```") {
+		answer = strings.TrimPrefix(answer, "```json")
+		answer = strings.TrimPrefix(answer, "```")
+		answer = strings.TrimSuffix(strings.TrimSpace(answer), "```")
+	}
+
+	if start := strings.IndexAny(answer, "{["); start > 0 {
+		answer = answer[start:]
+	}
+
+	var data interface{}
+	decoder := json.NewDecoder(strings.NewReader(answer))
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/llm"
+)
+
+func TestTruncateContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+		want    string
+	}{
+		{"short", "abc", 10, "abc"},
+		{"at the last line break", "line one\nline two\nline three", 22, "line one\nline two\n"},
+		{"line break too early", "a\nbcdefghijklmnop", 10, "a\nbcdefghi"},
+		{"between runes", "ééééé", 5, "éé"},
+		{"between runes after a break", "ab\ncdé", 6, "ab\ncd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateContent(tt.content, tt.limit); got != tt.want {
+				t.Errorf("truncateContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONAnswer(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		want    string
+		wantErr bool
+	}{
+		{"plain object", `{"a":1}`, "map[a:1]", false},
+		{"code fence", "```json\n{\"a\":1}\n```", "map[a:1]", false},
+		{"bare fence", "```\n[1,2]\n```", "[1 2]", false},
+		{"text around", "Here it is: {\"a\":1} hope that helps", "map[a:1]", false},
+		{"not JSON", "no idea", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJSONAnswer(tt.answer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONAnswer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && fmt.Sprint(got) != tt.want {
+				t.Errorf("parseJSONAnswer() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRetries(t *testing.T) {
+	const url = "https://example.com/post"
+	jsonSchema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"title"},
+	}
+
+	tests := []struct {
+		name        string
+		first       string
+		wantProblem string
+	}{
+		{"invalid JSON", "not json", "not valid JSON"},
+		{"fails the schema", `{"title":1}`, "/title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{LLMProvider: "fake", Converter: ConverterNative, ExtractMaxAttempts: 3})
+			s.SetRenderer(siteRenderer{url: "<h1>x</h1>"})
+			fake := llm.NewFakeProvider(tt.first, `{"title":"x"}`)
+			s.LLM().RegisterProvider(fake)
+
+			result, err := s.Extract(context.Background(), url, jsonSchema, ScrapeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Attempts != 2 || !result.Valid {
+				t.Fatalf("Attempts = %d, Valid = %v, want 2 and true (%v)", result.Attempts, result.Valid, result.Errors)
+			}
+			if data, _ := result.Data.(map[string]interface{}); data["title"] != "x" {
+				t.Errorf("Data = %v, want the second answer", result.Data)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("got %d requests, want 2", len(requests))
+			}
+			messages := requests[1].Messages
+			if len(messages) != 3 || messages[1].Content != tt.first {
+				t.Fatalf("retry messages = %+v, want the previous answer sent back", messages)
+			}
+			if !strings.Contains(messages[2].Content, tt.wantProblem) {
+				t.Errorf("retry message = %q, want it to mention %q", messages[2].Content, tt.wantProblem)
+			}
+		})
+	}
+}
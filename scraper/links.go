@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractLinks returns the absolute http(s) links found in anchor tags,
+// resolved against the page URL or its <base href>. Fragments are dropped and
+// each link is returned once, in document order.
+func ExtractLinks(document string, pageURL string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var links []string
+
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return links
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		if token.Data != "a" && token.Data != "base" {
+			continue
+		}
+
+		href := attr(token, "href")
+		if href == "" {
+			continue
+		}
+
+		if token.Data == "base" {
+			if b, err := base.Parse(href); err == nil {
+				base = b
+			}
+			continue
+		}
+
+		if hasRel(attr(token, "rel"), "nofollow") {
+			continue
+		}
+
+		link, ok := normalizeLink(base, href)
+		if !ok || seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+}
+
+func normalizeLink(base *url.URL, href string) (string, bool) {
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String(), true
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,395 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Metadata is what a page says about itself in its markup, mostly in the
+// <head>. Fields the page does not provide are left empty.
+type Metadata struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Canonical   string `json:"canonical,omitempty"`
+	// Language is the lang attribute of <html>.
+	Language string `json:"language,omitempty"`
+	Author   string `json:"author,omitempty"`
+	// Published and Modified are given as the page states them, usually
+	// ISO 8601.
+	Published string `json:"published,omitempty"`
+	Modified  string `json:"modified,omitempty"`
+	// OpenGraph and Twitter hold the og: and twitter: properties without
+	// their prefix, e.g. "title" or "image". The first of repeated
+	// properties is kept.
+	OpenGraph map[string]string `json:"openGraph,omitempty"`
+	Twitter   map[string]string `json:"twitter,omitempty"`
+	JSONLD    []json.RawMessage `json:"jsonLd,omitempty"`
+	Microdata []MicrodataItem   `json:"microdata,omitempty"`
+}
+
+// MicrodataItem is an itemscope element. Property values are strings or,
+// for nested items, MicrodataItems.
+type MicrodataItem struct {
+	Type       []string                 `json:"type,omitempty"`
+	ID         string                   `json:"id,omitempty"`
+	Properties map[string][]interface{} `json:"properties"`
+}
+
+// Meta names and properties that give the author and dates, in order of
+// preference.
+var (
+	authorMeta    = []string{"author", "article:author", "dc.creator", "byl"}
+	publishedMeta = []string{"article:published_time", "datepublished", "pubdate", "publishdate", "date", "dc.date", "dc.date.issued"}
+	modifiedMeta  = []string{"article:modified_time", "og:updated_time", "datemodified", "last-modified", "dc.date.modified"}
+)
+
+// ExtractMetadata collects the metadata of an HTML document. Relative URLs
+// are resolved against pageURL.
+func ExtractMetadata(document string, pageURL string) *Metadata {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return &Metadata{}
+	}
+	base, _ := url.Parse(pageURL)
+	resolve := func(ref string) string {
+		ref = strings.TrimSpace(ref)
+		if base == nil || ref == "" {
+			return ref
+		}
+		if u, err := base.Parse(ref); err == nil {
+			return u.String()
+		}
+		return ref
+	}
+
+	meta := &Metadata{}
+	// Meta values by lowercased name or property, first one wins.
+	values := make(map[string]string)
+	var jsonLD []interface{}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Html:
+				meta.Language = strings.TrimSpace(nodeAttr(n, "lang"))
+			case atom.Base:
+				if href := nodeAttr(n, "href"); href != "" && base != nil {
+					if b, err := base.Parse(href); err == nil {
+						base = b
+					}
+				}
+			case atom.Title:
+				if meta.Title == "" && !insideSVG(n) {
+					meta.Title = collapseSpace(nodeText(n))
+				}
+			case atom.Meta:
+				key := strings.ToLower(strings.TrimSpace(nodeAttr(n, "property")))
+				if key == "" {
+					key = strings.ToLower(strings.TrimSpace(nodeAttr(n, "name")))
+				}
+				if key == "" {
+					key = strings.ToLower(strings.TrimSpace(nodeAttr(n, "itemprop")))
+				}
+				content := strings.TrimSpace(nodeAttr(n, "content"))
+				if key != "" && content != "" {
+					if _, ok := values[key]; !ok {
+						values[key] = content
+					}
+				}
+			case atom.Link:
+				rel := strings.Fields(strings.ToLower(nodeAttr(n, "rel")))
+				for _, r := range rel {
+					if r == "canonical" && meta.Canonical == "" {
+						meta.Canonical = resolve(nodeAttr(n, "href"))
+					}
+				}
+			case atom.Script:
+				if strings.EqualFold(strings.TrimSpace(nodeAttr(n, "type")), "application/ld+json") {
+					var value interface{}
+					raw := nodeText(n)
+					if json.Unmarshal([]byte(raw), &value) == nil {
+						var compact bytes.Buffer
+						if json.Compact(&compact, []byte(raw)) == nil {
+							meta.JSONLD = append(meta.JSONLD, compact.Bytes())
+							jsonLD = append(jsonLD, value)
+						}
+					}
+				}
+				return
+			case atom.A:
+				if meta.Author == "" && hasRel(nodeAttr(n, "rel"), "author") {
+					meta.Author = collapseSpace(nodeText(n))
+				}
+			case atom.Time:
+				_, pubdate := attrOf(n, "pubdate")
+				if meta.Published == "" && (pubdate || hasRel(nodeAttr(n, "rel"), "pubdate")) {
+					meta.Published = strings.TrimSpace(nodeAttr(n, "datetime"))
+				}
+			}
+
+			if _, ok := attrOf(n, "itemscope"); ok {
+				if _, isProp := attrOf(n, "itemprop"); !isProp {
+					meta.Microdata = append(meta.Microdata, microdataItem(n, resolve))
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	meta.OpenGraph = prefixed(values, "og:")
+	meta.Twitter = prefixed(values, "twitter:")
+
+	if meta.Title == "" {
+		meta.Title = first(values, "og:title", "twitter:title")
+	}
+	meta.Description = first(values, "description", "og:description", "twitter:description")
+	if meta.Canonical == "" {
+		if ogURL := values["og:url"]; ogURL != "" {
+			meta.Canonical = resolve(ogURL)
+		}
+	}
+
+	// Meta tags first, then structured data, then markup hints found above.
+	if author := first(values, authorMeta...); author != "" && !strings.HasPrefix(author, "http") {
+		meta.Author = author
+	} else if author := linkedDataString(jsonLD, "author"); author != "" {
+		meta.Author = author
+	} else if meta.Author == "" {
+		meta.Author = microdataString(meta.Microdata, "author")
+	}
+	if published := first(values, publishedMeta...); published != "" {
+		meta.Published = published
+	} else if published := linkedDataString(jsonLD, "datePublished"); published != "" {
+		meta.Published = published
+	} else if meta.Published == "" {
+		meta.Published = microdataString(meta.Microdata, "datePublished")
+	}
+	if modified := first(values, modifiedMeta...); modified != "" {
+		meta.Modified = modified
+	} else if modified := linkedDataString(jsonLD, "dateModified"); modified != "" {
+		meta.Modified = modified
+	} else {
+		meta.Modified = microdataString(meta.Microdata, "dateModified")
+	}
+
+	return meta
+}
+
+// microdataItem reads the properties of the itemscope element n. Properties
+// of nested items belong to those items.
+func microdataItem(n *html.Node, resolve func(string) string) MicrodataItem {
+	item := MicrodataItem{
+		Type:       strings.Fields(nodeAttr(n, "itemtype")),
+		ID:         strings.TrimSpace(nodeAttr(n, "itemid")),
+		Properties: make(map[string][]interface{}),
+	}
+
+	var walk func(el *html.Node)
+	walk = func(el *html.Node) {
+		for child := el.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			_, scope := attrOf(child, "itemscope")
+			names := strings.Fields(nodeAttr(child, "itemprop"))
+			if len(names) > 0 {
+				var value interface{}
+				if scope {
+					value = microdataItem(child, resolve)
+				} else {
+					value = microdataValue(child, resolve)
+				}
+				for _, name := range names {
+					item.Properties[name] = append(item.Properties[name], value)
+				}
+			}
+			if !scope {
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+
+	return item
+}
+
+// microdataValue is the value of an itemprop element, which depends on the
+// element as the microdata spec lays out.
+func microdataValue(n *html.Node, resolve func(string) string) string {
+	switch n.DataAtom {
+	case atom.Meta:
+		return strings.TrimSpace(nodeAttr(n, "content"))
+	case atom.A, atom.Link, atom.Area:
+		return resolve(nodeAttr(n, "href"))
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Iframe, atom.Embed, atom.Track:
+		return resolve(nodeAttr(n, "src"))
+	case atom.Object:
+		return resolve(nodeAttr(n, "data"))
+	case atom.Time:
+		if datetime, ok := attrOf(n, "datetime"); ok {
+			return strings.TrimSpace(datetime)
+		}
+	case atom.Data, atom.Meter:
+		return strings.TrimSpace(nodeAttr(n, "value"))
+	}
+	if content, ok := attrOf(n, "content"); ok {
+		return strings.TrimSpace(content)
+	}
+	return collapseSpace(nodeText(n))
+}
+
+// linkedDataString finds key in the JSON-LD blocks, including their @graph,
+// and returns it as a string. Objects such as a Person give their name.
+func linkedDataString(blocks []interface{}, key string) string {
+	var find func(v interface{}) string
+	find = func(v interface{}) string {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				if s := find(item); s != "" {
+					return s
+				}
+			}
+		case map[string]interface{}:
+			if value, ok := v[key]; ok {
+				if s := linkedDataName(value); s != "" {
+					return s
+				}
+			}
+			if graph, ok := v["@graph"]; ok {
+				return find(graph)
+			}
+		}
+		return ""
+	}
+	return find(blocks)
+}
+
+func linkedDataName(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		var names []string
+		for _, item := range v {
+			if name := linkedDataName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// microdataString returns the first value of property in the items, with
+// a nested item such as a Person given by its name.
+func microdataString(items []MicrodataItem, property string) string {
+	for _, item := range items {
+		for _, value := range item.Properties[property] {
+			switch value := value.(type) {
+			case string:
+				if value != "" {
+					return value
+				}
+			case MicrodataItem:
+				if names := value.Properties["name"]; len(names) > 0 {
+					if name, ok := names[0].(string); ok && name != "" {
+						return name
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func prefixed(values map[string]string, prefix string) map[string]string {
+	var found map[string]string
+	for key, value := range values {
+		if strings.HasPrefix(key, prefix) {
+			if found == nil {
+				found = make(map[string]string)
+			}
+			found[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	return found
+}
+
+func first(values map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := values[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// hasRel reports whether the space-separated rel attribute value rels
+// contains rel, ignoring case.
+func hasRel(rels, rel string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rels)) {
+		if r == rel {
+			return true
+		}
+	}
+	return false
+}
+
+func insideSVG(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Svg {
+			return true
+		}
+	}
+	return false
+}
+
+func attrOf(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func nodeAttr(n *html.Node, key string) string {
+	val, _ := attrOf(n, key)
+	return val
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && (child.DataAtom == atom.Script || child.DataAtom == atom.Style) {
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
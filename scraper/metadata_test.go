@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "head tags",
+			html: `<html lang="en-GB"><head>
+				<title>  The
+				Title </title>
+				<meta name="description" content="About the page">
+				<link rel="canonical" href="/canonical">
+				<meta name="author" content="Ada">
+				<meta property="article:published_time" content="2024-01-02T03:04:05Z">
+				<meta property="article:modified_time" content="2024-02-03">
+			</head><body></body></html>`,
+			want: `{"title":"The Title","description":"About the page","canonical":"https://example.com/canonical","language":"en-GB","author":"Ada","published":"2024-01-02T03:04:05Z","modified":"2024-02-03"}`,
+		},
+		{
+			name: "OpenGraph and Twitter fill in",
+			html: `<head>
+				<meta property="og:title" content="OG title">
+				<meta property="og:title" content="Second OG title">
+				<meta property="OG:Description" content="OG description">
+				<meta property="og:url" content="https://example.com/og">
+				<meta name="twitter:card" content="summary">
+			</head>`,
+			want: `{"title":"OG title","description":"OG description","canonical":"https://example.com/og","openGraph":{"description":"OG description","title":"OG title","url":"https://example.com/og"},"twitter":{"card":"summary"}}`,
+		},
+		{
+			name: "base element changes how URLs resolve",
+			html: `<head><base href="https://cdn.example.org/dir/"><link rel="canonical" href="page"></head>`,
+			want: `{"canonical":"https://cdn.example.org/dir/page"}`,
+		},
+		{
+			name: "SVG titles are not the page title",
+			html: `<body><svg><title>icon</title></svg></body>`,
+			want: `{}`,
+		},
+		{
+			name: "JSON-LD gives author and dates",
+			html: `<head><script type="application/ld+json">
+				{"@context": "https://schema.org", "@graph": [{"@type": "Article", "author": [{"@type": "Person", "name": "Ada"}, {"name": "Grace"}], "datePublished": "2023-05-06"}]}
+			</script><script type="application/ld+json">not json</script></head>`,
+			want: `{"author":"Ada, Grace","published":"2023-05-06","jsonLd":[{"@context":"https://schema.org","@graph":[{"@type":"Article","author":[{"@type":"Person","name":"Ada"},{"name":"Grace"}],"datePublished":"2023-05-06"}]}]}`,
+		},
+		{
+			name: "markup hints",
+			html: `<body><a rel="author" href="/ada">Ada  L.</a><time pubdate datetime="2022-01-01">Jan 1</time></body>`,
+			want: `{"author":"Ada L.","published":"2022-01-01"}`,
+		},
+		{
+			name: "meta tags win over structured data and markup",
+			html: `<head><meta name="author" content="Meta"><script type="application/ld+json">{"author": "LD"}</script></head><body><a rel="author">Link</a></body>`,
+			want: `{"author":"Meta","jsonLd":[{"author":"LD"}]}`,
+		},
+		{
+			name: "author URLs are not names",
+			html: `<head><meta property="article:author" content="https://example.com/ada"><script type="application/ld+json">{"author": {"name": "Ada"}}</script></head>`,
+			want: `{"author":"Ada","jsonLd":[{"author":{"name":"Ada"}}]}`,
+		},
+		{
+			name: "microdata",
+			html: `<body><div itemscope itemtype="https://schema.org/BlogPosting" itemid="post-1">
+				<h1 itemprop="headline">Post</h1>
+				<div itemprop="author" itemscope itemtype="https://schema.org/Person"><span itemprop="name">Ada</span></div>
+				<time itemprop="datePublished" datetime="2021-03-04">March 4</time>
+				<a itemprop="url" href="/post">link</a>
+				<img itemprop="image" src="a.png">
+				<meta itemprop="dateModified" content="2021-04-05">
+			</div></body>`,
+			want: `{"author":"Ada","published":"2021-03-04","modified":"2021-04-05","microdata":[{"type":["https://schema.org/BlogPosting"],"id":"post-1","properties":{"author":[{"type":["https://schema.org/Person"],"properties":{"name":["Ada"]}}],"dateModified":["2021-04-05"],"datePublished":["2021-03-04"],"headline":["Post"],"image":["https://example.com/a.png"],"url":["https://example.com/post"]}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(ExtractMetadata(tt.html, "https://example.com/page"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ExtractMetadata() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
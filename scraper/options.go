@@ -0,0 +1,534 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+// Output formats of a scrape. Every requested format is produced from the
+// same rendered page.
+const (
+	OutputMarkdown = "markdown"
+	OutputText     = "text"
+	OutputHTML     = "html"
+	OutputJSON     = "json"
+)
+
+const (
+	ScreenshotPNG  = "png"
+	ScreenshotJPEG = "jpeg"
+
+	PaperLetter = "letter"
+	PaperLegal  = "legal"
+	PaperA4     = "a4"
+)
+
+const (
+	RateLimitWait = "wait"
+	RateLimitFail = "fail"
+)
+
+// Limits on what a request can ask of the browser.
+const (
+	maxWaitTime       = 120000
+	maxViewportWidth  = 7680
+	maxViewportHeight = 4320
+	maxScaleFactor    = 4
+)
+
+// ScrapeOptions are the per-request settings of a scrape, sent as "params".
+// Unset fields take the server's defaults.
+type ScrapeOptions struct {
+	// WaitTime is how long to wait for challenges and late content after
+	// the page loads, in milliseconds.
+	WaitTime *int `json:"waitTime,omitempty"`
+	// Selectors are waited for, up to 5 seconds each, before the HTML is
+	// read.
+	Selectors        []string `json:"selectors,omitempty"`
+	BypassCloudflare *bool    `json:"bypassCloudflare,omitempty"`
+	Converter        string   `json:"converter,omitempty"`
+	// OutputFormat is a shorthand for Formats with a single format.
+	OutputFormat string `json:"outputFormat,omitempty"`
+	// Formats lists the outputs to return, by default only markdown.
+	Formats  []string `json:"formats,omitempty"`
+	Provider string   `json:"provider,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	// Fallback set to false uses only the first step of the LLM fallback
+	// chain.
+	Fallback     *bool  `json:"fallback,omitempty"`
+	CacheMode    string `json:"cacheMode,omitempty"`
+	RateLimit    string `json:"rateLimit,omitempty"`
+	IgnoreRobots bool   `json:"ignoreRobots,omitempty"`
+	// Headers are sent with every request of the page, over the rotated
+	// defaults.
+	Headers   map[string]string `json:"headers,omitempty"`
+	Cookies   []Cookie          `json:"cookies,omitempty"`
+	Viewport  *Viewport         `json:"viewport,omitempty"`
+	UserAgent string            `json:"userAgent,omitempty"`
+	// Screenshot and PDF capture the rendered page. Captures always come
+	// from a fresh render, not the HTML cache.
+	Screenshot *ScreenshotOptions `json:"screenshot,omitempty"`
+	PDF        *PDFOptions        `json:"pdf,omitempty"`
+	// Actions run in order on the loaded page before its content is read.
+	Actions []Action `json:"actions,omitempty"`
+	// Paginate loads more of the page after the actions, by scrolling,
+	// clicking "load more" or following next-page links.
+	Paginate *PaginateOptions `json:"paginate,omitempty"`
+}
+
+// Cookie is set in the browser before the page is loaded. Domain and Path
+// default to the page's host and "/".
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+}
+
+type Viewport struct {
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"deviceScaleFactor,omitempty"`
+	Mobile            bool    `json:"mobile,omitempty"`
+}
+
+type ScreenshotOptions struct {
+	// FullPage captures the whole page instead of the viewport.
+	FullPage bool   `json:"fullPage,omitempty"`
+	Format   string `json:"format,omitempty"`
+	// Quality is the JPEG quality from 1 to 100.
+	Quality int `json:"quality,omitempty"`
+}
+
+type PDFOptions struct {
+	Landscape       bool   `json:"landscape,omitempty"`
+	PrintBackground bool   `json:"printBackground,omitempty"`
+	PaperSize       string `json:"paperSize,omitempty"`
+}
+
+const defaultJPEGQuality = 80
+
+var outputFormats = []string{OutputMarkdown, OutputText, OutputHTML, OutputJSON}
+
+var defaultViewport = Viewport{Width: 1920, Height: 1080, DeviceScaleFactor: 1}
+
+// FieldError is one invalid option.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// OptionsError lists every invalid option of a request. It wraps
+// errors.ErrInvalidParams.
+type OptionsError struct {
+	Fields []FieldError
+}
+
+func (e *OptionsError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Field + ": " + field.Message
+	}
+	return fmt.Sprintf("%v: %s", errors.ErrInvalidParams, strings.Join(problems, "; "))
+}
+
+func (e *OptionsError) Unwrap() error {
+	return errors.ErrInvalidParams
+}
+
+func (e *OptionsError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *OptionsError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// optionNames are the JSON names of the ScrapeOptions fields.
+var optionNames = jsonNames(reflect.TypeOf(ScrapeOptions{}))
+
+func jsonNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	return names
+}
+
+// jsonField returns the field of the struct type t with the JSON name.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if tagged, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); tagged == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// UnmarshalJSON decodes and validates the options strictly: unknown names,
+// including ones that differ from an option only in case, values of the
+// wrong type and invalid values are reported together as an OptionsError.
+// Names inside nested options, such as viewport or actions, are checked
+// the same way.
+func (o *ScrapeOptions) UnmarshalJSON(data []byte) error {
+	invalid := &OptionsError{}
+	if string(data) == "null" {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		invalid.add("params", "must be a JSON object")
+		return invalid
+	}
+
+	optionsType := reflect.TypeOf(ScrapeOptions{})
+	type plain ScrapeOptions
+	var opts plain
+	mistyped := make(map[string]bool)
+	// unknownIn are the nested options with unknown names. Their values
+	// are not validated, since a misspelt name leaves its field at zero.
+	var unknownIn []string
+	for _, name := range sortedNames(fields) {
+		field, ok := jsonField(optionsType, name)
+		if !ok {
+			addUnknown(invalid, name, name, optionNames)
+			continue
+		}
+		unknownIn = append(unknownIn, unknownNames(invalid, name, fields[name], field.Type)...)
+
+		// Options are decoded one at a time so that every bad value is
+		// reported, not only the first.
+		single, _ := json.Marshal(map[string]json.RawMessage{name: fields[name]})
+		if err := json.Unmarshal(single, &opts); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.AsType(err, &typeErr) {
+				invalid.add(typeErr.Field, "must be %s", jsonKind(typeErr.Type))
+				mistyped[typeErr.Field] = true
+			} else {
+				invalid.add(name, "%v", err)
+			}
+		}
+	}
+
+	// Mistyped options are left at zero, which is not worth reporting again.
+	decoded := ScrapeOptions(opts)
+	values := &OptionsError{}
+	decoded.validate(values)
+	for _, field := range values.Fields {
+		if !mistyped[field.Field] && !within(field.Field, unknownIn) {
+			invalid.Fields = append(invalid.Fields, field)
+		}
+	}
+	if err := invalid.err(); err != nil {
+		return err
+	}
+	*o = decoded
+	return nil
+}
+
+// unknownNames reports the names in raw, at any depth, that are not fields
+// of t, and returns the paths of the objects they were found in. Values
+// that do not have the shape of t are left for decoding to report.
+func unknownNames(invalid *OptionsError, path string, raw json.RawMessage, t reflect.Type) []string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return unknownNames(invalid, path, raw, t.Elem())
+	case reflect.Slice:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return nil
+		}
+		var found []string
+		for i, item := range items {
+			found = append(found, unknownNames(invalid, fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+		return found
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(raw, &fields) != nil {
+			return nil
+		}
+		var found []string
+		unknown := false
+		for _, name := range sortedNames(fields) {
+			field, ok := jsonField(t, name)
+			if !ok {
+				addUnknown(invalid, path+"."+name, name, jsonNames(t))
+				unknown = true
+				continue
+			}
+			found = append(found, unknownNames(invalid, path+"."+name, fields[name], field.Type)...)
+		}
+		if unknown {
+			found = append(found, path)
+		}
+		return found
+	}
+	return nil
+}
+
+func addUnknown(invalid *OptionsError, field, name string, known []string) {
+	if suggestion := suggestName(name, known); suggestion != "" {
+		invalid.add(field, "unknown option, did you mean %s?", suggestion)
+	} else {
+		invalid.add(field, "unknown option")
+	}
+}
+
+func sortedNames(fields map[string]json.RawMessage) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// within reports whether field is one of paths or inside one of them.
+func within(field string, paths []string) bool {
+	for _, path := range paths {
+		if field == path || strings.HasPrefix(field, path+".") || strings.HasPrefix(field, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestName returns the name of known that name differs from only in
+// case or separators, e.g. waitTime for "waittime" or "wait_time", or else
+// the closest one within two typos, e.g. timeout for "timout".
+func suggestName(name string, known []string) string {
+	squash := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+	best, bestDistance := "", 3
+	for _, k := range known {
+		d := editDistance(squash(name), squash(k))
+		if d == 0 {
+			return k
+		}
+		if d < bestDistance && d < len(k)/2 {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// editDistance counts the characters inserted, deleted, replaced or
+// swapped with their neighbour to turn a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// jsonKind describes a Go type the way it appears in JSON.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonKind(t.Elem())
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "an array"
+	}
+	return "an object"
+}
+
+// Validate checks the values of the options and reports every invalid one
+// in an OptionsError.
+func (o *ScrapeOptions) Validate() error {
+	invalid := &OptionsError{}
+	o.validate(invalid)
+	return invalid.err()
+}
+
+func (o *ScrapeOptions) validate(invalid *OptionsError) {
+	if o.WaitTime != nil && (*o.WaitTime < 0 || *o.WaitTime > maxWaitTime) {
+		invalid.add("waitTime", "must be between 0 and %d milliseconds", maxWaitTime)
+	}
+	for i, selector := range o.Selectors {
+		if strings.TrimSpace(selector) == "" {
+			invalid.add(fmt.Sprintf("selectors[%d]", i), "must not be empty")
+		}
+	}
+	checkEnum(invalid, "converter", o.Converter, ConverterNative, ConverterLLM, ConverterHybrid)
+	checkEnum(invalid, "outputFormat", o.OutputFormat, outputFormats...)
+	for i, format := range o.Formats {
+		checkEnum(invalid, fmt.Sprintf("formats[%d]", i), format, outputFormats...)
+	}
+	if o.OutputFormat != "" && len(o.Formats) > 0 {
+		invalid.add("outputFormat", "cannot be combined with formats")
+	}
+	checkEnum(invalid, "cacheMode", o.CacheMode, CacheModeUse, CacheModeRefresh, CacheModeBypass)
+	checkEnum(invalid, "rateLimit", o.RateLimit, RateLimitWait, RateLimitFail)
+
+	headerNames := make([]string, 0, len(o.Headers))
+	for name := range o.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		value := o.Headers[name]
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			invalid.add("headers", "%q is not a valid header name", name)
+		} else if strings.ContainsAny(value, "\r\n") {
+			invalid.add("headers."+name, "must not contain line breaks")
+		}
+	}
+	for i, cookie := range o.Cookies {
+		if cookie.Name == "" {
+			invalid.add(fmt.Sprintf("cookies[%d].name", i), "is required")
+		}
+	}
+	if v := o.Viewport; v != nil {
+		if v.Width < 1 || v.Width > maxViewportWidth {
+			invalid.add("viewport.width", "must be between 1 and %d", maxViewportWidth)
+		}
+		if v.Height < 1 || v.Height > maxViewportHeight {
+			invalid.add("viewport.height", "must be between 1 and %d", maxViewportHeight)
+		}
+		if v.DeviceScaleFactor < 0 || v.DeviceScaleFactor > maxScaleFactor {
+			invalid.add("viewport.deviceScaleFactor", "must be between 0 and %d", maxScaleFactor)
+		}
+	}
+	if shot := o.Screenshot; shot != nil {
+		checkEnum(invalid, "screenshot.format", shot.Format, ScreenshotPNG, ScreenshotJPEG)
+		if shot.Quality < 0 || shot.Quality > 100 {
+			invalid.add("screenshot.quality", "must be between 1 and 100")
+		} else if shot.Quality > 0 && shot.Format != ScreenshotJPEG {
+			invalid.add("screenshot.quality", "only applies to jpeg")
+		}
+	}
+	if o.PDF != nil {
+		checkEnum(invalid, "pdf.paperSize", o.PDF.PaperSize, PaperLetter, PaperLegal, PaperA4)
+	}
+	validateActions(invalid, o.Actions)
+	validatePaginate(invalid, o.Paginate)
+}
+
+func checkEnum(invalid *OptionsError, field, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	invalid.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// withDefaults returns the options with every unset field filled from cfg
+// or the built-in defaults.
+func (o ScrapeOptions) withDefaults(cfg *config.Config) ScrapeOptions {
+	if o.WaitTime == nil {
+		wait := cfg.CloudflareWaitMS
+		o.WaitTime = &wait
+	}
+	if o.BypassCloudflare == nil {
+		bypass := true
+		o.BypassCloudflare = &bypass
+	}
+	if o.Converter == "" {
+		o.Converter = cfg.Converter
+	}
+	if len(o.Formats) == 0 {
+		if o.OutputFormat == "" {
+			o.OutputFormat = OutputMarkdown
+		}
+		o.Formats = []string{o.OutputFormat}
+	}
+	if o.Fallback == nil {
+		fallback := true
+		o.Fallback = &fallback
+	}
+	if o.CacheMode == "" {
+		o.CacheMode = CacheModeUse
+	}
+	if o.RateLimit == "" {
+		o.RateLimit = RateLimitWait
+	}
+	if o.Viewport == nil {
+		viewport := defaultViewport
+		o.Viewport = &viewport
+	} else if o.Viewport.DeviceScaleFactor == 0 {
+		viewport := *o.Viewport
+		viewport.DeviceScaleFactor = 1
+		o.Viewport = &viewport
+	}
+	if o.Screenshot != nil {
+		shot := *o.Screenshot
+		if shot.Format == "" {
+			shot.Format = ScreenshotPNG
+		}
+		if shot.Format == ScreenshotJPEG && shot.Quality == 0 {
+			shot.Quality = defaultJPEGQuality
+		}
+		o.Screenshot = &shot
+	}
+	if o.PDF != nil && o.PDF.PaperSize == "" {
+		pdf := *o.PDF
+		pdf.PaperSize = PaperLetter
+		o.PDF = &pdf
+	}
+	if o.Paginate != nil {
+		paginate := o.Paginate.withDefaults()
+		o.Paginate = &paginate
+	}
+	return o
+}
+
+// wants reports whether format is one of the requested formats. opts must
+// have their defaults applied.
+func (o *ScrapeOptions) wants(format string) bool {
+	for _, f := range o.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveOptions validates opts and fills in the defaults.
+func (s *ScraperService) resolveOptions(opts ScrapeOptions) (ScrapeOptions, error) {
+	if err := opts.Validate(); err != nil {
+		return opts, err
+	}
+	return opts.withDefaults(s.config), nil
+}
@@ -0,0 +1,296 @@
+package scraper
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+// fieldErrors returns the problems err reports as "field: message".
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var invalid *OptionsError
+	if !errors.AsType(err, &invalid) {
+		t.Fatalf("error = %v, want an OptionsError", err)
+	}
+	if !errors.IsType(err, errors.ErrInvalidParams) {
+		t.Errorf("error does not wrap ErrInvalidParams")
+	}
+	problems := make([]string, len(invalid.Fields))
+	for i, field := range invalid.Fields {
+		problems[i] = field.Field + ": " + field.Message
+	}
+	return problems
+}
+
+func TestScrapeOptionsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{"empty", `{}`, nil},
+		{"null", `null`, nil},
+		{"valid", `{"waitTime":100,"formats":["markdown","html"],"viewport":{"width":800,"height":600},"screenshot":{"fullPage":true},"actions":[{"type":"click","selector":"a","timeout":5}]}`, nil},
+		{"not an object", `[1]`, []string{"params: must be a JSON object"}},
+		{"unknown option", `{"wait":1}`, []string{"wait: unknown option"}},
+		{"option in the wrong case", `{"waittime":1}`, []string{"waittime: unknown option, did you mean waitTime?"}},
+		{"option with separators", `{"cache_mode":"use"}`, []string{"cache_mode: unknown option, did you mean cacheMode?"}},
+		{"wrong type", `{"waitTime":"1"}`, []string{"waitTime: must be a number"}},
+		{"invalid value", `{"waitTime":-1}`, []string{"waitTime: must be between 0 and 120000 milliseconds"}},
+		{
+			"every problem",
+			`{"converter":"magic","formats":["pdf"],"selectors":[" "],"bogus":true}`,
+			[]string{
+				"bogus: unknown option",
+				"selectors[0]: must not be empty",
+				"converter: must be one of native, llm, hybrid",
+				"formats[0]: must be one of markdown, text, html, json",
+			},
+		},
+		{"nested option in the wrong case", `{"screenshot":{"fullpage":true}}`, []string{"screenshot.fullpage: unknown option, did you mean fullPage?"}},
+		{"unknown action option", `{"actions":[{"type":"click","selector":"a","timout":5}]}`, []string{"actions[0].timout: unknown option, did you mean timeout?"}},
+		{"unknown viewport option", `{"viewport":{"widht":100}}`, []string{"viewport.widht: unknown option, did you mean width?"}},
+		{"unknown cookie option", `{"cookies":[{"name":"a","value":"b"},{"name":"c","http_only":true}]}`, []string{"cookies[1].http_only: unknown option, did you mean httpOnly?"}},
+		{"unknown pdf option", `{"pdf":{"paper":"a4"}}`, []string{"pdf.paper: unknown option"}},
+		{"unknown paginate option", `{"paginate":{"mode":"scroll","max_pages":3}}`, []string{"paginate.max_pages: unknown option, did you mean maxPages?"}},
+		{
+			"other actions are still validated",
+			`{"actions":[{"type":"wait","milisecond":5},{"type":"click"}]}`,
+			[]string{
+				"actions[0].milisecond: unknown option, did you mean milliseconds?",
+				"actions[1].selector: is required for click",
+			},
+		},
+		{"nested wrong type", `{"viewport":{"width":"wide","height":600}}`, []string{"viewport.width: must be a number"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts ScrapeOptions
+			got := fieldErrors(t, json.Unmarshal([]byte(tt.json), &opts))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrapeOptionsUnmarshalJSONKeepsOptions(t *testing.T) {
+	var opts ScrapeOptions
+	if err := json.Unmarshal([]byte(`{"waitTime":100,"viewport":{"width":800,"height":600}}`), &opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.WaitTime == nil || *opts.WaitTime != 100 {
+		t.Errorf("WaitTime = %v, want 100", opts.WaitTime)
+	}
+	if opts.Viewport == nil || *opts.Viewport != (Viewport{Width: 800, Height: 600}) {
+		t.Errorf("Viewport = %+v, want 800x600", opts.Viewport)
+	}
+
+	before := opts
+	if err := json.Unmarshal([]byte(`{"waitTime":-1}`), &opts); err == nil {
+		t.Fatal("invalid options were accepted")
+	}
+	if !reflect.DeepEqual(opts, before) {
+		t.Errorf("invalid options changed the value to %+v", opts)
+	}
+}
+
+func TestScrapeOptionsValidate(t *testing.T) {
+	intp := func(n int) *int { return &n }
+
+	tests := []struct {
+		name string
+		opts ScrapeOptions
+		want []string
+	}{
+		{"zero", ScrapeOptions{}, nil},
+		{"wait time", ScrapeOptions{WaitTime: intp(maxWaitTime + 1)}, []string{"waitTime: must be between 0 and 120000 milliseconds"}},
+		{"format and formats", ScrapeOptions{OutputFormat: OutputHTML, Formats: []string{OutputText}}, []string{"outputFormat: cannot be combined with formats"}},
+		{"cache mode", ScrapeOptions{CacheMode: "sometimes"}, []string{"cacheMode: must be one of use, refresh, bypass"}},
+		{"rate limit", ScrapeOptions{RateLimit: "drop"}, []string{"rateLimit: must be one of wait, fail"}},
+		{"header name", ScrapeOptions{Headers: map[string]string{"Bad Name": "x"}}, []string{`headers: "Bad Name" is not a valid header name`}},
+		{"header value", ScrapeOptions{Headers: map[string]string{"X-A": "a\r\nb"}}, []string{"headers.X-A: must not contain line breaks"}},
+		{"cookie name", ScrapeOptions{Cookies: []Cookie{{Value: "v"}}}, []string{"cookies[0].name: is required"}},
+		{
+			"viewport",
+			ScrapeOptions{Viewport: &Viewport{Width: 0, Height: maxViewportHeight + 1, DeviceScaleFactor: 5}},
+			[]string{
+				"viewport.width: must be between 1 and 7680",
+				"viewport.height: must be between 1 and 4320",
+				"viewport.deviceScaleFactor: must be between 0 and 4",
+			},
+		},
+		{"screenshot format", ScrapeOptions{Screenshot: &ScreenshotOptions{Format: "gif"}}, []string{"screenshot.format: must be one of png, jpeg"}},
+		{"screenshot quality", ScrapeOptions{Screenshot: &ScreenshotOptions{Format: ScreenshotJPEG, Quality: 101}}, []string{"screenshot.quality: must be between 1 and 100"}},
+		{"quality of a png", ScrapeOptions{Screenshot: &ScreenshotOptions{Quality: 50}}, []string{"screenshot.quality: only applies to jpeg"}},
+		{"paper size", ScrapeOptions{PDF: &PDFOptions{PaperSize: "a3"}}, []string{"pdf.paperSize: must be one of letter, legal, a4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldErrors(t, tt.opts.Validate())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		want    []string
+	}{
+		{
+			"valid",
+			[]Action{
+				{Type: ActionClick, Selector: "a"},
+				{Type: ActionType, Selector: "input", Text: "hi"},
+				{Type: ActionPress, Key: "Enter"},
+				{Type: ActionPress, Key: "x"},
+				{Type: ActionScroll},
+				{Type: ActionWait, NetworkIdle: true},
+				{Type: ActionSelect, Selector: "select", Value: "1"},
+				{Type: ActionHover, Selector: "a"},
+				{Type: ActionEval, Script: "1"},
+			},
+			nil,
+		},
+		{"unknown type", []Action{{Type: "jump"}}, []string{"actions[0].type: must be one of " + strings.Join(actionTypes, ", ")}},
+		{"missing selector", []Action{{Type: ActionHover}}, []string{"actions[0].selector: is required for hover"}},
+		{"missing text", []Action{{Type: ActionType, Selector: "input"}}, []string{"actions[0].text: is required for type"}},
+		{"missing value", []Action{{Type: ActionSelect, Selector: "select"}}, []string{"actions[0].value: is required for select"}},
+		{"missing script", []Action{{Type: ActionEval}}, []string{"actions[0].script: is required for eval"}},
+		{"unknown key", []Action{{Type: ActionPress, Key: "Hyper"}}, []string{"actions[0].key: must be a single character or one of Enter, Tab, Escape, Backspace, Delete, Space, ArrowUp, ArrowDown, ArrowLeft, ArrowRight, Home, End, PageUp, PageDown"}},
+		{"wait for nothing", []Action{{Type: ActionWait}}, []string{"actions[0]: wait needs exactly one of selector, networkIdle or milliseconds"}},
+		{"wait for two things", []Action{{Type: ActionWait, Selector: "a", Milliseconds: 5}}, []string{"actions[0]: wait needs exactly one of selector, networkIdle or milliseconds"}},
+		{"wait too long", []Action{{Type: ActionWait, Milliseconds: maxWaitTime + 1}}, []string{"actions[0].milliseconds: must be between 1 and 120000"}},
+		{"timeout", []Action{{Type: ActionScroll, Timeout: -1}}, []string{"actions[0].timeout: must be between 0 and 60000 milliseconds"}},
+		{"too many", make([]Action, maxActions+1), []string{"actions: must have at most 50 entries"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := &OptionsError{}
+			validateActions(invalid, tt.actions)
+			if got := fieldErrors(t, invalid.err()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		paginate *PaginateOptions
+		want     []string
+	}{
+		{"unset", nil, nil},
+		{"scroll", &PaginateOptions{Mode: PaginateScroll}, nil},
+		{"next", &PaginateOptions{Mode: PaginateNext, ItemSelector: "li", MaxItems: 10}, nil},
+		{"no mode", &PaginateOptions{}, []string{"paginate.mode: is required"}},
+		{"unknown mode", &PaginateOptions{Mode: "infinite"}, []string{"paginate.mode: must be one of scroll, click, next"}},
+		{"click without selector", &PaginateOptions{Mode: PaginateClick}, []string{"paginate.selector: is required for click"}},
+		{"max items without item selector", &PaginateOptions{Mode: PaginateScroll, MaxItems: 10}, []string{"paginate.maxItems: needs itemSelector to count items"}},
+		{"negative max items", &PaginateOptions{Mode: PaginateScroll, MaxItems: -1}, []string{"paginate.maxItems: must not be negative"}},
+		{
+			"limits",
+			&PaginateOptions{Mode: PaginateScroll, MaxPages: maxPaginatePages + 1, TimeBudget: -1, IdleTime: maxIdleTime + 1},
+			[]string{
+				"paginate.maxPages: must be between 1 and 100",
+				"paginate.timeBudget: must be between 1 and 120000 milliseconds",
+				"paginate.idleTime: must be between 1 and 30000 milliseconds",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := &OptionsError{}
+			validatePaginate(invalid, tt.paginate)
+			if got := fieldErrors(t, invalid.err()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	cfg := &config.Config{CloudflareWaitMS: 3000, Converter: ConverterNative}
+
+	got := ScrapeOptions{}.withDefaults(cfg)
+	if *got.WaitTime != 3000 || !*got.BypassCloudflare || !*got.Fallback {
+		t.Errorf("WaitTime, BypassCloudflare, Fallback = %d, %v, %v", *got.WaitTime, *got.BypassCloudflare, *got.Fallback)
+	}
+	if got.Converter != ConverterNative || got.CacheMode != CacheModeUse || got.RateLimit != RateLimitWait {
+		t.Errorf("Converter, CacheMode, RateLimit = %q, %q, %q", got.Converter, got.CacheMode, got.RateLimit)
+	}
+	if !reflect.DeepEqual(got.Formats, []string{OutputMarkdown}) {
+		t.Errorf("Formats = %q, want markdown", got.Formats)
+	}
+	if *got.Viewport != defaultViewport {
+		t.Errorf("Viewport = %+v, want %+v", *got.Viewport, defaultViewport)
+	}
+	if got.Screenshot != nil || got.PDF != nil || got.Paginate != nil {
+		t.Errorf("captures or pagination set without being asked for")
+	}
+
+	viewport := &Viewport{Width: 800, Height: 600}
+	opts := ScrapeOptions{
+		OutputFormat: OutputText,
+		Viewport:     viewport,
+		Screenshot:   &ScreenshotOptions{Format: ScreenshotJPEG},
+		PDF:          &PDFOptions{},
+		Paginate:     &PaginateOptions{Mode: PaginateNext},
+	}.withDefaults(cfg)
+	if !reflect.DeepEqual(opts.Formats, []string{OutputText}) {
+		t.Errorf("Formats = %q, want text", opts.Formats)
+	}
+	if opts.Viewport.DeviceScaleFactor != 1 || viewport.DeviceScaleFactor != 0 {
+		t.Errorf("DeviceScaleFactor = %v, caller's = %v, want 1 and 0", opts.Viewport.DeviceScaleFactor, viewport.DeviceScaleFactor)
+	}
+	if opts.Screenshot.Quality != defaultJPEGQuality {
+		t.Errorf("Quality = %d, want %d", opts.Screenshot.Quality, defaultJPEGQuality)
+	}
+	if opts.PDF.PaperSize != PaperLetter {
+		t.Errorf("PaperSize = %q, want %q", opts.PDF.PaperSize, PaperLetter)
+	}
+	if opts.Paginate.Selector != defaultNextSelector || opts.Paginate.MaxPages != defaultMaxPages {
+		t.Errorf("Paginate = %+v, want the defaults", opts.Paginate)
+	}
+}
+
+func TestSuggestName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"waittime", "waitTime"},
+		{"wait_time", "waitTime"},
+		{"WAIT-TIME", "waitTime"},
+		{"waitTim", "waitTime"},
+		{"wiatTime", "waitTime"},
+		{"formatz", "formats"},
+		{"wait", ""},
+		{"xyz", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestName(tt.name, optionNames); got != tt.want {
+				t.Errorf("suggestName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"golang.org/x/net/html"
+)
+
+// Pagination modes for the "paginate" param.
+const (
+	// PaginateScroll scrolls to the bottom until no new content loads.
+	PaginateScroll = "scroll"
+	// PaginateClick clicks a "load more" button until it is gone or no new
+	// content loads.
+	PaginateClick = "click"
+	// PaginateNext follows next-page links and merges the pages into the
+	// first one.
+	PaginateNext = "next"
+)
+
+// Reasons pagination stopped.
+const (
+	StopNoNewContent = "no_new_content"
+	// StopNoMore means the "load more" button or next-page link was not
+	// found.
+	StopNoMore     = "no_more"
+	StopMaxItems   = "max_items"
+	StopMaxPages   = "max_pages"
+	StopTimeBudget = "time_budget"
+	StopError      = "error"
+)
+
+const (
+	defaultNextSelector = `a[rel~="next"], link[rel~="next"]`
+	defaultMaxPages     = 20
+	maxPaginatePages    = 100
+	defaultTimeBudget   = 30000
+	maxTimeBudget       = 120000
+	defaultIdleTime     = 2000
+	maxIdleTime         = 30000
+
+	// pagesAttr marks the element of the first page that pages followed in
+	// next mode are appended to.
+	pagesAttr = "data-scarab-pages"
+)
+
+// PaginateOptions harvest content that is loaded in batches. Times are in
+// milliseconds.
+type PaginateOptions struct {
+	Mode string `json:"mode"`
+	// Selector is the "load more" button in click mode and the next-page
+	// link in next mode, where it defaults to rel=next links.
+	Selector string `json:"selector,omitempty"`
+	// ItemSelector matches the items of the listing. It makes new content
+	// and MaxItems countable, and in next mode appends only the items of
+	// later pages next to those of the first.
+	ItemSelector string `json:"itemSelector,omitempty"`
+	MaxItems     int    `json:"maxItems,omitempty"`
+	// MaxPages bounds the loads, counting the first page.
+	MaxPages   int `json:"maxPages,omitempty"`
+	TimeBudget int `json:"timeBudget,omitempty"`
+	// IdleTime is how long to wait for new content after each scroll,
+	// click or page load.
+	IdleTime int `json:"idleTime,omitempty"`
+}
+
+// PaginationResult reports what pagination loaded and why it stopped.
+type PaginationResult struct {
+	Mode string `json:"mode"`
+	// Pages counts the first page and every load that brought new content.
+	Pages int `json:"pages"`
+	// Items is the number of items matched, when ItemSelector is set.
+	Items int `json:"items,omitempty"`
+	// URLs are the pages followed in next mode.
+	URLs    []string `json:"urls,omitempty"`
+	Stopped string   `json:"stopped"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func validatePaginate(invalid *OptionsError, p *PaginateOptions) {
+	if p == nil {
+		return
+	}
+	if p.Mode == "" {
+		invalid.add("paginate.mode", "is required")
+	}
+	checkEnum(invalid, "paginate.mode", p.Mode, PaginateScroll, PaginateClick, PaginateNext)
+	if p.Mode == PaginateClick && strings.TrimSpace(p.Selector) == "" {
+		invalid.add("paginate.selector", "is required for click")
+	}
+	if p.MaxItems < 0 {
+		invalid.add("paginate.maxItems", "must not be negative")
+	} else if p.MaxItems > 0 && strings.TrimSpace(p.ItemSelector) == "" {
+		invalid.add("paginate.maxItems", "needs itemSelector to count items")
+	}
+	if p.MaxPages < 0 || p.MaxPages > maxPaginatePages {
+		invalid.add("paginate.maxPages", "must be between 1 and %d", maxPaginatePages)
+	}
+	if p.TimeBudget < 0 || p.TimeBudget > maxTimeBudget {
+		invalid.add("paginate.timeBudget", "must be between 1 and %d milliseconds", maxTimeBudget)
+	}
+	if p.IdleTime < 0 || p.IdleTime > maxIdleTime {
+		invalid.add("paginate.idleTime", "must be between 1 and %d milliseconds", maxIdleTime)
+	}
+}
+
+func (p PaginateOptions) withDefaults() PaginateOptions {
+	if p.Mode == PaginateNext && p.Selector == "" {
+		p.Selector = defaultNextSelector
+	}
+	if p.MaxPages == 0 {
+		p.MaxPages = defaultMaxPages
+	}
+	if p.TimeBudget == 0 {
+		p.TimeBudget = defaultTimeBudget
+	}
+	if p.IdleTime == 0 {
+		p.IdleTime = defaultIdleTime
+	}
+	return p
+}
+
+func milliseconds(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// pageSize is what loading more content grows.
+type pageSize struct {
+	Items  int `json:"items"`
+	Height int `json:"height"`
+	Length int `json:"length"`
+}
+
+func (s pageSize) grewFrom(prev pageSize, counted bool) bool {
+	if counted {
+		return s.Items > prev.Items
+	}
+	return s.Height > prev.Height || s.Length > prev.Length
+}
+
+func measure(page *rod.Page, itemSelector string) (pageSize, error) {
+	var size pageSize
+	res, err := page.Eval(`(sel) => ({
+		items: sel ? document.querySelectorAll(sel).length : 0,
+		height: document.documentElement.scrollHeight,
+		length: document.body ? document.body.innerHTML.length : 0,
+	})`, itemSelector)
+	if err != nil {
+		return size, err
+	}
+	err = res.Value.Unmarshal(&size)
+	return size, err
+}
+
+// stop records why pagination ended after err, telling the time budget
+// running out apart from other failures.
+func (r *PaginationResult) stop(budget context.Context, err error) {
+	if budget.Err() != nil {
+		r.Stopped = StopTimeBudget
+		return
+	}
+	r.Stopped = StopError
+	r.Error = err.Error()
+	fmt.Printf("Warning: pagination stopped: %v\n", err)
+}
+
+// loadMore scrolls or clicks the "load more" button until no new content
+// appears, leaving everything loaded in the page.
+func loadMore(ctx context.Context, page *rod.Page, opts *PaginateOptions) *PaginationResult {
+	budget, cancel := context.WithTimeout(ctx, milliseconds(opts.TimeBudget))
+	defer cancel()
+	page = page.Context(budget)
+	counted := opts.ItemSelector != ""
+
+	result := &PaginationResult{Mode: opts.Mode, Pages: 1}
+	last, err := measure(page, opts.ItemSelector)
+	if err != nil {
+		result.stop(budget, err)
+		return result
+	}
+	result.Items = last.Items
+
+	for {
+		if opts.MaxItems > 0 && result.Items >= opts.MaxItems {
+			result.Stopped = StopMaxItems
+			return result
+		}
+		if result.Pages >= opts.MaxPages {
+			result.Stopped = StopMaxPages
+			return result
+		}
+
+		more := true
+		err := rod.Try(func() {
+			if opts.Mode == PaginateScroll {
+				page.MustEval(`() => window.scrollTo(0, document.documentElement.scrollHeight)`)
+				return
+			}
+			has, button, err := page.Has(opts.Selector)
+			if err != nil {
+				panic(err)
+			}
+			if !has || !button.MustVisible() {
+				more = false
+				return
+			}
+			button.MustScrollIntoView()
+			button.MustClick()
+		})
+		if err != nil {
+			result.stop(budget, err)
+			return result
+		}
+		if !more {
+			result.Stopped = StopNoMore
+			return result
+		}
+
+		// Content arrives some time after the scroll or click; poll until it
+		// grows or IdleTime passes.
+		grown := false
+		idle := time.Now().Add(milliseconds(opts.IdleTime))
+		for !grown && time.Now().Before(idle) {
+			if err := sleepContext(budget, 250*time.Millisecond); err != nil {
+				result.stop(budget, err)
+				return result
+			}
+			size, err := measure(page, opts.ItemSelector)
+			if err != nil {
+				result.stop(budget, err)
+				return result
+			}
+			if size.grewFrom(last, counted) {
+				last, grown = size, true
+			}
+		}
+		if !grown {
+			result.Stopped = StopNoNewContent
+			return result
+		}
+		result.Pages++
+		result.Items = last.Items
+	}
+}
+
+// markPagesContainer marks where followNext appends later pages: the
+// parent of the last item, or the body.
+func markPagesContainer(page *rod.Page, itemSelector string) error {
+	_, err := page.Eval(`(sel, attr) => {
+		const items = sel ? document.querySelectorAll(sel) : [];
+		const container = items.length ? items[items.length - 1].parentElement : document.body;
+		if (container) container.setAttribute(attr, '');
+	}`, itemSelector, pagesAttr)
+	return err
+}
+
+// followNext loads the pages linked as next from the first page, whose HTML
+// is first, and returns that HTML with the items of the later pages, or
+// their whole body without ItemSelector, appended. Only pages on the same
+// host are followed, each after check allows it. The tab is left on the
+// last page.
+func followNext(ctx context.Context, page *rod.Page, opts *PaginateOptions, first string, pageURL string, check func(context.Context, string) error) (string, *PaginationResult) {
+	budget, cancel := context.WithTimeout(ctx, milliseconds(opts.TimeBudget))
+	defer cancel()
+	page = page.Context(budget)
+
+	result := &PaginationResult{Mode: PaginateNext, Pages: 1}
+	if size, err := measure(page, opts.ItemSelector); err == nil {
+		result.Items = size.Items
+	}
+
+	current, _ := neturl.Parse(pageURL)
+	visited := map[string]bool{pageURL: true}
+	var fragments []string
+
+	for {
+		if opts.MaxItems > 0 && result.Items >= opts.MaxItems {
+			result.Stopped = StopMaxItems
+			break
+		}
+		if result.Pages >= opts.MaxPages {
+			result.Stopped = StopMaxPages
+			break
+		}
+
+		res, err := page.Eval(`(sel) => {
+			const link = document.querySelector(sel);
+			return link ? link.href || link.getAttribute('href') || '' : '';
+		}`, opts.Selector)
+		if err != nil {
+			result.stop(budget, err)
+			break
+		}
+		href := res.Value.Str()
+		next, err := neturl.Parse(href)
+		if err != nil || href == "" || current == nil || (next.Scheme != "http" && next.Scheme != "https") ||
+			!strings.EqualFold(next.Hostname(), current.Hostname()) {
+			result.Stopped = StopNoMore
+			break
+		}
+		next.Fragment = ""
+		if visited[next.String()] {
+			result.Stopped = StopNoMore
+			break
+		}
+		visited[next.String()] = true
+
+		if check != nil {
+			if err := check(budget, next.String()); err != nil {
+				result.stop(budget, err)
+				break
+			}
+		}
+
+		var found []string
+		err = rod.Try(func() {
+			waitLoad := page.WaitNavigation(proto.PageLifecycleEventNameLoad)
+			page.MustNavigate(next.String())
+			waitLoad()
+			page.Timeout(milliseconds(opts.IdleTime)).WaitRequestIdle(500*time.Millisecond, nil, nil, nil)()
+
+			items := page.MustEval(`(sel) => sel
+				? Array.from(document.querySelectorAll(sel), el => el.outerHTML)
+				: [document.body ? document.body.innerHTML : '']`, opts.ItemSelector)
+			if err := items.Unmarshal(&found); err != nil {
+				panic(err)
+			}
+		})
+		if err != nil {
+			result.stop(budget, err)
+			break
+		}
+		if len(found) == 0 || (opts.ItemSelector == "" && strings.TrimSpace(found[0]) == "") {
+			result.Stopped = StopNoNewContent
+			break
+		}
+
+		if opts.ItemSelector != "" {
+			if opts.MaxItems > 0 && result.Items+len(found) > opts.MaxItems {
+				found = found[:opts.MaxItems-result.Items]
+			}
+			result.Items += len(found)
+		}
+		fragments = append(fragments, found...)
+		result.Pages++
+		result.URLs = append(result.URLs, next.String())
+		current = next
+	}
+
+	return mergePages(first, fragments), result
+}
+
+// mergePages appends the fragments to the element of document marked with
+// pagesAttr, or to its body, and removes the mark.
+func mergePages(document string, fragments []string) string {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return document
+	}
+
+	var container, body *html.Node
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.Data == "body" && body == nil {
+				body = n
+			}
+			for i, a := range n.Attr {
+				if a.Key == pagesAttr && container == nil {
+					container = n
+					n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+					break
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			find(child)
+		}
+	}
+	find(doc)
+	if container == nil {
+		container = body
+	}
+	if container == nil {
+		return document
+	}
+
+	for _, fragment := range fragments {
+		nodes, err := html.ParseFragment(strings.NewReader(fragment), container)
+		if err != nil {
+			continue
+		}
+		for _, node := range nodes {
+			container.AppendChild(node)
+		}
+	}
+
+	var sb strings.Builder
+	if err := html.Render(&sb, doc); err != nil {
+		return document
+	}
+	return sb.String()
+}
@@ -0,0 +1,172 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+func TestPaginateOptionsWithDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		opts PaginateOptions
+		want PaginateOptions
+	}{
+		{
+			"scroll",
+			PaginateOptions{Mode: PaginateScroll},
+			PaginateOptions{Mode: PaginateScroll, MaxPages: defaultMaxPages, TimeBudget: defaultTimeBudget, IdleTime: defaultIdleTime},
+		},
+		{
+			"click keeps its selector",
+			PaginateOptions{Mode: PaginateClick, Selector: "button.more", MaxPages: 5},
+			PaginateOptions{Mode: PaginateClick, Selector: "button.more", MaxPages: 5, TimeBudget: defaultTimeBudget, IdleTime: defaultIdleTime},
+		},
+		{
+			"next defaults to rel=next links",
+			PaginateOptions{Mode: PaginateNext, TimeBudget: 1000, IdleTime: 100},
+			PaginateOptions{Mode: PaginateNext, Selector: defaultNextSelector, MaxPages: defaultMaxPages, TimeBudget: 1000, IdleTime: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.withDefaults(); got != tt.want {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageSizeGrewFrom(t *testing.T) {
+	prev := pageSize{Items: 10, Height: 1000, Length: 5000}
+
+	tests := []struct {
+		name    string
+		size    pageSize
+		counted bool
+		want    bool
+	}{
+		{"more items", pageSize{Items: 20, Height: 1000, Length: 5000}, true, true},
+		{"taller without new items", pageSize{Items: 10, Height: 2000, Length: 9000}, true, false},
+		{"taller", pageSize{Height: 2000, Length: 5000}, false, true},
+		{"longer", pageSize{Height: 1000, Length: 6000}, false, true},
+		{"unchanged", prev, false, false},
+		{"shrunk", pageSize{Items: 5, Height: 800, Length: 4000}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.size.grewFrom(prev, tt.counted); got != tt.want {
+				t.Errorf("grewFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginationResultStop(t *testing.T) {
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		budget      context.Context
+		wantStopped string
+		wantError   string
+	}{
+		{"time budget", expired, StopTimeBudget, ""},
+		{"error", context.Background(), StopError, "click failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &PaginationResult{}
+			result.stop(tt.budget, errors.New("click failed"))
+			if result.Stopped != tt.wantStopped || result.Error != tt.wantError {
+				t.Errorf("stop() = %q %q, want %q %q", result.Stopped, result.Error, tt.wantStopped, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestMergePages(t *testing.T) {
+	tests := []struct {
+		name      string
+		document  string
+		fragments []string
+		want      string
+	}{
+		{
+			"appended to the marked container",
+			`<html><head></head><body><ul ` + pagesAttr + `=""><li>1</li></ul><footer>f</footer></body></html>`,
+			[]string{"<li>2</li>", "<li>3</li><li>4</li>"},
+			`<html><head></head><body><ul><li>1</li><li>2</li><li>3</li><li>4</li></ul><footer>f</footer></body></html>`,
+		},
+		{
+			"appended to the body without a container",
+			`<html><head></head><body><p>1</p></body></html>`,
+			[]string{"<p>2</p>"},
+			`<html><head></head><body><p>1</p><p>2</p></body></html>`,
+		},
+		{
+			"table rows parsed in context",
+			`<html><head></head><body><table><tbody ` + pagesAttr + `=""><tr><td>1</td></tr></tbody></table></body></html>`,
+			[]string{"<tr><td>2</td></tr>"},
+			`<html><head></head><body><table><tbody><tr><td>1</td></tr><tr><td>2</td></tr></tbody></table></body></html>`,
+		},
+		{
+			"no fragments",
+			`<html><head></head><body><p>1</p></body></html>`,
+			nil,
+			`<html><head></head><body><p>1</p></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePages(tt.document, tt.fragments); got != tt.want {
+				t.Errorf("mergePages() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// paginatingRenderer reports loading three pages in the mode asked for.
+type paginatingRenderer struct{}
+
+func (paginatingRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
+	result := &RenderResult{HTML: "<ul><li>1</li><li>2</li><li>3</li></ul>", URL: url}
+	if p := options.Paginate; p != nil {
+		result.Pagination = &PaginationResult{Mode: p.Mode, Pages: 3, Items: 3, Stopped: StopNoMore}
+	}
+	return result, nil
+}
+
+func TestScrapePagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		paginate *PaginateOptions
+		want     *PaginationResult
+	}{
+		{"not asked for", nil, nil},
+		{"scroll", &PaginateOptions{Mode: PaginateScroll}, &PaginationResult{Mode: PaginateScroll, Pages: 3, Items: 3, Stopped: StopNoMore}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{Converter: ConverterNative})
+			s.SetRenderer(paginatingRenderer{})
+
+			result, err := s.Scrape(context.Background(), "https://example.com/", ScrapeOptions{Paginate: tt.paginate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Pagination, tt.want) {
+				t.Errorf("Pagination = %+v, want %+v", result.Pagination, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+type pooledBrowser struct {
+	browser  *rod.Browser
+	launcher *launcher.Launcher
+	active   int
+	// ready is closed once the browser has launched, or failed to with err
+	// set. The slot is reserved while it launches.
+	ready chan struct{}
+	err   error
+}
+
+// PooledPage is a page borrowed from a BrowserPool. Each page has its own
+// incognito browser context, so cookies and storage are not shared with
+// other requests. It must be handed back with Release, or Discard if the
+// page is in an unknown state.
+type PooledPage struct {
+	*rod.Page
+	incognito *rod.Browser
+	owner     *pooledBrowser
+	slot      int
+}
+
+// BrowserPool keeps a fixed number of Chromium instances and caps the number
+// of pages open across all of them. Callers that arrive while every slot is
+// taken queue until a page is released or their context is done.
+type BrowserPool struct {
+	config   *config.Config
+	slots    chan struct{}
+	mu       sync.Mutex
+	browsers []*pooledBrowser
+	closed   bool
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewBrowserPool(cfg *config.Config) *BrowserPool {
+	size := cfg.BrowserPoolSize
+	if size < 1 {
+		size = 1
+	}
+	maxConcurrency := cfg.BrowserMaxConcurrency
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	p := &BrowserPool{
+		config:   cfg,
+		slots:    make(chan struct{}, maxConcurrency),
+		browsers: make([]*pooledBrowser, size),
+		stop:     make(chan struct{}),
+	}
+
+	if cfg.BrowserHealthCheckSeconds > 0 {
+		go p.healthLoop(time.Duration(cfg.BrowserHealthCheckSeconds) * time.Second)
+	}
+
+	return p
+}
+
+func (p *BrowserPool) Acquire(ctx context.Context) (*PooledPage, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	page, err := p.checkout(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// checkout opens a page on one of the browsers. Browsers are launched and
+// pages created without holding the lock, so a slow launch only holds
+// up the callers waiting for that browser, and each of them gives up when
+// its ctx is done.
+func (p *BrowserPool) checkout(ctx context.Context) (*PooledPage, error) {
+	page, err := p.tryCheckout(ctx)
+	if err == errBrowserGone {
+		// The browser most likely crashed, or its launch was abandoned;
+		// relaunch it and try once more.
+		page, err = p.tryCheckout(ctx)
+	}
+	if err == errBrowserGone {
+		err = fmt.Errorf("failed to create page: browser is not responding")
+	}
+	return page, err
+}
+
+var errBrowserGone = fmt.Errorf("browser is gone")
+
+func (p *BrowserPool) tryCheckout(ctx context.Context) (*PooledPage, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("browser pool is closed")
+	}
+
+	slot := p.pickSlot()
+	pb, launch := p.reserveSlot(slot)
+	pb.active++
+	p.mu.Unlock()
+
+	if launch {
+		p.launch(ctx, slot, pb)
+	}
+	select {
+	case <-pb.ready:
+	case <-ctx.Done():
+	}
+	err := ctx.Err()
+	if err == nil {
+		err = pb.err
+	}
+	if err != nil {
+		p.mu.Lock()
+		pb.active--
+		p.mu.Unlock()
+		return nil, err
+	}
+
+	incognito, err := pb.browser.Incognito()
+	var page *rod.Page
+	if err == nil {
+		if page, err = incognito.Page(proto.TargetCreateTarget{URL: "about:blank"}); err != nil {
+			_ = incognito.Close()
+		}
+	}
+	if err != nil {
+		p.mu.Lock()
+		pb.active--
+		p.mu.Unlock()
+		p.remove(slot, pb)
+		return nil, errBrowserGone
+	}
+
+	return &PooledPage{Page: page, incognito: incognito, owner: pb, slot: slot}, nil
+}
+
+// pickSlot prefers an empty slot if every running browser is busy, then
+// the least busy running browser.
+func (p *BrowserPool) pickSlot() int {
+	least, empty := -1, -1
+	for i, pb := range p.browsers {
+		if pb == nil {
+			if empty == -1 {
+				empty = i
+			}
+			continue
+		}
+		if least == -1 || pb.active < p.browsers[least].active {
+			least = i
+		}
+	}
+
+	if least == -1 || (empty != -1 && p.browsers[least].active > 0) {
+		return empty
+	}
+	return least
+}
+
+// reserveSlot returns the browser in slot, reserving the slot for a new
+// one when it is empty. launch reports whether the caller must launch it.
+// p.mu must be held.
+func (p *BrowserPool) reserveSlot(slot int) (pb *pooledBrowser, launch bool) {
+	if pb := p.browsers[slot]; pb != nil {
+		return pb, false
+	}
+	pb = &pooledBrowser{ready: make(chan struct{})}
+	p.browsers[slot] = pb
+	return pb, true
+}
+
+// launch starts the browser reserved in slot and publishes it, unless the
+// slot was given up while it launched. The launch is abandoned when ctx is
+// done, and the other callers waiting for it then launch the browser again.
+func (p *BrowserPool) launch(ctx context.Context, slot int, pb *pooledBrowser) {
+	launched, err := launchBrowser(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer close(pb.ready)
+
+	if err != nil {
+		pb.err = err
+		if ctx.Err() != nil {
+			pb.err = errBrowserGone
+		}
+		if p.browsers[slot] == pb {
+			p.browsers[slot] = nil
+		}
+		return
+	}
+	if p.browsers[slot] != pb {
+		pb.err = fmt.Errorf("browser pool slot was shut down while launching")
+		go func() {
+			_ = launched.browser.Close()
+			launched.launcher.Kill()
+		}()
+		return
+	}
+	pb.browser, pb.launcher = launched.browser, launched.launcher
+}
+
+func launchBrowser(ctx context.Context) (*pooledBrowser, error) {
+	l := launcher.New().
+		Context(ctx).
+		Headless(true).
+		Set("--window-size", "1280,720")
+
+	// Uncomment if you want to use proxies
+	// proxy := r.proxyRotator.GetRandomProxy()
+	// if proxy != "" {
+	// 	l = l.Proxy(proxy)
+	// }
+
+	browserURL, err := l.Launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	browser := rod.New().Context(ctx).ControlURL(browserURL)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+
+	// The browser outlives the request that launched it.
+	return &pooledBrowser{browser: browser.Context(context.Background()), launcher: l}, nil
+}
+
+// remove gives up slot if it still holds pb and shuts pb down.
+func (p *BrowserPool) remove(slot int, pb *pooledBrowser) {
+	p.mu.Lock()
+	if p.browsers[slot] != pb {
+		p.mu.Unlock()
+		return
+	}
+	p.browsers[slot] = nil
+	p.mu.Unlock()
+
+	pb.shutdown()
+}
+
+// shutdown closes a launched browser. One still launching is shut down by
+// launch once it sees its slot was given up.
+func (pb *pooledBrowser) shutdown() {
+	select {
+	case <-pb.ready:
+	default:
+		return
+	}
+	if pb.browser == nil {
+		return
+	}
+	_ = pb.browser.Close()
+	pb.launcher.Kill()
+}
+
+// Release closes the page together with its browser context, so nothing
+// the request left behind reaches the next one.
+func (p *BrowserPool) Release(page *PooledPage) {
+	if page == nil {
+		return
+	}
+	defer func() { <-p.slots }()
+
+	p.close(page)
+}
+
+// Discard closes the page like Release, and checks that its browser is
+// still alive.
+func (p *BrowserPool) Discard(page *PooledPage) {
+	if page == nil {
+		return
+	}
+	defer func() { <-p.slots }()
+
+	if p.close(page) && !browserHealthy(page.owner) {
+		p.remove(page.slot, page.owner)
+	}
+}
+
+// close disposes of the page's browser context, which closes the page, and
+// reports whether the page's browser is still the one in its slot.
+func (p *BrowserPool) close(page *PooledPage) bool {
+	_ = page.incognito.Timeout(5 * time.Second).Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.browsers[page.slot] != page.owner {
+		// The browser was relaunched while the page was out.
+		return false
+	}
+	page.owner.active--
+	return true
+}
+
+func browserHealthy(pb *pooledBrowser) bool {
+	_, err := proto.BrowserGetVersion{}.Call(pb.browser.Timeout(5 * time.Second))
+	return err == nil
+}
+
+func (p *BrowserPool) healthLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+// checkHealth relaunches browsers that stopped responding. The checks run
+// without the lock so that they do not hold up Acquire and Release.
+func (p *BrowserPool) checkHealth() {
+	p.mu.Lock()
+	browsers := append([]*pooledBrowser(nil), p.browsers...)
+	p.mu.Unlock()
+
+	for slot, pb := range browsers {
+		if pb == nil {
+			continue
+		}
+		select {
+		case <-pb.ready:
+		default:
+			// Still launching.
+			continue
+		}
+		if pb.err != nil || browserHealthy(pb) {
+			continue
+		}
+
+		fmt.Printf("Warning: browser %d failed health check, relaunching\n", slot)
+		p.remove(slot, pb)
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		fresh, launch := p.reserveSlot(slot)
+		p.mu.Unlock()
+		if launch {
+			p.launch(context.Background(), slot, fresh)
+			if fresh.err != nil {
+				fmt.Printf("Warning: failed to relaunch browser %d: %v\n", slot, fresh.err)
+			}
+		}
+	}
+}
+
+func (p *BrowserPool) Close() error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	p.mu.Lock()
+	p.closed = true
+	browsers := append([]*pooledBrowser(nil), p.browsers...)
+	for slot := range p.browsers {
+		p.browsers[slot] = nil
+	}
+	p.mu.Unlock()
+
+	for _, pb := range browsers {
+		if pb != nil {
+			pb.shutdown()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+)
+
+func TestPickSlot(t *testing.T) {
+	running := func(active int) *pooledBrowser {
+		return &pooledBrowser{active: active}
+	}
+
+	tests := []struct {
+		name     string
+		browsers []*pooledBrowser
+		want     int
+	}{
+		{"first launch", []*pooledBrowser{nil, nil}, 0},
+		{"idle browser before launching another", []*pooledBrowser{running(0), nil}, 0},
+		{"launch when every browser is busy", []*pooledBrowser{running(1), nil}, 1},
+		{"least busy when the pool is full", []*pooledBrowser{running(3), running(1), running(2)}, 1},
+		{"first of equally busy", []*pooledBrowser{running(2), running(2)}, 0},
+		{"idle browser after an empty slot", []*pooledBrowser{nil, running(0)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &BrowserPool{browsers: tt.browsers}
+			if got := p.pickSlot(); got != tt.want {
+				t.Errorf("pickSlot() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveSlot(t *testing.T) {
+	p := NewBrowserPool(&config.Config{BrowserPoolSize: 2})
+	defer p.Close()
+
+	pb, launch := p.reserveSlot(1)
+	if !launch || p.browsers[1] != pb {
+		t.Fatalf("reserveSlot() on an empty slot = %v, launch %v", pb, launch)
+	}
+	if again, launch := p.reserveSlot(1); again != pb || launch {
+		t.Errorf("reserveSlot() on a reserved slot = %v, launch %v, want the reserved browser", again, launch)
+	}
+
+	// A browser still launching is left for launch to shut down.
+	done := make(chan struct{})
+	go func() {
+		pb.shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown() waited for the launch")
+	}
+}
+
+func TestAcquireWaitsForASlot(t *testing.T) {
+	p := NewBrowserPool(&config.Config{BrowserMaxConcurrency: 1})
+	defer p.Close()
+
+	// Take the only slot as if a page were out.
+	p.slots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	page, err := p.Acquire(ctx)
+	if err != context.DeadlineExceeded || page != nil {
+		t.Errorf("Acquire() = %v, %v, want context.DeadlineExceeded", page, err)
+	}
+	if len(p.slots) != 1 {
+		t.Errorf("%d slots taken, want 1", len(p.slots))
+	}
+}
+
+func TestAcquireClosedPool(t *testing.T) {
+	p := NewBrowserPool(&config.Config{BrowserMaxConcurrency: 2})
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := p.Acquire(context.Background())
+	if err == nil || page != nil {
+		t.Fatalf("Acquire() = %v, %v, want an error", page, err)
+	}
+	// The failed checkout gives its slot back.
+	if len(p.slots) != 0 {
+		t.Errorf("%d slots taken, want 0", len(p.slots))
+	}
+}
+
+func TestAcquireStopsWaitingForALaunch(t *testing.T) {
+	p := NewBrowserPool(&config.Config{BrowserPoolSize: 1, BrowserMaxConcurrency: 2})
+	defer p.Close()
+
+	// Reserve the only slot as if another request were launching its
+	// browser.
+	pb, _ := p.reserveSlot(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	page, err := p.Acquire(ctx)
+	if err != context.DeadlineExceeded || page != nil {
+		t.Errorf("Acquire() = %v, %v, want context.DeadlineExceeded", page, err)
+	}
+	if len(p.slots) != 0 {
+		t.Errorf("%d slots taken, want 0", len(p.slots))
+	}
+	if pb.active != 0 {
+		t.Errorf("launching browser has %d pages, want 0", pb.active)
+	}
+}
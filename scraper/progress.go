@@ -0,0 +1,30 @@
+package scraper
+
+import "context"
+
+type Stage string
+
+const (
+	StageNavigating Stage = "navigating"
+	StageRendered   Stage = "rendered"
+	StageConverting Stage = "converting"
+	// StageFallback means a conversion step failed and the next step of the
+	// fallback chain starts over; streamed markdown so far is void.
+	StageFallback Stage = "fallback"
+)
+
+type ProgressFunc func(stage Stage)
+
+type progressKey struct{}
+
+// WithProgress returns a context that makes Scrape report each stage it
+// enters to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, stage Stage) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(stage)
+	}
+}
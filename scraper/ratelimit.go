@@ -0,0 +1,211 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+type hostBucket struct {
+	limit   config.HostLimit
+	tokens  float64
+	last    time.Time
+	active  int
+	changed chan struct{}
+}
+
+// HostLimiter is a per-host token bucket that also caps how many requests
+// to one host are in flight at once.
+type HostLimiter struct {
+	defaults  config.HostLimit
+	overrides map[string]config.HostLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*hostBucket
+	lastSweep time.Time
+}
+
+// sweepInterval is how often idle buckets are looked for.
+const sweepInterval = time.Minute
+
+func NewHostLimiter(defaults config.HostLimit, overrides map[string]config.HostLimit) *HostLimiter {
+	return &HostLimiter{
+		defaults:  defaults,
+		overrides: overrides,
+		buckets:   make(map[string]*hostBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Acquire takes a token and a concurrency slot for the URL's host. When wait
+// is false it returns errors.ErrRateLimited instead of queueing. The returned
+// func releases the slot.
+func (l *HostLimiter) Acquire(ctx context.Context, rawURL string, wait bool) (func(), error) {
+	b, err := l.take(ctx, rawURL, wait, true)
+	if err != nil {
+		return nil, err
+	}
+	return func() { l.release(b) }, nil
+}
+
+// Take takes a token for the URL's host without a concurrency slot, for
+// further requests made under a slot already held, such as next pages.
+func (l *HostLimiter) Take(ctx context.Context, rawURL string, wait bool) error {
+	_, err := l.take(ctx, rawURL, wait, false)
+	return err
+}
+
+// take waits for a token, and a concurrency slot when slot is set, and
+// returns the host's bucket.
+func (l *HostLimiter) take(ctx context.Context, rawURL string, wait, slot bool) (*hostBucket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := trimWWW(u.Hostname())
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.sweep(now)
+		b := l.bucket(host)
+		b.refill(now)
+
+		hasSlot := !slot || b.limit.MaxConcurrent <= 0 || b.active < b.limit.MaxConcurrent
+		hasToken := b.limit.Rate <= 0 || b.tokens >= 1
+		if hasSlot && hasToken {
+			if b.limit.Rate > 0 {
+				b.tokens--
+			}
+			if slot {
+				b.active++
+			}
+			l.mu.Unlock()
+			return b, nil
+		}
+
+		changed := b.changed
+		retryIn := time.Duration(0)
+		if hasSlot {
+			retryIn = time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		if !wait {
+			return nil, errors.WithCause(errors.ErrRateLimited, "too many requests to %s", host)
+		}
+
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if retryIn > 0 {
+			timer = time.NewTimer(retryIn)
+			timerC = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-changed:
+		case <-timerC:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (l *HostLimiter) release(b *hostBucket) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b.active--
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (l *HostLimiter) bucket(host string) *hostBucket {
+	if b, ok := l.buckets[host]; ok {
+		return b
+	}
+
+	limit := l.limitFor(host)
+	b := &hostBucket{
+		limit:   limit,
+		tokens:  float64(limit.Burst),
+		last:    time.Now(),
+		changed: make(chan struct{}),
+	}
+	if b.tokens < 1 {
+		b.tokens = 1
+	}
+	l.buckets[host] = b
+	return b
+}
+
+// sweep drops the buckets of hosts that have no request in flight and have
+// not been used for longer than it takes them to refill, which are as good
+// as new. It keeps the map from growing with every host ever seen. l.mu
+// must be held.
+func (l *HostLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for host, b := range l.buckets {
+		if b.active == 0 && now.Sub(b.last) >= b.refillWindow() {
+			delete(l.buckets, host)
+		}
+	}
+}
+
+// refillWindow is how long an empty bucket takes to fill up.
+func (b *hostBucket) refillWindow() time.Duration {
+	if b.limit.Rate <= 0 {
+		return 0
+	}
+	burst := float64(b.limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return time.Duration(burst / b.limit.Rate * float64(time.Second))
+}
+
+// limitFor matches the host itself first, then each parent domain.
+func (l *HostLimiter) limitFor(host string) config.HostLimit {
+	for h := host; h != ""; {
+		if limit, ok := l.overrides[h]; ok {
+			return limit
+		}
+		i := strings.Index(h, ".")
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	return l.defaults
+}
+
+func (b *hostBucket) refill(now time.Time) {
+	if b.limit.Rate <= 0 {
+		return
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	burst := float64(b.limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
@@ -0,0 +1,215 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+)
+
+func TestHostLimiterBurstThenFailFast(t *testing.T) {
+	l := NewHostLimiter(config.HostLimit{Rate: 1, Burst: 2}, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		release, err := l.Acquire(ctx, "https://example.com/a", false)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		release()
+	}
+
+	_, err := l.Acquire(ctx, "https://www.example.com/b", false)
+	if !errors.IsType(err, errors.ErrRateLimited) {
+		t.Fatalf("third request: got %v, want ErrRateLimited", err)
+	}
+
+	// Other hosts have their own bucket.
+	release, err := l.Acquire(ctx, "https://other.com/", false)
+	if err != nil {
+		t.Fatalf("other host: %v", err)
+	}
+	release()
+}
+
+func TestHostLimiterWaitsForToken(t *testing.T) {
+	l := NewHostLimiter(config.HostLimit{Rate: 20, Burst: 1}, nil)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.Acquire(ctx, "https://example.com/", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	// Two refills at 20 per second take about 100ms.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("three requests took %v, want about 100ms", elapsed)
+	}
+}
+
+func TestHostLimiterConcurrency(t *testing.T) {
+	l := NewHostLimiter(config.HostLimit{MaxConcurrent: 1}, nil)
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx, "https://example.com/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, "https://example.com/", false); !errors.IsType(err, errors.ErrRateLimited) {
+		t.Fatalf("second request while the first runs: got %v, want ErrRateLimited", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := l.Acquire(ctx, "https://example.com/", true)
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("waiting request got a slot before the first was released")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting request was not woken by the release")
+	}
+}
+
+func TestHostLimiterCancelledWait(t *testing.T) {
+	l := NewHostLimiter(config.HostLimit{MaxConcurrent: 1}, nil)
+	release, err := l.Acquire(context.Background(), "https://example.com/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "https://example.com/", true); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestHostLimiterLimitFor(t *testing.T) {
+	defaults := config.HostLimit{Rate: 1, Burst: 1}
+	l := NewHostLimiter(defaults, map[string]config.HostLimit{
+		"example.com":     {Rate: 5, Burst: 5},
+		"api.example.com": {Rate: 10, Burst: 10},
+	})
+
+	tests := []struct {
+		host string
+		want float64
+	}{
+		{"example.com", 5},
+		{"docs.example.com", 5},
+		{"api.example.com", 10},
+		{"v2.api.example.com", 10},
+		{"example.org", 1},
+		{"notexample.com", 1},
+	}
+	for _, tt := range tests {
+		if got := l.limitFor(tt.host).Rate; got != tt.want {
+			t.Errorf("limitFor(%q).Rate = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHostLimiterEvictsIdleBuckets(t *testing.T) {
+	l := NewHostLimiter(config.HostLimit{Rate: 1, Burst: 10, MaxConcurrent: 2}, nil)
+	ctx := context.Background()
+
+	idle, err := l.Acquire(ctx, "https://idle.com/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle()
+	busy, err := l.Acquire(ctx, "https://busy.com/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy()
+	recent, err := l.Acquire(ctx, "https://recent.com/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent()
+
+	// Age idle.com and busy.com past their 10s refill window.
+	now := time.Now()
+	l.mu.Lock()
+	l.buckets["idle.com"].last = now.Add(-11 * time.Second)
+	l.buckets["busy.com"].last = now.Add(-11 * time.Second)
+	l.lastSweep = now.Add(-sweepInterval)
+	l.sweep(now)
+	_, hasIdle := l.buckets["idle.com"]
+	_, hasBusy := l.buckets["busy.com"]
+	_, hasRecent := l.buckets["recent.com"]
+	l.mu.Unlock()
+
+	if hasIdle {
+		t.Error("idle bucket was kept")
+	}
+	if !hasBusy {
+		t.Error("bucket with a request in flight was evicted")
+	}
+	if !hasRecent {
+		t.Error("bucket still refilling was evicted")
+	}
+}
+
+// nextRenderer follows next links like followNext, asking CheckURL before
+// each one, and counts the pages it got to.
+type nextRenderer struct {
+	pages int
+}
+
+func (r nextRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
+	result := &RenderResult{HTML: "<p>1</p>", URL: url, Pagination: &PaginationResult{Mode: PaginateNext, Pages: 1, Stopped: StopNoMore}}
+	for i := 2; i <= r.pages; i++ {
+		if err := options.CheckURL(ctx, fmt.Sprintf("%s?page=%d", url, i)); err != nil {
+			result.Pagination.stop(ctx, err)
+			break
+		}
+		result.Pagination.Pages++
+	}
+	return result, nil
+}
+
+func TestNextPagesTakeHostTokens(t *testing.T) {
+	s := NewScraperService(&config.Config{
+		Converter:     ConverterNative,
+		HostRateLimit: config.HostLimit{Rate: 0.001, Burst: 3},
+	})
+	s.SetRenderer(nextRenderer{pages: 5})
+	ctx := context.Background()
+
+	opts := ScrapeOptions{Paginate: &PaginateOptions{Mode: PaginateNext}, RateLimit: RateLimitFail}
+	result, err := s.Scrape(ctx, "https://example.com/", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first page and two next pages use up the burst of three.
+	if result.Pagination.Pages != 3 || result.Pagination.Stopped != StopError {
+		t.Errorf("Pagination = %+v, want 3 pages stopped by the rate limit", result.Pagination)
+	}
+
+	if _, err := s.Scrape(ctx, "https://example.com/other", opts); !errors.IsType(err, errors.ErrRateLimited) {
+		t.Errorf("next scrape: got %v, want ErrRateLimited", err)
+	}
+}
@@ -0,0 +1,320 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsEntry struct {
+	group     *robotsGroup
+	fetchedAt time.Time
+}
+
+// disallowAll stands in for a robots.txt the server failed to serve or that
+// could not be reached, which RFC 9309 says means nothing may be crawled.
+var disallowAll = &robotsGroup{rules: []robotsRule{{allow: false, pattern: "/"}}}
+
+// unavailableTTL bounds how long a failed fetch keeps a host blocked before
+// robots.txt is fetched again.
+const unavailableTTL = time.Minute
+
+// RobotsChecker fetches and caches robots.txt per host and spaces requests to
+// a host according to its Crawl-delay.
+type RobotsChecker struct {
+	userAgent  string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	entries   map[string]*robotsEntry
+	nextVisit map[string]time.Time
+}
+
+func NewRobotsChecker(userAgent string, ttl time.Duration) *RobotsChecker {
+	return &RobotsChecker{
+		userAgent:  userAgent,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		entries:    make(map[string]*robotsEntry),
+		nextVisit:  make(map[string]time.Time),
+	}
+}
+
+// Allowed reports whether rawURL may be fetched and the Crawl-delay that
+// applies to its host.
+func (rc *RobotsChecker) Allowed(ctx context.Context, rawURL string) (bool, time.Duration, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, 0, err
+	}
+
+	group, err := rc.group(ctx, u)
+	if err != nil {
+		return false, 0, err
+	}
+	if group == nil {
+		return true, 0, nil
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return group.allows(path), group.crawlDelay, nil
+}
+
+// Wait blocks until delay has passed since the previous visit to the host.
+func (rc *RobotsChecker) Wait(ctx context.Context, rawURL string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(u.Host)
+
+	rc.mu.Lock()
+	now := time.Now()
+	visitAt := rc.nextVisit[host]
+	if visitAt.Before(now) {
+		visitAt = now
+	}
+	rc.nextVisit[host] = visitAt.Add(delay)
+	rc.mu.Unlock()
+
+	return sleepContext(ctx, time.Until(visitAt))
+}
+
+func (rc *RobotsChecker) group(ctx context.Context, u *url.URL) (*robotsGroup, error) {
+	key := u.Scheme + "://" + strings.ToLower(u.Host)
+
+	rc.mu.Lock()
+	entry, ok := rc.entries[key]
+	rc.mu.Unlock()
+	if ok {
+		ttl := rc.ttl
+		if entry.group == disallowAll && ttl > unavailableTTL {
+			ttl = unavailableTTL
+		}
+		if time.Since(entry.fetchedAt) < ttl {
+			return entry.group, nil
+		}
+	}
+
+	group, err := rc.fetch(ctx, key+"/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	rc.mu.Lock()
+	rc.entries[key] = &robotsEntry{group: group, fetchedAt: time.Now()}
+	rc.mu.Unlock()
+
+	return group, nil
+}
+
+func (rc *RobotsChecker) fetch(ctx context.Context, robotsURL string) (*robotsGroup, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", rc.userAgent)
+
+	resp, err := rc.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// RFC 9309 treats an unreachable robots.txt as a complete
+		// disallow. It is cached only briefly, like a server error.
+		fmt.Printf("Warning: failed to fetch %s: %v, disallowing the host for now\n", robotsURL, err)
+		return disallowAll, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		fmt.Printf("Warning: %s answered %d, disallowing the host for now\n", robotsURL, resp.StatusCode)
+		return disallowAll, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Other client errors, such as 404, mean there are no rules.
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+	if err != nil {
+		return nil, fmt.Errorf("error reading robots.txt: %w", err)
+	}
+
+	return selectRobotsGroup(parseRobots(string(body)), rc.userAgent), nil
+}
+
+func parseRobots(body string) []*robotsGroup {
+	var (
+		groups  []*robotsGroup
+		current *robotsGroup
+		// Consecutive user-agent lines share one group.
+		collectingAgents bool
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !collectingAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			collectingAgents = true
+		case "allow", "disallow":
+			collectingAgents = false
+			if current == nil || (key == "disallow" && value == "") {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			collectingAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	return groups
+}
+
+// selectRobotsGroup picks the groups naming our product token, compared
+// case-insensitively as a whole, falling back to the wildcard groups. As
+// RFC 9309 asks, several groups for the same agent are merged into one.
+func selectRobotsGroup(groups []*robotsGroup, userAgent string) *robotsGroup {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var named, wildcard []*robotsGroup
+	for _, g := range groups {
+		isNamed, isWildcard := false, false
+		for _, agent := range g.agents {
+			switch {
+			case agent == "*":
+				isWildcard = true
+			case token != "" && agent == token:
+				isNamed = true
+			}
+		}
+		if isNamed {
+			named = append(named, g)
+		} else if isWildcard {
+			wildcard = append(wildcard, g)
+		}
+	}
+
+	if len(named) > 0 {
+		return mergeRobotsGroups(named)
+	}
+	return mergeRobotsGroups(wildcard)
+}
+
+func mergeRobotsGroups(groups []*robotsGroup) *robotsGroup {
+	switch len(groups) {
+	case 0:
+		return nil
+	case 1:
+		return groups[0]
+	}
+	merged := &robotsGroup{}
+	for _, g := range groups {
+		merged.agents = append(merged.agents, g.agents...)
+		merged.rules = append(merged.rules, g.rules...)
+		if g.crawlDelay > merged.crawlDelay {
+			merged.crawlDelay = g.crawlDelay
+		}
+	}
+	return merged
+}
+
+// allows applies the longest matching rule; allow wins ties.
+func (g *robotsGroup) allows(path string) bool {
+	best := -1
+	allowed := true
+	for _, rule := range g.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > best || (n == best && rule.allow) {
+			best = n
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+
+	if anchored {
+		if len(parts) > 1 {
+			return strings.HasSuffix(path, parts[len(parts)-1])
+		}
+		return pos == len(path)
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private/page", true},
+		{"/private", "/public", false},
+		{"/*.pdf", "/docs/file.pdf", true},
+		{"/*.pdf$", "/docs/file.pdf", true},
+		{"/*.pdf$", "/docs/file.pdf?x=1", false},
+		{"/exact$", "/exact", true},
+		{"/exact$", "/exact/more", false},
+		{"/a*b*c", "/a-x-b-y-c", true},
+		{"/a*b*c", "/a-x-c-y-b", false},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsGroupAllows(t *testing.T) {
+	group := selectRobotsGroup(parseRobots(`
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Disallow: /*.pdf$
+Disallow:
+`), "Scarab")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/private", false},
+		{"/private/secret", false},
+		{"/private/open/page", true},
+		{"/files/a.pdf", false},
+		{"/files/a.pdf.html", true},
+	}
+	for _, tt := range tests {
+		if got := group.allows(tt.path); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSelectRobotsGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		robots    string
+		userAgent string
+		// path is checked against the selected group, and allowed when
+		// no group applies.
+		path      string
+		want      bool
+		wantDelay time.Duration
+	}{
+		{
+			name:      "named group over wildcard",
+			robots:    "User-agent: *\nDisallow: /\n\nUser-agent: scarab\nDisallow: /admin\n",
+			userAgent: "Scarab/1.0",
+			path:      "/page",
+			want:      true,
+		},
+		{
+			name:      "token compared case-insensitively",
+			robots:    "User-agent: SCARAB\nDisallow: /\n",
+			userAgent: "scarab",
+			path:      "/page",
+			want:      false,
+		},
+		{
+			name:      "substring of the token does not match",
+			robots:    "User-agent: s\nDisallow: /\n\nUser-agent: *\nAllow: /\n",
+			userAgent: "Scarab",
+			path:      "/page",
+			want:      true,
+		},
+		{
+			name:      "longer agent does not match",
+			robots:    "User-agent: scarabbot\nDisallow: /\n",
+			userAgent: "Scarab",
+			path:      "/page",
+			want:      true,
+		},
+		{
+			name:      "groups for the same agent are merged",
+			robots:    "User-agent: scarab\nDisallow: /a\nCrawl-delay: 2\n\nUser-agent: other\nDisallow: /\n\nUser-agent: scarab\nDisallow: /b\nCrawl-delay: 5\n",
+			userAgent: "Scarab",
+			path:      "/b/page",
+			want:      false,
+			wantDelay: 5 * time.Second,
+		},
+		{
+			name:      "consecutive agents share a group",
+			robots:    "User-agent: other\nUser-agent: scarab\nDisallow: /x\n",
+			userAgent: "Scarab",
+			path:      "/x",
+			want:      false,
+		},
+		{
+			name:      "no matching group",
+			robots:    "User-agent: other\nDisallow: /\n",
+			userAgent: "Scarab",
+			path:      "/page",
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := selectRobotsGroup(parseRobots(tt.robots), tt.userAgent)
+			got := group == nil || group.allows(tt.path)
+			if got != tt.want {
+				t.Errorf("allows(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+			if group != nil && group.crawlDelay != tt.wantDelay {
+				t.Errorf("crawl delay = %v, want %v", group.crawlDelay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestRobotsCheckerFetch(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"rules apply", http.StatusOK, "User-agent: *\nDisallow: /page\n", false},
+		{"missing robots.txt allows all", http.StatusNotFound, "", true},
+		{"forbidden robots.txt allows all", http.StatusForbidden, "", true},
+		{"server error disallows all", http.StatusServiceUnavailable, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/robots.txt" {
+					t.Errorf("fetched %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			rc := NewRobotsChecker("Scarab", time.Hour)
+			allowed, _, err := rc.Allowed(context.Background(), srv.URL+"/page")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed != tt.want {
+				t.Errorf("Allowed = %v, want %v", allowed, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsCheckerUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	rc := NewRobotsChecker("Scarab", time.Hour)
+	allowed, _, err := rc.Allowed(context.Background(), url+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed {
+		t.Error("Allowed = true for a host whose robots.txt cannot be fetched")
+	}
+	for _, entry := range rc.entries {
+		if entry.group != disallowAll {
+			t.Errorf("cached %+v, want disallowAll so it expires after unavailableTTL", entry.group)
+		}
+	}
+}
+
+func TestRobotsCheckerCachesAndRefetchesAfterServerError(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	rc := NewRobotsChecker("Scarab", time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, _, err := rc.Allowed(context.Background(), srv.URL+"/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("fetched %d times, want 1", fetches)
+	}
+
+	// Past unavailableTTL the host is asked again, even though the TTL has
+	// not run out.
+	for _, entry := range rc.entries {
+		entry.fetchedAt = time.Now().Add(-unavailableTTL - time.Second)
+	}
+	if _, _, err := rc.Allowed(context.Background(), srv.URL+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Fatalf("fetched %d times, want 2", fetches)
+	}
+}
+
+func TestRobotsCheckerWait(t *testing.T) {
+	rc := NewRobotsChecker("Scarab", time.Hour)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := rc.Wait(ctx, "https://example.com/", 50*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("three visits took %v, want at least 100ms", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := rc.Wait(cancelled, "https://example.com/", time.Hour); err == nil {
+		t.Error("Wait ignored a cancelled context")
+	}
+}
@@ -3,15 +3,27 @@ package scraper
 import (
 	"context"
 	"fmt"
+	"log"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/Sagn1k/scarab/artifacts"
+	"github.com/Sagn1k/scarab/cache"
 	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/convert"
+	"github.com/Sagn1k/scarab/errors"
 	"github.com/Sagn1k/scarab/llm"
-	"strings"
 )
 
 type ScraperService struct {
 	config        *config.Config
-	renderer      *BrowserRenderer
+	renderer      Renderer
 	llmClient     *llm.Client
+	robots        *RobotsChecker
+	hostLimiter   *HostLimiter
+	cache         cache.Cache
+	artifacts     artifacts.Store
 	proxyRotator  *ProxyRotator
 	headerRotator *HeaderRotator
 }
@@ -22,61 +34,279 @@ func NewScraperService(cfg *config.Config) *ScraperService {
 	headerRotator := NewHeaderRotator(cfg.UserAgents)
 	browserRenderer := NewBrowserRenderer(cfg, proxyRotator, headerRotator)
 	llmClient := llm.NewClient(cfg)
+	if err := checkFallback(cfg.LLMFallback, llmClient); err != nil {
+		log.Fatalf("Invalid LLM_FALLBACK: %v", err)
+	}
 
 	return &ScraperService{
 		config:        cfg,
 		renderer:      browserRenderer,
 		llmClient:     llmClient,
+		robots:        NewRobotsChecker(cfg.RobotsUserAgent, time.Duration(cfg.RobotsCacheMinutes)*time.Minute),
+		hostLimiter:   NewHostLimiter(cfg.HostRateLimit, cfg.HostRateLimitRules),
+		cache:         newCache(cfg),
+		artifacts:     newArtifactStore(cfg),
 		proxyRotator:  proxyRotator,
 		headerRotator: headerRotator,
 	}
 }
 
-func (s *ScraperService) Scrape(ctx context.Context, url string, params map[string]interface{}) (string, error) {
-	// Extract wait time parameter if provided, otherwise use default
-	waitTime := s.config.CloudflareWaitMS
-	if waitParam, ok := params["waitTime"].(float64); ok {
-		waitTime = int(waitParam)
+// SetRenderer replaces the browser the service renders pages with, e.g.
+// with a stub in tests.
+func (s *ScraperService) SetRenderer(r Renderer) {
+	s.renderer = r
+}
+
+// LLM returns the client pages are converted with, e.g. to register a
+// FakeProvider in tests.
+func (s *ScraperService) LLM() *llm.Client {
+	return s.llmClient
+}
+
+type ConversionInfo struct {
+	Converter    string `json:"converter"`
+	Provider     string `json:"provider,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Chunks       int    `json:"chunks,omitempty"`
+	Truncated    bool   `json:"truncated"`
+	DroppedChars int    `json:"droppedChars,omitempty"`
+	// Fallbacks lists the steps of the LLM fallback chain that failed
+	// before the one that produced the result.
+	Fallbacks []FallbackAttempt `json:"fallbacks,omitempty"`
+}
+
+type FallbackAttempt struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	Error    string `json:"error"`
+}
+
+// Outputs are the formats of a page besides markdown. Each is set only when
+// requested.
+type Outputs struct {
+	Text     string            `json:"text,omitempty"`
+	HTML     string            `json:"html,omitempty"`
+	Document *convert.Document `json:"document,omitempty"`
+	// Screenshot and PDF are set when the "screenshot" and "pdf" params
+	// ask for them.
+	Screenshot *Artifact `json:"screenshot,omitempty"`
+	PDF        *Artifact `json:"pdf,omitempty"`
+}
+
+type ScrapeResult struct {
+	URL      string
+	Markdown string
+	Outputs
+	Metadata *Metadata
+	// Actions reports each action of the "actions" param.
+	Actions []ActionResult
+	// Pagination reports what the "paginate" param loaded.
+	Pagination *PaginationResult
+	// Conversion describes how the markdown was made. It is zero when
+	// markdown was not requested.
+	Conversion ConversionInfo
+	Usage      llm.Usage
+	// Cache says what the result was served from: CacheHitMarkdown,
+	// CacheHitHTML or empty when nothing was cached.
+	Cache string
+}
+
+func (s *ScraperService) Scrape(ctx context.Context, url string, opts ScrapeOptions) (*ScrapeResult, error) {
+	page, err := s.scrapePage(ctx, url, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract specific elements if provided
-	var targetSelectors []string
-	if selectors, ok := params["selectors"].([]interface{}); ok {
-		for _, selector := range selectors {
-			if selectorStr, ok := selector.(string); ok {
-				targetSelectors = append(targetSelectors, selectorStr)
-			}
+	return page.result, nil
+}
+
+type scrapedPage struct {
+	rendered *RenderResult
+	result   *ScrapeResult
+}
+
+func (s *ScraperService) scrapePage(ctx context.Context, url string, opts ScrapeOptions) (*scrapedPage, error) {
+	opts, err := s.resolveOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+
+	meter := &llm.Meter{}
+	ctx = llm.WithUsageRecorder(ctx, meter)
+
+	rendered, err := s.render(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ScrapeResult
+	if opts.wants(OutputMarkdown) {
+		if result, err = s.markdown(ctx, rendered, url, opts); err != nil {
+			return nil, err
+		}
+		result.Usage = meter.Usage()
+	} else {
+		result = &ScrapeResult{URL: rendered.URL}
+	}
+	if rendered.FromCache && result.Cache == "" {
+		result.Cache = CacheHitHTML
+	}
+
+	if result.Outputs, err = s.outputs(ctx, rendered, opts); err != nil {
+		return nil, err
+	}
+	result.Metadata = rendered.Metadata
+	result.Actions = rendered.Actions
+	result.Pagination = rendered.Pagination
+
+	return &scrapedPage{rendered: rendered, result: result}, nil
+}
+
+// markdown converts the rendered page with the requested converter, unless
+// the same page went through the same converter and model recently.
+func (s *ScraperService) markdown(ctx context.Context, rendered *RenderResult, url string, opts ScrapeOptions) (*ScrapeResult, error) {
+	reportProgress(ctx, StageConverting)
+	markdownKey := s.markdownCacheKey(url, opts)
+
+	if opts.CacheMode == CacheModeUse && rendered.FromCache && markdownKey != "" {
+		var cached cachedMarkdown
+		if s.cacheGet(markdownKey, &cached) {
+			streamMarkdown(ctx, cached.Markdown)
+			return &ScrapeResult{
+				URL:        rendered.URL,
+				Markdown:   cached.Markdown,
+				Conversion: cached.Conversion,
+				Cache:      CacheHitMarkdown,
+			}, nil
 		}
 	}
 
-	// Check if Cloudflare bypass is enabled/disabled
-	bypassCF := true // Default to true
-	if bypassValue, ok := params["bypassCloudflare"].(bool); ok {
-		bypassCF = bypassValue
+	result, err := s.convert(ctx, rendered.HTML, rendered.URL, opts)
+	if err != nil {
+		return nil, scrapeError(ctx, fmt.Errorf("failed to convert to markdown: %w", err))
 	}
 
-	// Configure renderer options
-	options := &RenderOptions{
-		WaitTime:  waitTime,
-		Selectors: targetSelectors,
-		BypassCF:  bypassCF,
+	// Results from a fallback step are not what the request asked for, so
+	// they are not cached under its key.
+	if opts.CacheMode != CacheModeBypass && markdownKey != "" && len(result.Conversion.Fallbacks) == 0 {
+		s.cacheSet(markdownKey, cachedMarkdown{Markdown: result.Markdown, Conversion: result.Conversion})
+	}
+	return result, nil
+}
+
+// outputs produces the requested formats other than markdown from the
+// rendered page and stores its captures. Like the native converter, the
+// formats keep the main content.
+func (s *ScraperService) outputs(ctx context.Context, rendered *RenderResult, opts ScrapeOptions) (Outputs, error) {
+	var out Outputs
+	var err error
+	convertOpts := convert.Options{MainContent: true}
+
+	if opts.wants(OutputJSON) || opts.wants(OutputText) {
+		var doc *convert.Document
+		if doc, err = convert.ToDocument(rendered.HTML, rendered.URL, convertOpts); err != nil {
+			return out, err
+		}
+		if opts.wants(OutputJSON) {
+			out.Document = doc
+		}
+		if opts.wants(OutputText) {
+			out.Text = doc.Text()
+		}
+	}
+	if opts.wants(OutputHTML) {
+		if out.HTML, err = convert.ToHTML(rendered.HTML, rendered.URL, convertOpts); err != nil {
+			return out, err
+		}
+	}
+
+	screenshotType := "image/png"
+	if opts.Screenshot != nil && opts.Screenshot.Format == ScreenshotJPEG {
+		screenshotType = "image/jpeg"
+	}
+	if out.Screenshot, err = s.artifact(ctx, screenshotType, rendered.Screenshot); err != nil {
+		return out, err
+	}
+	if out.PDF, err = s.artifact(ctx, "application/pdf", rendered.PDF); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+func (s *ScraperService) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.config.ScrapeTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(s.config.ScrapeTimeout)*time.Second)
+}
+
+// render serves the page from the HTML cache or checks robots.txt and the
+// host limiter, then renders the page, retrying failed renders and
+// Cloudflare challenges. opts must have their defaults applied.
+func (s *ScraperService) render(ctx context.Context, url string, opts ScrapeOptions) (*RenderResult, error) {
+	if err := validateURL(url); err != nil {
+		return nil, err
+	}
+
+	// Requests wait for the host's limiter unless they ask to fail fast
+	waitForHost := opts.RateLimit != RateLimitFail
+
+	options := renderOptions(opts)
+	// Next pages are fetched under the first page's concurrency slot, but
+	// each takes a token of its own.
+	options.CheckURL = func(ctx context.Context, next string) error {
+		if err := s.checkRobots(ctx, next, opts); err != nil {
+			return err
+		}
+		return s.hostLimiter.Take(ctx, next, waitForHost)
+	}
+	bypassCF := options.BypassCF
+
+	htmlKey := htmlCacheKey(url, options)
+	capturing := options.Screenshot != nil || options.PDF != nil
+	cacheable := opts.CacheMode != CacheModeBypass && replayable(options.Actions)
+	if cacheable && opts.CacheMode == CacheModeUse && !capturing {
+		if rendered, ok := s.cachedHTML(htmlKey); ok {
+			reportProgress(ctx, StageRendered)
+			return rendered, nil
+		}
+	}
+
+	if err := s.checkRobots(ctx, url, opts); err != nil {
+		return nil, scrapeError(ctx, err)
 	}
 
 	// Try multiple strategies if Cloudflare bypass is enabled
 	maxRetries := 3
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		// Attempt to render the page
-		html, err := s.renderer.RenderPage(ctx, url, options)
+		release, err := s.hostLimiter.Acquire(ctx, url, waitForHost)
+		if err != nil {
+			return nil, scrapeError(ctx, err)
+		}
+
+		reportProgress(ctx, StageNavigating)
+		rendered, err := s.renderer.RenderPage(ctx, url, options)
+		release()
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, scrapeError(ctx, err)
+			}
 			if attempt < maxRetries-1 {
 				// Retry with a different proxy if available
 				s.proxyRotator.GetNextProxy()
 				fmt.Printf("Render failed on attempt %d, retrying with new proxy...\n", attempt+1)
 				continue
 			}
-			return "", fmt.Errorf("failed to render page after %d attempts: %w", maxRetries, err)
+			return nil, fmt.Errorf("failed to render page after %d attempts: %w", maxRetries, err)
 		}
 
+		reportProgress(ctx, StageRendered)
+		html := rendered.HTML
+
 		// Check if we're still on the Cloudflare challenge page
 		if bypassCF && strings.Contains(html, "Just a moment") &&
 			strings.Contains(strings.ToLower(html), "cloudflare") {
@@ -88,18 +318,231 @@ func (s *ScraperService) Scrape(ctx context.Context, url string, params map[stri
 				continue
 			}
 
-			// If we're on the last attempt and still hitting Cloudflare, just use what we have
-			fmt.Println("Warning: Could not bypass Cloudflare after all attempts")
+			// The challenge page is not the content that was asked for
+			return nil, errors.WithCause(errors.ErrCloudflareBlock, "challenge still shown after %d attempts", maxRetries)
 		}
 
-		// Process the HTML with LLM to generate markdown
-		markdown, err := s.llmClient.HTMLToMarkdown(ctx, html, url)
+		if cacheable {
+			s.cacheSet(htmlKey, rendered)
+		}
+		return rendered, nil
+	}
+
+	return nil, errors.WithCause(errors.ErrPageLoad, "failed to render page after multiple attempts")
+}
+
+const (
+	ConverterNative = "native"
+	ConverterLLM    = "llm"
+	ConverterHybrid = "hybrid"
+)
+
+// convert turns rendered HTML into markdown. "native" never calls the LLM,
+// "llm" sends the raw HTML and "hybrid" sends the native output for cleanup.
+// LLM conversions walk the fallback chain until a step succeeds.
+func (s *ScraperService) convert(ctx context.Context, html, url string, opts ScrapeOptions) (*ScrapeResult, error) {
+	converter := opts.Converter
+
+	result := &ScrapeResult{
+		URL:        url,
+		Conversion: ConversionInfo{Converter: converter},
+	}
+
+	if converter != ConverterNative && converter != ConverterLLM && converter != ConverterHybrid {
+		return nil, errors.WithCause(errors.ErrInvalidParams, "unknown converter %q", converter)
+	}
+
+	// The native output is needed up front for hybrid and only as a last
+	// resort for llm.
+	var native string
+	nativeMarkdown := func() (string, error) {
+		if native != "" {
+			return native, nil
+		}
+		markdown, err := convert.ToMarkdown(html, url, convert.Options{MainContent: true})
+		native = markdown
+		return markdown, err
+	}
+
+	if converter == ConverterNative {
+		markdown, err := nativeMarkdown()
+		if err != nil {
+			return nil, err
+		}
+		streamMarkdown(ctx, markdown)
+		result.Markdown = markdown
+		return result, nil
+	}
+
+	steps := s.llmChain(opts)
+	for i, step := range steps {
+		if step.Provider == ConverterNative {
+			markdown, err := nativeMarkdown()
+			if err != nil {
+				return nil, err
+			}
+			streamMarkdown(ctx, markdown)
+			result.Markdown = markdown
+			result.Conversion.Converter = ConverterNative
+			return result, nil
+		}
+
+		client, err := s.llmClient.WithProvider(step.Provider, step.Model)
 		if err != nil {
-			return "", fmt.Errorf("failed to convert to markdown: %w", err)
+			return nil, err
+		}
+
+		var conversion *llm.Conversion
+		if converter == ConverterLLM {
+			conversion, err = client.HTMLToMarkdown(ctx, html, url)
+		} else {
+			var markdown string
+			if markdown, err = nativeMarkdown(); err != nil {
+				return nil, err
+			}
+			conversion, err = client.CleanupMarkdown(ctx, markdown, url)
+		}
+		if err != nil {
+			if i == len(steps)-1 || !fallbackable(err) {
+				return nil, err
+			}
+			result.Conversion.Fallbacks = append(result.Conversion.Fallbacks, FallbackAttempt{
+				Provider: client.Provider(),
+				Model:    client.Model(),
+				Error:    err.Error(),
+			})
+			reportProgress(ctx, StageFallback)
+			continue
+		}
+
+		result.Markdown = conversion.Markdown
+		result.Conversion.Provider = client.Provider()
+		result.Conversion.Model = client.Model()
+		result.Conversion.Chunks = conversion.Chunks
+		result.Conversion.Truncated = conversion.Truncated
+		result.Conversion.DroppedChars = conversion.DroppedChars
+		return result, nil
+	}
+
+	// llmChain always has the primary step, which returns above.
+	return nil, errors.WithCause(errors.ErrLLMAPIFailure, "no LLM configured")
+}
+
+// checkFallback returns an error for a fallback step naming a provider that
+// does not exist, so that a typo in LLM_FALLBACK stops the server instead of
+// failing scrapes as if the caller had asked for it.
+func checkFallback(steps []config.LLMStep, client *llm.Client) error {
+	for _, step := range steps {
+		if step.Provider != "" && step.Provider != ConverterNative && !client.HasProvider(step.Provider) {
+			return fmt.Errorf("unknown LLM provider %q", step.Provider)
 		}
+	}
+	return nil
+}
+
+// streamMarkdown sends markdown produced without the LLM to a streaming
+// caller in one piece.
+func streamMarkdown(ctx context.Context, markdown string) {
+	if fn := llm.Deltas(ctx); fn != nil {
+		fn(markdown)
+	}
+}
 
-		return markdown, nil
+// llmChain is the request's provider and model, from the "provider" and
+// "model" params or the defaults, followed by the configured fallbacks.
+// "fallback": false in params limits it to the first step.
+func (s *ScraperService) llmChain(opts ScrapeOptions) []config.LLMStep {
+	steps := []config.LLMStep{{Provider: opts.Provider, Model: opts.Model}}
+	if opts.Fallback != nil && !*opts.Fallback {
+		return steps
 	}
+	return append(steps, s.config.LLMFallback...)
+}
+
+// fallbackable reports whether a failed LLM step should hand over to the
+// next one. Outages, rate limits, open breakers, rejected keys and inputs
+// too long for the model may all succeed elsewhere; cancellation and
+// malformed requests will not.
+func fallbackable(err error) bool {
+	for _, class := range []error{
+		errors.ErrLLMRateLimited,
+		errors.ErrLLMServer,
+		errors.ErrLLMCircuitOpen,
+		errors.ErrLLMAuth,
+		errors.ErrLLMContextLength,
+	} {
+		if errors.IsType(err, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// llmFor returns the LLM client for a request, honouring the "provider"
+// and "model" params.
+func (s *ScraperService) llmFor(opts ScrapeOptions) (*llm.Client, error) {
+	if opts.Provider == "" && opts.Model == "" {
+		return s.llmClient, nil
+	}
+	return s.llmClient.WithProvider(opts.Provider, opts.Model)
+}
 
-	return "", fmt.Errorf("failed to render page after multiple attempts")
+// checkRobots refuses URLs disallowed by the host's robots.txt and waits out
+// its Crawl-delay. Requests can opt out with "ignoreRobots" for sites that
+// have given explicit permission.
+func (s *ScraperService) checkRobots(ctx context.Context, url string, opts ScrapeOptions) error {
+	if !s.config.RespectRobots || opts.IgnoreRobots {
+		return nil
+	}
+
+	allowed, delay, err := s.robots.Allowed(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to check robots.txt: %w", err)
+	}
+	if !allowed {
+		return errors.WithCause(errors.ErrRobotsDisallowed, "%s", url)
+	}
+
+	return s.robots.Wait(ctx, url, delay)
+}
+
+// renderOptions picks what the renderer needs from opts, which must have
+// their defaults applied.
+func renderOptions(opts ScrapeOptions) *RenderOptions {
+	return &RenderOptions{
+		WaitTime:  *opts.WaitTime,
+		Selectors: opts.Selectors,
+		BypassCF:  *opts.BypassCloudflare,
+		Headers:   opts.Headers,
+		Cookies:   opts.Cookies,
+		Viewport:  *opts.Viewport,
+		UserAgent: opts.UserAgent,
+
+		Screenshot: opts.Screenshot,
+		PDF:        opts.PDF,
+		Actions:    opts.Actions,
+		Paginate:   opts.Paginate,
+	}
+}
+
+// validateURL accepts absolute http and https URLs only.
+func validateURL(raw string) error {
+	parsed, err := neturl.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.WithCause(errors.ErrInvalidURL, "%q", raw)
+	}
+	return nil
+}
+
+func scrapeError(ctx context.Context, err error) error {
+	if errors.IsType(err, errors.ErrTimeout) {
+		return err
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return errors.WithCause(errors.ErrTimeout, "scrape deadline exceeded")
+	case context.Canceled:
+		return fmt.Errorf("scrape cancelled: %w", ctx.Err())
+	}
+	return err
 }
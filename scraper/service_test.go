@@ -0,0 +1,305 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/config"
+	"github.com/Sagn1k/scarab/errors"
+	"github.com/Sagn1k/scarab/llm"
+)
+
+// namedFake is a FakeProvider registered under another provider's name, so
+// that a fallback chain can have several steps.
+type namedFake struct {
+	*llm.FakeProvider
+	name string
+}
+
+func (p namedFake) Name() string {
+	return p.name
+}
+
+func statusError(status int) error {
+	return &llm.APIError{Provider: "fake", StatusCode: status, Message: "failed"}
+}
+
+const testPage = `<html><body><main><h1>Title</h1><p>Body text.</p></main></body></html>`
+
+func TestConvertFallback(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name          string
+		converter     string
+		fallback      []config.LLMStep
+		noFallback    bool
+		openaiErr     error
+		anthropicErr  error
+		wantMarkdown  string
+		wantProvider  string
+		wantConverter string
+		wantFallbacks []string
+		wantErr       error
+		wantCalls     map[string]int
+	}{
+		{
+			name:          "first step succeeds",
+			converter:     ConverterLLM,
+			fallback:      []config.LLMStep{{Provider: "anthropic"}},
+			wantMarkdown:  "from openai",
+			wantProvider:  "openai",
+			wantConverter: ConverterLLM,
+			wantCalls:     map[string]int{"openai": 1, "anthropic": 0},
+		},
+		{
+			name:          "rate limited step falls back",
+			converter:     ConverterLLM,
+			fallback:      []config.LLMStep{{Provider: "anthropic", Model: "small"}},
+			openaiErr:     statusError(429),
+			wantMarkdown:  "from anthropic",
+			wantProvider:  "anthropic",
+			wantConverter: ConverterLLM,
+			wantFallbacks: []string{"openai"},
+			wantCalls:     map[string]int{"openai": 1, "anthropic": 1},
+		},
+		{
+			name:      "request errors do not fall back",
+			converter: ConverterLLM,
+			fallback:  []config.LLMStep{{Provider: "anthropic"}},
+			openaiErr: statusError(400),
+			wantErr:   errors.ErrLLMAPIFailure,
+			wantCalls: map[string]int{"openai": 1, "anthropic": 0},
+		},
+		{
+			name:       "fallback disabled",
+			converter:  ConverterLLM,
+			fallback:   []config.LLMStep{{Provider: "anthropic"}},
+			noFallback: true,
+			openaiErr:  statusError(503),
+			wantErr:    errors.ErrLLMServer,
+			wantCalls:  map[string]int{"openai": 1, "anthropic": 0},
+		},
+		{
+			name:          "chain ends in the native converter",
+			converter:     ConverterHybrid,
+			fallback:      []config.LLMStep{{Provider: "anthropic"}, {Provider: ConverterNative}},
+			openaiErr:     statusError(500),
+			anthropicErr:  statusError(401),
+			wantMarkdown:  "# Title\n\nBody text.",
+			wantConverter: ConverterNative,
+			wantFallbacks: []string{"openai", "anthropic"},
+			wantCalls:     map[string]int{"openai": 1, "anthropic": 1},
+		},
+		{
+			name:         "last step's error is returned",
+			converter:    ConverterLLM,
+			fallback:     []config.LLMStep{{Provider: "anthropic"}},
+			openaiErr:    statusError(500),
+			anthropicErr: statusError(429),
+			wantErr:      errors.ErrLLMRateLimited,
+			wantCalls:    map[string]int{"openai": 1, "anthropic": 1},
+		},
+		{
+			name:          "native converter calls no LLM",
+			converter:     ConverterNative,
+			fallback:      []config.LLMStep{{Provider: "anthropic"}},
+			wantMarkdown:  "# Title\n\nBody text.",
+			wantConverter: ConverterNative,
+			wantCalls:     map[string]int{"openai": 0, "anthropic": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{LLMProvider: "openai", LLMFallback: tt.fallback})
+			fakes := map[string]*llm.FakeProvider{
+				"openai":    llm.NewFakeProvider("from openai"),
+				"anthropic": llm.NewFakeProvider("from anthropic"),
+			}
+			for name, fake := range fakes {
+				s.llmClient.RegisterProvider(namedFake{fake, name})
+			}
+			if tt.openaiErr != nil {
+				fakes["openai"].FailNext(tt.openaiErr)
+			}
+			if tt.anthropicErr != nil {
+				fakes["anthropic"].FailNext(tt.anthropicErr)
+			}
+
+			opts := ScrapeOptions{Converter: tt.converter}
+			if tt.noFallback {
+				opts.Fallback = &disabled
+			}
+			result, err := s.convert(context.Background(), testPage, "https://example.com/", opts)
+
+			for name, want := range tt.wantCalls {
+				if got := len(fakes[name].Requests()); got != want {
+					t.Errorf("%s got %d calls, want %d", name, got, want)
+				}
+			}
+			if tt.wantErr != nil {
+				if !errors.IsType(err, tt.wantErr) {
+					t.Errorf("convert() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convert() error = %v", err)
+			}
+
+			if strings.TrimSpace(result.Markdown) != tt.wantMarkdown {
+				t.Errorf("Markdown = %q, want %q", result.Markdown, tt.wantMarkdown)
+			}
+			if result.Conversion.Provider != tt.wantProvider || result.Conversion.Converter != tt.wantConverter {
+				t.Errorf("Conversion = %+v, want provider %q converter %q", result.Conversion, tt.wantProvider, tt.wantConverter)
+			}
+			var fallbacks []string
+			for _, attempt := range result.Conversion.Fallbacks {
+				fallbacks = append(fallbacks, attempt.Provider)
+			}
+			if strings.Join(fallbacks, ",") != strings.Join(tt.wantFallbacks, ",") {
+				t.Errorf("Fallbacks = %v, want %v", fallbacks, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+func TestFallbackable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.ErrLLMRateLimited, true},
+		{errors.ErrLLMServer, true},
+		{errors.ErrLLMCircuitOpen, true},
+		{errors.ErrLLMAuth, true},
+		{errors.ErrLLMContextLength, true},
+		{errors.ErrLLMAPIFailure, false},
+		{context.Canceled, false},
+		{errors.ErrInvalidParams, false},
+	}
+
+	for _, tt := range tests {
+		if got := fallbackable(tt.err); got != tt.want {
+			t.Errorf("fallbackable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFallback(t *testing.T) {
+	client := llm.NewClient(&config.Config{})
+
+	tests := []struct {
+		name    string
+		steps   []config.LLMStep
+		wantErr bool
+	}{
+		{"known providers", []config.LLMStep{{Provider: "anthropic", Model: "small"}, {Provider: "ollama"}}, false},
+		{"native", []config.LLMStep{{Provider: ConverterNative}}, false},
+		{"typo", []config.LLMStep{{Provider: "anthropic"}, {Provider: "antropic"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkFallback(tt.steps, client); (err != nil) != tt.wantErr {
+				t.Errorf("checkFallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadErrorKeepsCause(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now())
+	defer cancelExpired()
+
+	cause := fmt.Errorf("net::ERR_NAME_NOT_RESOLVED")
+	proxyCause := fmt.Errorf("net::ERR_PROXY_CONNECTION_FAILED")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want []error
+	}{
+		{"page load", context.Background(), cause, []error{errors.ErrPageLoad, cause}},
+		{"proxy", context.Background(), proxyCause, []error{errors.ErrProxyFailure, proxyCause}},
+		{"cancelled", cancelled, context.Canceled, []error{context.Canceled}},
+		{"deadline", expired, context.DeadlineExceeded, []error{errors.ErrTimeout, context.DeadlineExceeded}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadError(tt.ctx, tt.err)
+			for _, want := range tt.want {
+				if !errors.IsType(err, want) {
+					t.Errorf("loadError() = %v, want it to match %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+// hangingRenderer blocks until its context is done, like a page that never
+// finishes loading.
+type hangingRenderer struct {
+	calls int
+}
+
+func (r *hangingRenderer) RenderPage(ctx context.Context, url string, options *RenderOptions) (*RenderResult, error) {
+	r.calls++
+	<-ctx.Done()
+	return nil, renderError(ctx, ctx.Err())
+}
+
+func TestScrapeCancellation(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       func() (context.Context, context.CancelFunc)
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name: "cancelled by the caller",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr:   context.Canceled,
+			wantCalls: 1,
+		},
+		{
+			name: "caller's deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr:   errors.ErrTimeout,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraperService(&config.Config{Converter: ConverterNative, ScrapeTimeout: 60})
+			renderer := &hangingRenderer{}
+			s.SetRenderer(renderer)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			_, err := s.Scrape(ctx, "https://example.com/", ScrapeOptions{})
+			if !errors.IsType(err, tt.wantErr) {
+				t.Errorf("Scrape() error = %v, want %v", err, tt.wantErr)
+			}
+			// A cancelled render is not retried.
+			if renderer.calls != tt.wantCalls {
+				t.Errorf("rendered %d times, want %d", renderer.calls, tt.wantCalls)
+			}
+		})
+	}
+}
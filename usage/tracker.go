@@ -0,0 +1,187 @@
+package usage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sagn1k/scarab/llm"
+)
+
+// Anonymous is the key used for requests that carry no API key.
+const Anonymous = "anonymous"
+
+const (
+	dateLayout  = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// Totals counts the scrapes started with a key, one per page of a batch or
+// crawl, and the LLM usage they caused.
+type Totals struct {
+	Requests int `json:"requests"`
+	llm.Usage
+}
+
+func (t *Totals) Add(other Totals) {
+	t.Requests += other.Requests
+	t.Usage.Add(other.Usage)
+}
+
+// Tracker keeps running totals per API key and per UTC day. Days older than
+// the retention period are dropped as new usage comes in.
+type Tracker struct {
+	retention int
+
+	mu    sync.Mutex
+	byKey map[string]map[string]*Totals
+}
+
+func NewTracker(retentionDays int) *Tracker {
+	return &Tracker{
+		retention: retentionDays,
+		byKey:     make(map[string]map[string]*Totals),
+	}
+}
+
+func (t *Tracker) Record(key string, u llm.Usage) {
+	t.add(key, Totals{Usage: u})
+}
+
+// RecordRequest counts one scrape started with key.
+func (t *Tracker) RecordRequest(key string) {
+	t.add(key, Totals{Requests: 1})
+}
+
+func (t *Tracker) add(key string, totals Totals) {
+	if key == "" {
+		key = Anonymous
+	}
+	today := time.Now().UTC().Format(dateLayout)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	days, ok := t.byKey[key]
+	if !ok {
+		days = make(map[string]*Totals)
+		t.byKey[key] = days
+	}
+	day, ok := days[today]
+	if !ok {
+		day = &Totals{}
+		days[today] = day
+		t.prune()
+	}
+	day.Add(totals)
+}
+
+// prune drops the days that fall outside the retention period, and the
+// keys left without any.
+func (t *Tracker) prune() {
+	if t.retention <= 0 {
+		return
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -t.retention).Format(dateLayout)
+	for key, days := range t.byKey {
+		for date := range days {
+			// ISO dates compare correctly as strings.
+			if date < cutoff {
+				delete(days, date)
+			}
+		}
+		if len(days) == 0 {
+			delete(t.byKey, key)
+		}
+	}
+}
+
+// Recorder returns an llm.UsageRecorder that adds to key's totals.
+func (t *Tracker) Recorder(key string) llm.UsageRecorder {
+	return recorder{tracker: t, key: key}
+}
+
+type recorder struct {
+	tracker *Tracker
+	key     string
+}
+
+func (r recorder) RecordUsage(model string, u llm.Usage) {
+	r.tracker.Record(r.key, u)
+}
+
+// Day returns key's totals for the UTC day containing at.
+func (t *Tracker) Day(key string, at time.Time) Totals {
+	return t.sum(key, at.UTC().Format(dateLayout))
+}
+
+// Month returns key's totals for the UTC month containing at, as far as
+// the retention period reaches.
+func (t *Tracker) Month(key string, at time.Time) Totals {
+	return t.sum(key, at.UTC().Format(monthLayout))
+}
+
+// sum adds up the days whose date starts with prefix.
+func (t *Tracker) sum(key, prefix string) Totals {
+	if key == "" {
+		key = Anonymous
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var totals Totals
+	for date, day := range t.byKey[key] {
+		if strings.HasPrefix(date, prefix) {
+			totals.Add(*day)
+		}
+	}
+	return totals
+}
+
+type DayUsage struct {
+	Date string `json:"date"`
+	Totals
+}
+
+type Report struct {
+	Key   string     `json:"key"`
+	Total Totals     `json:"total"`
+	Days  []DayUsage `json:"days"`
+}
+
+// Report returns key's totals per day, oldest first, with the total over
+// the retained days.
+func (t *Tracker) Report(key string) Report {
+	if key == "" {
+		key = Anonymous
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := Report{Key: key, Days: []DayUsage{}}
+	for date, day := range t.byKey[key] {
+		report.Days = append(report.Days, DayUsage{Date: date, Totals: *day})
+		report.Total.Add(*day)
+	}
+	sort.Slice(report.Days, func(i, j int) bool {
+		return report.Days[i].Date < report.Days[j].Date
+	})
+
+	return report
+}
+
+// Keys returns every key with retained usage.
+func (t *Tracker) Keys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(t.byKey))
+	for key := range t.byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sagn1k/scarab/llm"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(30)
+	tracker.RecordRequest("k1")
+	tracker.RecordRequest("k1")
+	tracker.Record("k1", llm.Usage{Calls: 1, TotalTokens: 10, CostUSD: 0.5})
+	tracker.Recorder("k1").RecordUsage("m", llm.Usage{Calls: 2, TotalTokens: 5})
+	tracker.RecordRequest("")
+
+	now := time.Now()
+	want := Totals{Requests: 2, Usage: llm.Usage{Calls: 3, TotalTokens: 15, CostUSD: 0.5}}
+
+	tests := []struct {
+		name string
+		got  Totals
+		want Totals
+	}{
+		{"day", tracker.Day("k1", now), want},
+		{"month", tracker.Month("k1", now), want},
+		{"other day", tracker.Day("k1", now.AddDate(0, 0, -1)), Totals{}},
+		{"other key", tracker.Day("k2", now), Totals{}},
+		{"anonymous", tracker.Day(Anonymous, now), Totals{Requests: 1}},
+		{"empty key is anonymous", tracker.Day("", now), Totals{Requests: 1}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if keys := tracker.Keys(); len(keys) != 2 || keys[0] != Anonymous || keys[1] != "k1" {
+		t.Errorf("Keys() = %v", keys)
+	}
+}
+
+func TestTrackerReportAndRetention(t *testing.T) {
+	tracker := NewTracker(7)
+	old := time.Now().UTC().AddDate(0, 0, -10).Format(dateLayout)
+	recent := time.Now().UTC().AddDate(0, 0, -2).Format(dateLayout)
+	tracker.byKey["k"] = map[string]*Totals{
+		old:    {Requests: 4},
+		recent: {Requests: 3},
+	}
+
+	report := tracker.Report("k")
+	if len(report.Days) != 2 || report.Days[0].Date != old || report.Total.Requests != 7 {
+		t.Errorf("Report() before pruning = %+v", report)
+	}
+
+	// The first usage of a new day prunes days past the retention period.
+	tracker.RecordRequest("k")
+	report = tracker.Report("k")
+	if len(report.Days) != 2 || report.Days[0].Date != recent || report.Total.Requests != 4 {
+		t.Errorf("Report() after pruning = %+v", report)
+	}
+
+	tracker.byKey["idle"] = map[string]*Totals{old: {Requests: 1}}
+	tracker.RecordRequest("new")
+	if keys := tracker.Keys(); len(keys) != 2 || keys[0] != "k" || keys[1] != "new" {
+		t.Errorf("Keys() after pruning = %v, want the idle key dropped", keys)
+	}
+
+	if report := tracker.Report("unknown"); report.Days == nil || len(report.Days) != 0 {
+		t.Errorf("Report() for an unknown key = %+v, want no days", report)
+	}
+}